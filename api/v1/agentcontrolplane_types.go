@@ -17,29 +17,787 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// RegenerateISOAnnotation forces the discovery image to be regenerated
+// without changing the spec. Set it on an AgentControlPlane to any new value,
+// e.g. a timestamp, to request a regeneration. The controller copies the
+// handled value to the same annotation on the InfraEnv.
+const RegenerateISOAnnotation = "controlplane.openshift.io/regenerate-iso"
+
+// RotateKubeconfigAnnotation requests the <cluster>-kubeconfig Secret to be
+// rewritten from freshly fetched admin credentials. Set it on an
+// AgentControlPlane to any new value, e.g. a timestamp, to request a
+// rotation. The controller records the handled value in
+// status.kubeconfigRotation.
+const RotateKubeconfigAnnotation = "controlplane.openshift.io/rotate-kubeconfig"
+
+// UnmanagedMachineAnnotation excludes a control plane Machine from the
+// controller's deletions, e.g. while an operator recovers its host by hand.
+// The Machine still counts toward the replicas. Set it on the Machine to any
+// value; remove it to hand the Machine back.
+const UnmanagedMachineAnnotation = "controlplane.openshift.io/unmanaged"
+
+// ControllerVersionAnnotation is set by controllers started with
+// --controller-version to the version of the last one that reconciled the
+// AgentControlPlane. Controllers of an older version leave it alone, so that
+// an old and a new controller running side by side during a rollout do not
+// fight over it.
+const ControllerVersionAnnotation = "controlplane.openshift.io/controller-version"
+
+// ForceDeleteAnnotation allows an AgentControlPlane to be deleted while its
+// control plane is installing, which the validating webhook otherwise
+// refuses: interrupting an install can leave the hosts half-provisioned. Set
+// it to "true" before deleting.
+const ForceDeleteAnnotation = "controlplane.openshift.io/force-delete"
+
+// AgentControlPlaneFinalizer is set on AgentControlPlanes so their control
+// plane Machines are deleted before they are.
+const AgentControlPlaneFinalizer = "agentcontrolplane.controlplane.openshift.io"
+
+// HostnameOrdinalPlaceholder is replaced with the ordinal of each control
+// plane host in spec.hostnameTemplate.
+const HostnameOrdinalPlaceholder = "{ordinal}"
+
+// RenderHostname returns the hostname of the control plane host with the given
+// ordinal under hostnameTemplate.
+func RenderHostname(hostnameTemplate string, ordinal int) string {
+	return strings.ReplaceAll(hostnameTemplate, HostnameOrdinalPlaceholder, strconv.Itoa(ordinal))
+}
+
+// Platforms the control plane can be installed on.
+const (
+	PlatformBareMetal = "baremetal"
+	PlatformNone      = "none"
+	PlatformVSphere   = "vsphere"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// Methods hosts can boot the discovery image with.
+const (
+	BootMethodISO  = "iso"
+	BootMethodIPXE = "ipxe"
+)
+
+// iPXE scripts the InfraEnv can serve.
+const (
+	IPXEScriptTypeDiscoveryImageAlways = "DiscoveryImageAlways"
+	IPXEScriptTypeBootOrderControl     = "BootOrderControl"
+)
 
 // AgentControlPlaneSpec defines the desired state of AgentControlPlane
 type AgentControlPlaneSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Replicas is the number of desired control plane machines. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// MachineTemplate describes the control plane Machines. Machines are
+	// only created when it is set.
+	// +optional
+	MachineTemplate *AgentControlPlaneMachineTemplate `json:"machineTemplate,omitempty"`
+
+	// ManageMachines lets the controller create the control plane Machines
+	// from spec.machineTemplate, and delete stale ones. When false, the
+	// controller only manages the InfraEnv and the install; other tooling
+	// then takes on what Cluster API expects of a control plane provider:
+	// creating a Machine, labeled with cluster.x-k8s.io/control-plane and
+	// cluster.x-k8s.io/control-plane-name, for each bound host. The
+	// controller still reports those Machines in status.replicas and
+	// status.versionSkew, but applies neither spec.nodeLabels nor
+	// spec.nodeTaints to their Nodes. Defaults to true.
+	// +kubebuilder:default=true
+	// +optional
+	ManageMachines *bool `json:"manageMachines,omitempty"`
+
+	// Version is the OpenShift version to install, e.g. "4.15.0".
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// InfraEnvRecreateOnVersionChange deletes the InfraEnv when spec.version
+	// changes before the control plane is initialized, and creates a fresh
+	// one for the new version, rather than updating it in place. The Agents
+	// discovered through the deleted InfraEnv go with it: hosts must boot the
+	// new discovery image. Defaults to false.
+	// +optional
+	InfraEnvRecreateOnVersionChange bool `json:"infraEnvRecreateOnVersionChange,omitempty"`
+
+	// ReleaseImage is the pull spec of the OpenShift release image to
+	// install, overriding the one derived from Version. Use it to pin the
+	// release by digest, e.g. to a mirror in disconnected environments. It
+	// must be fully qualified, including the registry and a tag or digest.
+	// +optional
+	ReleaseImage string `json:"releaseImage,omitempty"`
+
+	// ClusterImageSetRef references an existing hive ClusterImageSet whose
+	// release is installed, rather than one created from Version or
+	// ReleaseImage. Neither may be set along with it.
+	// +optional
+	ClusterImageSetRef *corev1.LocalObjectReference `json:"clusterImageSetRef,omitempty"`
+
+	// BaseDomain is the base DNS domain of the workload cluster.
+	// +optional
+	BaseDomain string `json:"baseDomain,omitempty"`
+
+	// ClusterNetwork is the list of IP address pools for pods.
+	// +optional
+	ClusterNetwork []CIDRBlock `json:"clusterNetwork,omitempty"`
+
+	// ServiceNetwork is the list of IP address pools for services, in CIDR
+	// notation.
+	// +optional
+	ServiceNetwork []string `json:"serviceNetwork,omitempty"`
+
+	// MachineNetwork is the list of IP address pools for machines, in CIDR
+	// notation.
+	// +optional
+	MachineNetwork []string `json:"machineNetwork,omitempty"`
+
+	// Platform is the platform the cluster is installed on. On baremetal and
+	// vsphere, a control plane of more than one replica requires APIVIPs and
+	// IngressVIPs. With none, the load balancing of the API and ingress is
+	// user managed and VIPs cannot be set.
+	// +kubebuilder:validation:Enum=baremetal;none;vsphere
+	// +kubebuilder:default=baremetal
+	// +optional
+	Platform string `json:"platform,omitempty"`
+
+	// APIVIPs are the virtual IPs used to reach the OpenShift cluster's API.
+	// Each must be within one of the machine networks. Set one address for
+	// single-stack clusters, or an IPv4 and an IPv6 address for dual-stack
+	// clusters. VIPs require platform baremetal or vsphere, which both load
+	// balance the API and ingress through them.
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	APIVIPs []string `json:"apiVIPs,omitempty"`
+
+	// IngressVIPs are the virtual IPs used for cluster ingress traffic. The
+	// same rules as for APIVIPs apply.
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	IngressVIPs []string `json:"ingressVIPs,omitempty"`
+
+	// DiskEncryption configures disk encryption of the installed nodes.
+	// +optional
+	DiskEncryption *DiskEncryption `json:"diskEncryption,omitempty"`
+
+	// FIPS installs the control plane in FIPS mode, restricting its
+	// cryptography to FIPS 140 validated modules. It is set in the install
+	// config at install time, so changing it afterwards has no effect. FIPS
+	// mode is not available on aarch64 releases: the webhook rejects the
+	// releases tagged as aarch64, and warns about the releases pinned by
+	// digest or referenced through a ClusterImageSet, whose architecture it
+	// cannot check.
+	// +optional
+	FIPS bool `json:"fips,omitempty"`
+
+	// ManifestsConfigMapRefs reference ConfigMaps in the AgentControlPlane's
+	// namespace holding extra manifests, or install-config overrides, applied
+	// by assisted-service during the install.
+	// +optional
+	ManifestsConfigMapRefs []corev1.LocalObjectReference `json:"manifestsConfigMapRefs,omitempty"`
+
+	// InlineManifests are extra manifests applied by assisted-service during
+	// the install, like those of ManifestsConfigMapRefs, embedded for the
+	// small ones not worth a ConfigMap of their own. Their names are unique.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	InlineManifests []Manifest `json:"inlineManifests,omitempty"`
+
+	// PullSecretRef references the secret holding the pull secret used by the
+	// discovery image and the installed cluster.
+	// +optional
+	PullSecretRef *corev1.LocalObjectReference `json:"pullSecretRef,omitempty"`
+
+	// InfraEnvNamespace is the namespace the InfraEnv, and so the discovered
+	// Agents, are kept in, for deployments keeping them apart from the
+	// AgentControlPlane. The pull secret is mirrored there; ConfigMaps
+	// referenced by the InfraEnv, such as the mirror registry configuration,
+	// are not. Defaults to the AgentControlPlane's namespace.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="infraEnvNamespace is immutable"
+	// +optional
+	InfraEnvNamespace string `json:"infraEnvNamespace,omitempty"`
+
+	// SSHAuthorizedKey is added to the discovery image so hosts can be
+	// accessed while they are being discovered.
+	// +optional
+	SSHAuthorizedKey string `json:"sshAuthorizedKey,omitempty"`
+
+	// NodeSSHAuthorizedKey is the SSH public key, in authorized_keys format,
+	// installed on the control plane nodes, for access to them once
+	// installed. Unlike SSHAuthorizedKey, it is set in the install config:
+	// changing it after the install has no effect.
+	// +optional
+	NodeSSHAuthorizedKey string `json:"nodeSSHAuthorizedKey,omitempty"`
+
+	// ImageType is the type of discovery image to generate.
+	// +kubebuilder:validation:Enum=full-iso;minimal-iso
+	// +kubebuilder:default=minimal-iso
+	// +optional
+	ImageType string `json:"imageType,omitempty"`
+
+	// BootMethod is how hosts boot the discovery image, selecting the boot
+	// artifacts reported in status.bootArtifacts: the ISO with iso, the iPXE
+	// script and the kernel, initrd and rootfs it loads with ipxe.
+	// +kubebuilder:validation:Enum=iso;ipxe
+	// +kubebuilder:default=iso
+	// +optional
+	BootMethod string `json:"bootMethod,omitempty"`
+
+	// IPXEScriptType is the iPXE script the InfraEnv serves to hosts booting
+	// with ipxe: DiscoveryImageAlways boots the discovery image on every
+	// boot, BootOrderControl boots it until the host is installed and the
+	// installed disk after that, so that hosts need no boot order change.
+	// It is only allowed with the ipxe boot method. assisted-service
+	// defaults it to DiscoveryImageAlways.
+	// +kubebuilder:validation:Enum=DiscoveryImageAlways;BootOrderControl
+	// +optional
+	IPXEScriptType string `json:"ipxeScriptType,omitempty"`
+
+	// BootArtifactsGeneration pins status.bootArtifacts to the discovery
+	// image of an InfraEnv generation, so that hosts keep booting the same
+	// image while the InfraEnv changes. The URLs of the InfraEnv are reported
+	// up to this generation, then kept; raise it, e.g. to the generation the
+	// InfraEnv reached, to boot the current image. The URLs follow the latest
+	// InfraEnv generation when unset.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BootArtifactsGeneration *int64 `json:"bootArtifactsGeneration,omitempty"`
+
+	// KernelArguments are applied to the kernel command line of the discovery
+	// image, e.g. to load or blacklist a driver needed by the hosts' NICs or
+	// storage controllers.
+	// +optional
+	KernelArguments []KernelArgument `json:"kernelArguments,omitempty"`
+
+	// IgnitionConfigOverride is a JSON-formatted ignition config merged into
+	// the discovery image, e.g. to add driver disks or out-of-tree kernel
+	// modules. Changing it regenerates the discovery image.
+	// +optional
+	IgnitionConfigOverride string `json:"ignitionConfigOverride,omitempty"`
+
+	// AgentLogLevel is the log level of the agent running on the discovered
+	// hosts, e.g. debug to troubleshoot a host in the assisted-service logs.
+	// It is set through a drop-in for the agent service merged into the
+	// discovery image's ignition, so changing it regenerates the discovery
+	// image. The agent's default applies when unset.
+	// +kubebuilder:validation:Enum=debug;info;warning;error
+	// +optional
+	AgentLogLevel string `json:"agentLogLevel,omitempty"`
+
+	// AdditionalTrustBundle is a PEM-encoded X.509 certificate bundle trusted
+	// by the discovery image and the installed cluster.
+	// +optional
+	AdditionalTrustBundle string `json:"additionalTrustBundle,omitempty"`
+
+	// AdditionalCAs are PEM-encoded X.509 CA certificates trusted by the
+	// installed control plane. Unlike AdditionalTrustBundle, they are not
+	// added to the discovery image: they are installed as the
+	// additional-trusted-cas ConfigMap in the openshift-config namespace,
+	// through an extra install manifest.
+	// +optional
+	AdditionalCAs []string `json:"additionalCAs,omitempty"`
+
+	// AdditionalTrustBundleConfigMapRef selects a key of a ConfigMap in the
+	// AgentControlPlane's namespace holding more PEM-encoded certificates to
+	// trust, e.g. a copy of the management cluster's trusted CA bundle. They
+	// are merged with AdditionalTrustBundle, dropping duplicates.
+	// +optional
+	AdditionalTrustBundleConfigMapRef *corev1.ConfigMapKeySelector `json:"additionalTrustBundleConfigMapRef,omitempty"`
+
+	// MirrorRegistryRef references a ConfigMap in the AgentControlPlane's
+	// namespace configuring the registry mirrors of the discovered hosts, for
+	// disconnected installs. It holds the mirror configuration in the
+	// registries.conf key and, optionally, the mirrors' CA certificates in
+	// the ca-bundle.crt key.
+	// +optional
+	MirrorRegistryRef *corev1.LocalObjectReference `json:"mirrorRegistryRef,omitempty"`
+
+	// StaticRoutesRef references a ConfigMap in the AgentControlPlane's
+	// namespace holding static routes for the discovered hosts, for networks
+	// the default route does not reach. The routes key holds a YAML list of
+	// routes, each with a destination CIDR, a nextHopAddress of the same IP
+	// family, a nextHopInterface naming the host interface and an optional
+	// metric. They are applied to the hosts through an NMStateConfig
+	// selected by the InfraEnv, so changing them regenerates the discovery
+	// image.
+	// +optional
+	StaticRoutesRef *corev1.LocalObjectReference `json:"staticRoutesRef,omitempty"`
+
+	// NMStateConfigLabelSelector selects NMStateConfigs, in the namespace of
+	// the InfraEnv, holding the static network configuration of the
+	// discovered hosts, such as their addresses. It requires static
+	// networking: spec.machineNetwork must declare the network of the static
+	// addresses, and StaticRoutesRef cannot be set, as the InfraEnv selects
+	// NMStateConfigs by a single selector; put the routes in the selected
+	// NMStateConfigs instead. It cannot be empty, which would select the
+	// NMStateConfigs of every cluster.
+	// +optional
+	NMStateConfigLabelSelector *metav1.LabelSelector `json:"nmStateConfigLabelSelector,omitempty"`
+
+	// Proxy configures the HTTP proxy of the discovered hosts. Fields set
+	// here take precedence over the ones derived through ProxyFrom.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+
+	// ProxyFrom derives the proxy of the discovered hosts from other
+	// objects, so the settings are not duplicated in each AgentControlPlane.
+	// +optional
+	ProxyFrom *ProxySource `json:"proxyFrom,omitempty"`
+
+	// AdditionalNTPSources are NTP servers, by hostname or IP address, the
+	// discovered hosts synchronize their clocks with, in addition to the ones
+	// they learn through DHCP.
+	// +optional
+	AdditionalNTPSources []string `json:"additionalNTPSources,omitempty"`
+
+	// HostSelector restricts the discovered hosts considered for the control
+	// plane to the Agents carrying all of these labels. It is a best-effort
+	// scheduling hint: it narrows the pool the controller binds from, but
+	// does not reserve hosts or guarantee which of the matching ones are
+	// picked.
+	// +optional
+	HostSelector map[string]string `json:"hostSelector,omitempty"`
+
+	// InstallationDisks pins the disk RHCOS is installed on for some of the
+	// hosts. The first hint matching a bound host is applied; hosts matching
+	// no hint use the disk picked by assisted-service, and hints matching no
+	// host are ignored.
+	// +optional
+	InstallationDisks []InstallationDiskHint `json:"installationDisks,omitempty"`
+
+	// MinimumHostRequirements are the least resources a discovered host must
+	// have to be bound as a control plane node. Hosts falling short, or that
+	// have not reported their inventory yet, are skipped.
+	// +optional
+	MinimumHostRequirements *HostRequirements `json:"minimumHostRequirements,omitempty"`
+
+	// HostnameTemplate names the control plane hosts. Each bound host gets
+	// the template with "{ordinal}" replaced by a number from 0 to
+	// spec.replicas-1, e.g. "cp-{ordinal}" names them cp-0, cp-1 and so on.
+	// A host keeps its ordinal once named. The hostnames the hosts report
+	// are used when unset.
+	// +optional
+	HostnameTemplate string `json:"hostnameTemplate,omitempty"`
+
+	// HostIgnitionOverrides maps the hostnames of control plane hosts to a
+	// JSON-formatted ignition config merged into the config that host is
+	// installed with, e.g. to configure a NIC only one host has. A host
+	// matches by the hostname it is assigned, or else the one it reports.
+	// Hosts without an entry keep the override set on their Agent, if any.
+	// +optional
+	HostIgnitionOverrides map[string]string `json:"hostIgnitionOverrides,omitempty"`
+
+	// FailureGracePeriod is how long a failed install is tolerated before it
+	// is recorded as a terminal failure in status.failureReason and
+	// status.failureMessage, giving assisted-service a chance to recover from
+	// transient errors. A failure that clears within the period is not
+	// recorded. Failures are recorded immediately when unset.
+	// +optional
+	FailureGracePeriod *metav1.Duration `json:"failureGracePeriod,omitempty"`
+
+	// MaxInstallRetries is how many times a failed install is retried, by
+	// recreating its AgentClusterInstall, before the failure is recorded as
+	// terminal. Timeouts are not retried. Failed installs are not retried
+	// when unset.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	MaxInstallRetries *int32 `json:"maxInstallRetries,omitempty"`
+
+	// NodeLabels are set on the Nodes backing control plane Machines once
+	// they join the workload cluster. Labels removed from this map are
+	// removed from the Nodes as well.
+	// +optional
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+
+	// NodeTaints are set on the Nodes backing control plane Machines once
+	// they join the workload cluster. Taints removed from this list are
+	// removed from the Nodes as well.
+	// +optional
+	NodeTaints []corev1.Taint `json:"nodeTaints,omitempty"`
+}
+
+// AgentControlPlaneMachineTemplate describes the control plane Machines.
+type AgentControlPlaneMachineTemplate struct {
+	// InfrastructureRef references the infrastructure template, in the
+	// AgentControlPlane's namespace, the infrastructure of each control plane
+	// Machine is cloned from.
+	InfrastructureRef corev1.ObjectReference `json:"infrastructureRef"`
+
+	// HealthCheckLabels are set on the control plane Machines, and their
+	// infrastructure objects, when they are created, for MachineHealthChecks
+	// to select them on. The labels the controller selects the Machines by,
+	// cluster.x-k8s.io/cluster-name, cluster.x-k8s.io/control-plane and
+	// cluster.x-k8s.io/control-plane-name, are reserved and cannot be set.
+	// Changes only apply to Machines created afterwards. The controller does
+	// not remediate the Machines a MachineHealthCheck finds unhealthy: they are
+	// reported as such until replaced by hand, so that quorum is never put at
+	// risk automatically.
+	// +optional
+	HealthCheckLabels map[string]string `json:"healthCheckLabels,omitempty"`
+
+	// FailureDomains spread the control plane Machines, e.g. across racks
+	// or zones: each Machine created is placed in the failure domain holding
+	// the fewest control plane Machines, in list order on ties. When Agents
+	// are labeled with topology.kubernetes.io/zone, the failure domains with
+	// more available Agents than Machines are preferred, so that each Machine
+	// has a host to bind. Machines are created one at a time and never moved
+	// to rebalance them, as that would put etcd quorum at risk. The failure
+	// domains of the Cluster marked for the control plane are used when
+	// unset.
+	// +optional
+	FailureDomains []string `json:"failureDomains,omitempty"`
+
+	// NodeDrainTimeout is how long the Machine controller drains the Node of
+	// a deleted Machine before giving up on it. Zero drains for as long as
+	// it takes. Defaults to 10m.
+	// +optional
+	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
+
+	// NodeVolumeDetachTimeout is how long the Machine controller waits for
+	// the volumes of a deleted Machine's Node to be detached. Zero waits for
+	// as long as it takes. Defaults to 5m.
+	// +optional
+	NodeVolumeDetachTimeout *metav1.Duration `json:"nodeVolumeDetachTimeout,omitempty"`
+
+	// NodeDeletionTimeout is how long the Machine controller retries
+	// deleting the Node of a deleted Machine once its host is gone. Zero
+	// retries for as long as it takes. Defaults to 10s.
+	// +optional
+	NodeDeletionTimeout *metav1.Duration `json:"nodeDeletionTimeout,omitempty"`
+}
+
+// DiskEncryption configures LUKS disk encryption of the installed nodes.
+type DiskEncryption struct {
+	// EnableOn selects the nodes whose disks are encrypted.
+	// +kubebuilder:default=none
+	// +kubebuilder:validation:Enum=none;all;masters;workers
+	// +optional
+	EnableOn string `json:"enableOn,omitempty"`
+
+	// Mode is how the encryption key is bound: to the host's TPM 2.0 chip,
+	// or to network-bound Tang servers.
+	// +kubebuilder:validation:Enum=tpmv2;tang
+	// +optional
+	Mode string `json:"mode,omitempty"`
 
-	// Foo is an example field of AgentControlPlane. Edit agentcontrolplane_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	// TangServers are the Tang servers the key is bound to. Required when
+	// mode is tang.
+	// +optional
+	TangServers []TangServer `json:"tangServers,omitempty"`
 }
 
+// TangServer is a Tang server disk encryption keys are bound to.
+type TangServer struct {
+	// URL is the http or https URL of the Tang server.
+	URL string `json:"url"`
+
+	// Thumbprint is the base64url-encoded thumbprint of the Tang server's
+	// signing key.
+	Thumbprint string `json:"thumbprint"`
+}
+
+// HostRequirements are resources a host must have.
+type HostRequirements struct {
+	// CPUCores is the minimum number of CPU cores.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CPUCores int64 `json:"cpuCores,omitempty"`
+
+	// Memory is the minimum physical memory, e.g. 16Gi.
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+}
+
+// InstallationDiskHint selects the installation disk of the hosts matching
+// either a hostname or a label selector.
+type InstallationDiskHint struct {
+	// Hostname matches the host with this hostname.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// Selector matches the hosts whose Agent carries all of these labels.
+	// +optional
+	Selector map[string]string `json:"selector,omitempty"`
+
+	// DiskID is the ID of the disk to install on, as reported in the Agent's
+	// inventory, e.g. "/dev/disk/by-id/wwn-0x5000c50015ea71ac".
+	DiskID string `json:"diskID"`
+}
+
+// ManifestEncodingBase64 is the encoding of a Manifest whose content is
+// base64-encoded.
+const ManifestEncodingBase64 = "base64"
+
+// Manifest is an extra install manifest.
+type Manifest struct {
+	// Name is the file name of the manifest, e.g. 50-chrony.yaml.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Content is the YAML of the manifest, base64-encoded when Encoding is
+	// base64.
+	Content string `json:"content"`
+
+	// Encoding is the encoding of Content, plain text when unset.
+	// +kubebuilder:validation:Enum=base64
+	// +optional
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Decode returns the YAML of the manifest.
+func (m Manifest) Decode() (string, error) {
+	if m.Encoding != ManifestEncodingBase64 {
+		return m.Content, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(m.Content)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// KernelArgument is a change to the kernel command line of the discovery
+// image.
+type KernelArgument struct {
+	// Operation is the operation to apply on the kernel argument.
+	// +kubebuilder:validation:Enum=append;replace;delete
+	Operation string `json:"operation,omitempty"`
+
+	// Value can have the form <parameter> or <parameter>=<value>.
+	// +kubebuilder:validation:Pattern=`^(?:(?:[^ \t\n\r"]+)|(?:"[^"]*"))+$`
+	Value string `json:"value,omitempty"`
+}
+
+// Proxy is the HTTP proxy configuration of the discovered hosts.
+type Proxy struct {
+	// HTTPProxy is the URL of the proxy for HTTP requests.
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the URL of the proxy for HTTPS requests.
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma-separated list of destination domain names,
+	// domains, IP addresses or CIDRs to exclude from proxying.
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+// ProxySource selects where the proxy of the discovered hosts is derived
+// from. When both are set, the ConfigMap provides the proxy URLs and both
+// contribute to noProxy.
+type ProxySource struct {
+	// ClusterNetwork excludes the pod and service networks and the service
+	// domain of the owner Cluster's spec.clusterNetwork from proxying.
+	// +optional
+	ClusterNetwork bool `json:"clusterNetwork,omitempty"`
+
+	// ConfigMapRef references a ConfigMap in the AgentControlPlane's
+	// namespace holding the proxy in the httpProxy, httpsProxy and noProxy
+	// keys, e.g. a copy of the management cluster's proxy settings.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+}
+
+// CIDRBlock is an IP address pool from which pod IPs are allocated.
+type CIDRBlock struct {
+	// CIDR is the IP address pool, in CIDR notation.
+	CIDR string `json:"cidr"`
+
+	// HostPrefix is the prefix size to allocate to each node from the CIDR.
+	// For example, 24 would allocate 2^8=256 addresses to each node.
+	// +optional
+	HostPrefix int32 `json:"hostPrefix,omitempty"`
+}
+
+// BootArtifacts are the URLs of the artifacts booting the discovery image.
+// Only those of the configured boot method are set.
+type BootArtifacts struct {
+	// ISOURL is the URL of the discovery ISO.
+	// +optional
+	ISOURL string `json:"isoURL,omitempty"`
+
+	// IPXEScriptURL is the URL of the iPXE script booting the kernel,
+	// initrd and rootfs.
+	// +optional
+	IPXEScriptURL string `json:"ipxeScriptURL,omitempty"`
+
+	// KernelURL is the URL of the kernel.
+	// +optional
+	KernelURL string `json:"kernelURL,omitempty"`
+
+	// InitrdURL is the URL of the initrd.
+	// +optional
+	InitrdURL string `json:"initrdURL,omitempty"`
+
+	// RootfsURL is the URL of the rootfs.
+	// +optional
+	RootfsURL string `json:"rootfsURL,omitempty"`
+
+	// InfraEnvGeneration is the generation of the InfraEnv the URLs were
+	// reported for.
+	// +optional
+	InfraEnvGeneration int64 `json:"infraEnvGeneration,omitempty"`
+}
+
+// MaxAgentSummaries is the largest number of Agents summarized in the
+// status of an AgentControlPlane.
+const MaxAgentSummaries = 16
+
+// AgentSummary is the state of an Agent bound to the control plane.
+type AgentSummary struct {
+	// Name is the name of the Agent.
+	Name string `json:"name"`
+
+	// Role is the role the host is installed with.
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// Stage is the installation stage the host is at.
+	// +optional
+	Stage string `json:"stage,omitempty"`
+
+	// Ready denotes that the host meets the requirements to be installed.
+	Ready bool `json:"ready"`
+}
+
+// VersionSkew is the range of versions the control plane Machines run.
+type VersionSkew struct {
+	// MinVersion is the oldest version a control plane Machine runs.
+	MinVersion string `json:"minVersion"`
+
+	// MaxVersion is the newest version a control plane Machine runs.
+	MaxVersion string `json:"maxVersion"`
+}
+
+// AgentControlPlanePhase summarizes the state of an AgentControlPlane for
+// humans and dashboards. Automation should rely on the conditions instead.
+type AgentControlPlanePhase string
+
+const (
+	// AgentControlPlanePhaseProvisioning is the phase of a control plane
+	// waiting for its dependencies, hosts or Machines.
+	AgentControlPlanePhaseProvisioning AgentControlPlanePhase = "Provisioning"
+
+	// AgentControlPlanePhaseInstalling is the phase of a control plane whose
+	// desired number of hosts is bound, or that is installed but not yet
+	// reachable.
+	AgentControlPlanePhaseInstalling AgentControlPlanePhase = "Installing"
+
+	// AgentControlPlanePhaseReady is the phase of a control plane ready to
+	// serve requests.
+	AgentControlPlanePhaseReady AgentControlPlanePhase = "Ready"
+
+	// AgentControlPlanePhaseFailed is the phase of a control plane with a
+	// terminal failure recorded in status.failureReason.
+	AgentControlPlanePhaseFailed AgentControlPlanePhase = "Failed"
+
+	// AgentControlPlanePhaseDeleting is the phase of a control plane being
+	// deleted.
+	AgentControlPlanePhaseDeleting AgentControlPlanePhase = "Deleting"
+)
+
 // AgentControlPlaneStatus defines the observed state of AgentControlPlane
 type AgentControlPlaneStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Initialized denotes whether the control plane API server has been
+	// installed and can accept requests.
+	// +optional
+	Initialized bool `json:"initialized"`
+
+	// Ready denotes that the control plane is ready to serve requests.
+	// +optional
+	Ready bool `json:"ready"`
+
+	// Replicas is the number of control plane Machines, whether the
+	// controller created them or, with spec.manageMachines false, other
+	// tooling did.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Phase summarizes the state of the control plane. The first of these
+	// applies: Deleting while being deleted, Failed while status.failureReason
+	// is set, Ready while status.ready is, Installing once initialized or
+	// once the desired number of hosts is bound, and Provisioning otherwise.
+	// +kubebuilder:validation:Enum=Provisioning;Installing;Ready;Failed;Deleting
+	// +optional
+	Phase AgentControlPlanePhase `json:"phase,omitempty"`
+
+	// InfraEnvCreated is set once the InfraEnv of the control plane was
+	// created or adopted, so that its deletion can be told apart from it not
+	// having been created yet.
+	// +optional
+	InfraEnvCreated bool `json:"infraEnvCreated,omitempty"`
+
+	// BootArtifacts are the URLs of the discovery image artifacts hosts boot
+	// with spec.bootMethod, once the InfraEnv reports them.
+	// +optional
+	BootArtifacts *BootArtifacts `json:"bootArtifacts,omitempty"`
+
+	// Agents summarizes the Agents bound to the control plane, sorted by
+	// name.
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Agents []AgentSummary `json:"agents,omitempty"`
+
+	// VersionSkew is set while the control plane Machines run different
+	// versions, e.g. midway through an upgrade, and cleared once they
+	// converge.
+	// +optional
+	VersionSkew *VersionSkew `json:"versionSkew,omitempty"`
+
+	// FailureReason indicates that there is a terminal problem reconciling
+	// the control plane, meant to be suitable for programmatic interpretation.
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// FailureMessage indicates that there is a terminal problem reconciling
+	// the control plane, meant to be suitable for humans.
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// KubeconfigRotation is the value of the RotateKubeconfigAnnotation
+	// handled last.
+	// +optional
+	KubeconfigRotation string `json:"kubeconfigRotation,omitempty"`
+
+	// FailingSince is when the install was first seen failing. It is reset
+	// once the install no longer fails.
+	// +optional
+	FailingSince *metav1.Time `json:"failingSince,omitempty"`
+
+	// FailedGeneration is the generation of the spec a non-retryable failure
+	// was recorded for. Once the spec changes, the failure is cleared and the
+	// install restarted.
+	// +optional
+	FailedGeneration int64 `json:"failedGeneration,omitempty"`
+
+	// InstallRetries is how many times the failed install was retried, up to
+	// spec.maxInstallRetries.
+	// +optional
+	InstallRetries int32 `json:"installRetries,omitempty"`
+
+	// Conditions defines current service state of the AgentControlPlane.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Phase of the control plane"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // AgentControlPlane is the Schema for the agentcontrolplanes API
 type AgentControlPlane struct {
@@ -50,6 +808,16 @@ type AgentControlPlane struct {
 	Status AgentControlPlaneStatus `json:"status,omitempty"`
 }
 
+// GetConditions returns the set of conditions for this object.
+func (c *AgentControlPlane) GetConditions() clusterv1.Conditions {
+	return c.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (c *AgentControlPlane) SetConditions(conditions clusterv1.Conditions) {
+	c.Status.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // AgentControlPlaneList contains a list of AgentControlPlane
@@ -0,0 +1,828 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"golang.org/x/crypto/ssh"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultMaxReplicas is the largest control plane allowed unless configured
+// otherwise. etcd gains little from more members, and each one is a
+// bare-metal host taken from the pool.
+const DefaultMaxReplicas = 5
+
+// The defaults of the Machine timeouts of spec.machineTemplate.
+const (
+	DefaultNodeDrainTimeout        = 10 * time.Minute
+	DefaultNodeVolumeDetachTimeout = 5 * time.Minute
+	DefaultNodeDeletionTimeout     = 10 * time.Second
+)
+
+// reservedMachineLabels are the labels the controller sets on the control
+// plane Machines and selects them by, which spec.machineTemplate cannot set.
+var reservedMachineLabels = []string{
+	clusterv1.ClusterNameLabel,
+	clusterv1.MachineControlPlaneLabel,
+	clusterv1.MachineControlPlaneNameLabel,
+}
+
+// WebhookOptions configures the AgentControlPlane webhooks.
+type WebhookOptions struct {
+	// MaxReplicas is the largest allowed spec.replicas. It must be odd.
+	// DefaultMaxReplicas is used when it is zero.
+	MaxReplicas int32
+}
+
+// SetupWebhookWithManager will setup the manager to manage the webhooks
+func (r *AgentControlPlane) SetupWebhookWithManager(mgr ctrl.Manager, opts WebhookOptions) error {
+	if opts.MaxReplicas < 0 || (opts.MaxReplicas > 0 && opts.MaxReplicas%2 == 0) {
+		return fmt.Errorf("the maximum number of replicas must be a positive odd number, got %d", opts.MaxReplicas)
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&agentControlPlaneDefaulter{}).
+		WithValidator(&agentControlPlaneValidator{maxReplicas: opts.MaxReplicas}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-controlplane-openshift-io-v1-agentcontrolplane,mutating=true,failurePolicy=fail,sideEffects=None,groups=controlplane.openshift.io,resources=agentcontrolplanes,verbs=create;update,versions=v1,name=magentcontrolplane.kb.io,admissionReviewVersions=v1
+
+// agentControlPlaneDefaulter normalizes AgentControlPlanes on create and
+// update, so that equivalent specs are stored alike and do not show up as
+// changes in GitOps diffs.
+type agentControlPlaneDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &agentControlPlaneDefaulter{}
+
+// Default implements webhook.CustomDefaulter.
+func (d *agentControlPlaneDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	acp, err := toAgentControlPlane(obj)
+	if err != nil {
+		return err
+	}
+	acp.normalizeDurations()
+	acp.Spec.MachineTemplate.defaultTimeouts()
+	return nil
+}
+
+// defaultTimeouts sets the unset Machine timeouts of t to their defaults.
+func (t *AgentControlPlaneMachineTemplate) defaultTimeouts() {
+	if t == nil {
+		return
+	}
+	if t.NodeDrainTimeout == nil {
+		t.NodeDrainTimeout = &metav1.Duration{Duration: DefaultNodeDrainTimeout}
+	}
+	if t.NodeVolumeDetachTimeout == nil {
+		t.NodeVolumeDetachTimeout = &metav1.Duration{Duration: DefaultNodeVolumeDetachTimeout}
+	}
+	if t.NodeDeletionTimeout == nil {
+		t.NodeDeletionTimeout = &metav1.Duration{Duration: DefaultNodeDeletionTimeout}
+	}
+}
+
+// durationField is a duration field of the spec.
+type durationField struct {
+	path  *field.Path
+	value **metav1.Duration
+	// zeroIsSet tells that zero differs from unset, as for timeouts where
+	// zero waits forever.
+	zeroIsSet bool
+}
+
+// durationFields returns the duration fields of the spec.
+func (r *AgentControlPlane) durationFields() []durationField {
+	fields := []durationField{
+		{path: field.NewPath("spec", "failureGracePeriod"), value: &r.Spec.FailureGracePeriod},
+	}
+	if template := r.Spec.MachineTemplate; template != nil {
+		path := field.NewPath("spec", "machineTemplate")
+		fields = append(fields,
+			durationField{path: path.Child("nodeDrainTimeout"), value: &template.NodeDrainTimeout, zeroIsSet: true},
+			durationField{path: path.Child("nodeVolumeDetachTimeout"), value: &template.NodeVolumeDetachTimeout, zeroIsSet: true},
+			durationField{path: path.Child("nodeDeletionTimeout"), value: &template.NodeDeletionTimeout, zeroIsSet: true},
+		)
+	}
+	return fields
+}
+
+// normalizeDurations unsets the zero duration fields that behave as if
+// unset. The others are written in their canonical form, e.g. "1h30m0s" for
+// "90m", when the object is serialized again.
+func (r *AgentControlPlane) normalizeDurations() {
+	for _, f := range r.durationFields() {
+		if *f.value != nil && (*f.value).Duration == 0 && !f.zeroIsSet {
+			*f.value = nil
+		}
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-controlplane-openshift-io-v1-agentcontrolplane,mutating=false,failurePolicy=fail,sideEffects=None,groups=controlplane.openshift.io,resources=agentcontrolplanes,verbs=create;update;delete,versions=v1,name=vagentcontrolplane.kb.io,admissionReviewVersions=v1
+
+// agentControlPlaneValidator validates AgentControlPlanes on create and
+// update, and refuses deleting them mid-install.
+type agentControlPlaneValidator struct {
+	// maxReplicas is the largest allowed spec.replicas; DefaultMaxReplicas
+	// when zero.
+	maxReplicas int32
+}
+
+var _ webhook.CustomValidator = &agentControlPlaneValidator{}
+
+// ValidateCreate implements webhook.CustomValidator.
+func (v *agentControlPlaneValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	acp, err := toAgentControlPlane(obj)
+	if err != nil {
+		return nil, err
+	}
+	return acp.fipsWarnings(), acp.validate(v.maxReplicas)
+}
+
+// ValidateUpdate implements webhook.CustomValidator.
+func (v *agentControlPlaneValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	acp, err := toAgentControlPlane(newObj)
+	if err != nil {
+		return nil, err
+	}
+	return acp.fipsWarnings(), acp.validate(v.maxReplicas)
+}
+
+// ValidateDelete implements webhook.CustomValidator. It refuses deleting an
+// AgentControlPlane whose install is in progress unless the
+// ForceDeleteAnnotation is set.
+func (v *agentControlPlaneValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	acp, err := toAgentControlPlane(obj)
+	if err != nil {
+		return nil, err
+	}
+	if !acp.installing() {
+		return nil, nil
+	}
+	if acp.Annotations[ForceDeleteAnnotation] == "true" {
+		return admission.Warnings{"deleting the AgentControlPlane while its control plane is installing; the hosts may need to be reprovisioned"}, nil
+	}
+	return nil, apierrors.NewForbidden(GroupVersion.WithResource("agentcontrolplanes").GroupResource(), acp.Name,
+		fmt.Errorf("the control plane is installing and deleting it may leave the hosts half-provisioned; "+
+			"wait for the install to finish or fail, or set the %s annotation to \"true\" to delete it anyway", ForceDeleteAnnotation))
+}
+
+// installing reports whether the install of the control plane is in
+// progress, including while a failed install is retried. An install that has
+// not started yet, e.g. with hosts that never booted, is not in progress.
+func (r *AgentControlPlane) installing() bool {
+	if !conditions.IsFalse(r, InstallCompleteCondition) {
+		return false
+	}
+	reason := conditions.GetReason(r, InstallCompleteCondition)
+	return reason == InstallInProgressReason || reason == InstallRetryingReason
+}
+
+func toAgentControlPlane(obj runtime.Object) (*AgentControlPlane, error) {
+	acp, ok := obj.(*AgentControlPlane)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected an AgentControlPlane but got a %T", obj))
+	}
+	return acp, nil
+}
+
+func (r *AgentControlPlane) validate(maxReplicas int32) error {
+	allErrs := r.validateReplicas(maxReplicas)
+	allErrs = append(allErrs, r.validateNetworks()...)
+	allErrs = append(allErrs, r.validateVIPs()...)
+	allErrs = append(allErrs, r.validatePlatform()...)
+	allErrs = append(allErrs, r.validateDualStack()...)
+	allErrs = append(allErrs, r.validateNMStateConfigLabelSelector()...)
+	allErrs = append(allErrs, r.validateReleaseImage()...)
+	allErrs = append(allErrs, r.validateClusterImageSetRef()...)
+	allErrs = append(allErrs, r.validateFIPS()...)
+	allErrs = append(allErrs, r.validateNodeSSHAuthorizedKey()...)
+	allErrs = append(allErrs, r.validateIPXEScriptType()...)
+	allErrs = append(allErrs, r.validateDiskEncryption()...)
+	allErrs = append(allErrs, r.validateHostnameTemplate()...)
+	allErrs = append(allErrs, r.validateHostIgnitionOverrides()...)
+	allErrs = append(allErrs, r.validateHealthCheckLabels()...)
+	allErrs = append(allErrs, r.validateAdditionalCAs()...)
+	allErrs = append(allErrs, r.validateInlineManifests()...)
+	allErrs = append(allErrs, r.validateDurations()...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("AgentControlPlane").GroupKind(), r.Name, allErrs)
+}
+
+// validateReplicas checks that the control plane has a positive, odd number
+// of replicas, so etcd keeps quorum through as many failures as possible, and
+// no more than maxReplicas.
+func (r *AgentControlPlane) validateReplicas(maxReplicas int32) field.ErrorList {
+	if r.Spec.Replicas == nil {
+		return nil
+	}
+	if maxReplicas == 0 {
+		maxReplicas = DefaultMaxReplicas
+	}
+
+	path := field.NewPath("spec", "replicas")
+	replicas := *r.Spec.Replicas
+	switch {
+	case replicas < 1:
+		return field.ErrorList{field.Invalid(path, replicas, "must be at least 1")}
+	case replicas%2 == 0:
+		return field.ErrorList{field.Invalid(path, replicas, "must be an odd number to preserve etcd quorum")}
+	case replicas > maxReplicas:
+		return field.ErrorList{field.Invalid(path, replicas, fmt.Sprintf("must be at most %d", maxReplicas))}
+	}
+	return nil
+}
+
+// validateNetworks checks that the cluster, service and machine networks are
+// valid CIDRs and that none of them overlap.
+func (r *AgentControlPlane) validateNetworks() field.ErrorList {
+	type network struct {
+		path   *field.Path
+		prefix netip.Prefix
+	}
+
+	var allErrs field.ErrorList
+	var networks []network
+	add := func(path *field.Path, cidr string) {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path, cidr, "must be a valid CIDR"))
+			return
+		}
+		for _, n := range networks {
+			if n.prefix.Overlaps(prefix) {
+				allErrs = append(allErrs, field.Invalid(path, cidr, fmt.Sprintf("overlaps with %s (%s)", n.path, n.prefix)))
+			}
+		}
+		networks = append(networks, network{path: path, prefix: prefix})
+	}
+
+	specPath := field.NewPath("spec")
+	for i, block := range r.Spec.ClusterNetwork {
+		add(specPath.Child("clusterNetwork").Index(i).Child("cidr"), block.CIDR)
+	}
+	for i, cidr := range r.Spec.ServiceNetwork {
+		add(specPath.Child("serviceNetwork").Index(i), cidr)
+	}
+	for i, cidr := range r.Spec.MachineNetwork {
+		add(specPath.Child("machineNetwork").Index(i), cidr)
+	}
+	return allErrs
+}
+
+// validateVIPs checks that the API and ingress VIPs are IP addresses within
+// the machine networks, with at most one address per IP family.
+func (r *AgentControlPlane) validateVIPs() field.ErrorList {
+	var machineNetworks []netip.Prefix
+	for _, cidr := range r.Spec.MachineNetwork {
+		// Unparseable machine networks are reported by validateNetworks.
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			machineNetworks = append(machineNetworks, prefix)
+		}
+	}
+
+	var allErrs field.ErrorList
+	validate := func(path *field.Path, vips []string) {
+		families := map[bool]bool{}
+		for i, vip := range vips {
+			addr, err := netip.ParseAddr(vip)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(path.Index(i), vip, "must be a valid IP address"))
+				continue
+			}
+			if families[addr.Is4()] {
+				allErrs = append(allErrs, field.Invalid(path.Index(i), vip,
+					"at most one address per IP family may be set; use one IPv4 and one IPv6 address for dual-stack"))
+				continue
+			}
+			families[addr.Is4()] = true
+			if !containsAddr(machineNetworks, addr) {
+				allErrs = append(allErrs, field.Invalid(path.Index(i), vip, "must be within one of spec.machineNetwork"))
+			}
+		}
+	}
+
+	specPath := field.NewPath("spec")
+	validate(specPath.Child("apiVIPs"), r.Spec.APIVIPs)
+	validate(specPath.Child("ingressVIPs"), r.Spec.IngressVIPs)
+	return allErrs
+}
+
+// validatePlatform checks that VIPs are set when the platform load balances
+// a multi-node control plane through them, and not set when it does not use
+// them.
+func (r *AgentControlPlane) validatePlatform() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	switch r.Spec.Platform {
+	case PlatformNone:
+		if len(r.Spec.APIVIPs) > 0 {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("apiVIPs"), "VIPs cannot be set on platform none"))
+		}
+		if len(r.Spec.IngressVIPs) > 0 {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("ingressVIPs"), "VIPs cannot be set on platform none"))
+		}
+	default:
+		// A single-node control plane is reached on its node's address.
+		if r.Spec.Replicas == nil || *r.Spec.Replicas <= 1 {
+			break
+		}
+		platform := r.Spec.Platform
+		if platform == "" {
+			platform = PlatformBareMetal
+		}
+		if len(r.Spec.APIVIPs) == 0 {
+			allErrs = append(allErrs, field.Required(specPath.Child("apiVIPs"),
+				fmt.Sprintf("required on platform %s with more than one replica", platform)))
+		}
+		if len(r.Spec.IngressVIPs) == 0 {
+			allErrs = append(allErrs, field.Required(specPath.Child("ingressVIPs"),
+				fmt.Sprintf("required on platform %s with more than one replica", platform)))
+		}
+	}
+	return allErrs
+}
+
+// validateDualStack checks that a dual-stack cluster, one with networks of
+// both IP families, has both families in each of its cluster, service and
+// machine networks, with the same primary family, the one of their first
+// CIDR. The VIPs must start with an address of the primary family too.
+func (r *AgentControlPlane) validateDualStack() field.ErrorList {
+	specPath := field.NewPath("spec")
+	var clusterNetwork []string
+	for _, block := range r.Spec.ClusterNetwork {
+		clusterNetwork = append(clusterNetwork, block.CIDR)
+	}
+	networks := []struct {
+		path  *field.Path
+		cidrs []string
+	}{
+		{specPath.Child("clusterNetwork"), clusterNetwork},
+		{specPath.Child("serviceNetwork"), r.Spec.ServiceNetwork},
+		{specPath.Child("machineNetwork"), r.Spec.MachineNetwork},
+	}
+
+	var primary string
+	dualStack := false
+	for _, network := range networks {
+		families := ipFamilies(network.cidrs)
+		if primary == "" && len(families) > 0 {
+			primary = families[0]
+		}
+		if len(families) == 2 {
+			dualStack = true
+		}
+	}
+	if !dualStack {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	for _, network := range networks {
+		families := ipFamilies(network.cidrs)
+		switch {
+		// Unset networks are defaulted by the installer.
+		case len(families) == 0:
+		case families[0] != primary:
+			allErrs = append(allErrs, field.Invalid(network.path.Index(0), network.cidrs[0],
+				fmt.Sprintf("must be an %s CIDR: the first CIDR of each network sets the primary IP family of a dual-stack cluster", primary)))
+		case len(families) == 1:
+			allErrs = append(allErrs, field.Invalid(network.path, network.cidrs,
+				"must have both an IPv4 and an IPv6 CIDR in a dual-stack cluster"))
+		}
+	}
+	for _, vips := range []struct {
+		path  *field.Path
+		addrs []string
+	}{
+		{specPath.Child("apiVIPs"), r.Spec.APIVIPs},
+		{specPath.Child("ingressVIPs"), r.Spec.IngressVIPs},
+	} {
+		if len(vips.addrs) == 0 {
+			continue
+		}
+		// Unparseable VIPs are reported by validateVIPs.
+		if addr, err := netip.ParseAddr(vips.addrs[0]); err == nil && ipFamily(addr) != primary {
+			allErrs = append(allErrs, field.Invalid(vips.path.Index(0), vips.addrs[0],
+				fmt.Sprintf("must be an %s address, the primary IP family of the dual-stack cluster", primary)))
+		}
+	}
+	return allErrs
+}
+
+// validateNMStateConfigLabelSelector checks that the NMStateConfig selector is
+// valid and not empty, and that the static networking it implies is
+// configured: a machine network, and no static routes of the controller's own
+// competing for the InfraEnv selector.
+func (r *AgentControlPlane) validateNMStateConfigLabelSelector() field.ErrorList {
+	selector := r.Spec.NMStateConfigLabelSelector
+	if selector == nil {
+		return nil
+	}
+
+	path := field.NewPath("spec", "nmStateConfigLabelSelector")
+	var allErrs field.ErrorList
+	if s, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		allErrs = append(allErrs, field.Invalid(path, selector, err.Error()))
+	} else if s.Empty() {
+		allErrs = append(allErrs, field.Invalid(path, selector, "must not be empty, which would select the NMStateConfigs of every cluster"))
+	}
+	if len(r.Spec.MachineNetwork) == 0 {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "machineNetwork"),
+			"required with spec.nmStateConfigLabelSelector, for the static addresses of the hosts"))
+	}
+	if r.Spec.StaticRoutesRef != nil {
+		allErrs = append(allErrs, field.Forbidden(path,
+			"must not be set along with spec.staticRoutesRef; put the static routes in the selected NMStateConfigs instead"))
+	}
+	return allErrs
+}
+
+// ipFamilies returns the IP families of the CIDRs, in the order they first
+// appear. Unparseable CIDRs are reported by validateNetworks.
+func ipFamilies(cidrs []string) []string {
+	var families []string
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if family := ipFamily(prefix.Addr()); !slices.Contains(families, family) {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+func ipFamily(addr netip.Addr) string {
+	if addr.Is4() {
+		return "IPv4"
+	}
+	return "IPv6"
+}
+
+// validateReleaseImage checks that the release image is a fully qualified
+// image reference, pinned by tag or digest.
+func (r *AgentControlPlane) validateReleaseImage() field.ErrorList {
+	if r.Spec.ReleaseImage == "" {
+		return nil
+	}
+
+	path := field.NewPath("spec", "releaseImage")
+	named, err := reference.ParseNamed(r.Spec.ReleaseImage)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, r.Spec.ReleaseImage,
+			fmt.Sprintf("must be a fully qualified image reference: %v", err))}
+	}
+	_, tagged := named.(reference.Tagged)
+	_, digested := named.(reference.Digested)
+	if !tagged && !digested {
+		return field.ErrorList{field.Invalid(path, r.Spec.ReleaseImage, "must include a tag or digest")}
+	}
+	return nil
+}
+
+// validateClusterImageSetRef checks that a referenced ClusterImageSet is
+// named and is the only source of the release to install.
+func (r *AgentControlPlane) validateClusterImageSetRef() field.ErrorList {
+	if r.Spec.ClusterImageSetRef == nil {
+		return nil
+	}
+
+	path := field.NewPath("spec", "clusterImageSetRef")
+	var allErrs field.ErrorList
+	if r.Spec.ClusterImageSetRef.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("name"), "must name a ClusterImageSet"))
+	}
+	if r.Spec.Version != "" {
+		allErrs = append(allErrs, field.Forbidden(path, "must not be set along with spec.version"))
+	}
+	if r.Spec.ReleaseImage != "" {
+		allErrs = append(allErrs, field.Forbidden(path, "must not be set along with spec.releaseImage"))
+	}
+	return allErrs
+}
+
+// validateFIPS checks that FIPS mode is not requested for an aarch64
+// release, on which it is not available. The architecture is read from the
+// tag of spec.releaseImage; releases derived from spec.version are x86_64.
+// Releases of an unknown architecture are left to fipsWarnings.
+func (r *AgentControlPlane) validateFIPS() field.ErrorList {
+	if !r.Spec.FIPS {
+		return nil
+	}
+	if tag, ok := releaseImageTag(r.Spec.ReleaseImage); ok &&
+		(strings.HasSuffix(tag, "-aarch64") || strings.HasSuffix(tag, "-arm64")) {
+		return field.ErrorList{field.Forbidden(field.NewPath("spec", "fips"),
+			"FIPS mode is not available on aarch64 releases")}
+	}
+	return nil
+}
+
+// fipsWarnings warns that FIPS mode is requested for a release whose
+// architecture the webhook cannot check: a release image pinned by digest
+// alone, or the release of a referenced ClusterImageSet, which the webhook
+// does not read. An aarch64 release then only fails the install.
+func (r *AgentControlPlane) fipsWarnings() admission.Warnings {
+	if !r.Spec.FIPS {
+		return nil
+	}
+	switch {
+	case r.Spec.ClusterImageSetRef != nil:
+		return admission.Warnings{fmt.Sprintf("spec.fips: the architecture of the release of ClusterImageSet %s is not checked; "+
+			"FIPS mode is not available on aarch64 releases", r.Spec.ClusterImageSetRef.Name)}
+	case r.Spec.ReleaseImage != "":
+		named, err := reference.ParseNamed(r.Spec.ReleaseImage)
+		if err != nil {
+			return nil
+		}
+		_, tagged := named.(reference.Tagged)
+		if _, digested := named.(reference.Digested); digested && !tagged {
+			return admission.Warnings{"spec.fips: the architecture of a release image pinned by digest is not checked; " +
+				"FIPS mode is not available on aarch64 releases"}
+		}
+	}
+	return nil
+}
+
+// releaseImageTag returns the tag of the release image image, and whether it
+// has one. Invalid references are reported by validateReleaseImage.
+func releaseImageTag(image string) (string, bool) {
+	named, err := reference.ParseNamed(image)
+	if err != nil {
+		return "", false
+	}
+	tagged, ok := named.(reference.Tagged)
+	if !ok {
+		return "", false
+	}
+	return tagged.Tag(), true
+}
+
+// validateNodeSSHAuthorizedKey checks that the SSH key of the installed nodes
+// is a single public key in authorized_keys format, which the install would
+// otherwise only reject once started.
+func (r *AgentControlPlane) validateNodeSSHAuthorizedKey() field.ErrorList {
+	key := r.Spec.NodeSSHAuthorizedKey
+	if key == "" {
+		return nil
+	}
+
+	path := field.NewPath("spec", "nodeSSHAuthorizedKey")
+	_, _, _, rest, err := ssh.ParseAuthorizedKey([]byte(key))
+	switch {
+	case err != nil:
+		return field.ErrorList{field.Invalid(path, key, fmt.Sprintf("must be an SSH public key in authorized_keys format: %v", err))}
+	case len(bytes.TrimSpace(rest)) > 0:
+		return field.ErrorList{field.Invalid(path, key, "must be a single SSH public key")}
+	}
+	return nil
+}
+
+// validateIPXEScriptType checks that the iPXE script type is a known one and
+// only set for hosts booting with ipxe, the only ones served the script.
+func (r *AgentControlPlane) validateIPXEScriptType() field.ErrorList {
+	scriptType := r.Spec.IPXEScriptType
+	if scriptType == "" {
+		return nil
+	}
+
+	path := field.NewPath("spec", "ipxeScriptType")
+	types := []string{IPXEScriptTypeDiscoveryImageAlways, IPXEScriptTypeBootOrderControl}
+	if !slices.Contains(types, scriptType) {
+		return field.ErrorList{field.NotSupported(path, scriptType, types)}
+	}
+	if r.Spec.BootMethod != BootMethodIPXE {
+		return field.ErrorList{field.Forbidden(path, "is only allowed with spec.bootMethod ipxe")}
+	}
+	return nil
+}
+
+// validateHostnameTemplate checks that the hostname template numbers the hosts
+// and renders to DNS-1123 subdomains. Only the first and last ordinals are
+// rendered: the others differ from them by digits alone.
+func (r *AgentControlPlane) validateHostnameTemplate() field.ErrorList {
+	if r.Spec.HostnameTemplate == "" {
+		return nil
+	}
+
+	path := field.NewPath("spec", "hostnameTemplate")
+	if !strings.Contains(r.Spec.HostnameTemplate, HostnameOrdinalPlaceholder) {
+		return field.ErrorList{field.Invalid(path, r.Spec.HostnameTemplate,
+			fmt.Sprintf("must contain %s to give each host a distinct name", HostnameOrdinalPlaceholder))}
+	}
+
+	replicas := int32(1)
+	if r.Spec.Replicas != nil && *r.Spec.Replicas > 0 {
+		replicas = *r.Spec.Replicas
+	}
+	for _, ordinal := range []int{0, int(replicas) - 1} {
+		hostname := RenderHostname(r.Spec.HostnameTemplate, ordinal)
+		if errs := validation.IsDNS1123Subdomain(hostname); len(errs) > 0 {
+			return field.ErrorList{field.Invalid(path, r.Spec.HostnameTemplate,
+				fmt.Sprintf("renders to %q, which is not a valid hostname: %s", hostname, strings.Join(errs, "; ")))}
+		}
+	}
+	return nil
+}
+
+// validateHealthCheckLabels checks that the health check labels of the
+// Machine template leave the reserved Machine labels alone.
+func (r *AgentControlPlane) validateHealthCheckLabels() field.ErrorList {
+	if r.Spec.MachineTemplate == nil {
+		return nil
+	}
+
+	path := field.NewPath("spec", "machineTemplate", "healthCheckLabels")
+	var allErrs field.ErrorList
+	for _, key := range reservedMachineLabels {
+		if _, ok := r.Spec.MachineTemplate.HealthCheckLabels[key]; ok {
+			allErrs = append(allErrs, field.Forbidden(path.Key(key),
+				"is reserved for the labels the controller selects the control plane Machines by"))
+		}
+	}
+	return allErrs
+}
+
+// validateHostIgnitionOverrides checks that each of
+// spec.hostIgnitionOverrides is valid JSON.
+func (r *AgentControlPlane) validateHostIgnitionOverrides() field.ErrorList {
+	hostnames := make([]string, 0, len(r.Spec.HostIgnitionOverrides))
+	for hostname := range r.Spec.HostIgnitionOverrides {
+		hostnames = append(hostnames, hostname)
+	}
+	slices.Sort(hostnames)
+
+	var allErrs field.ErrorList
+	for _, hostname := range hostnames {
+		if override := r.Spec.HostIgnitionOverrides[hostname]; !json.Valid([]byte(override)) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "hostIgnitionOverrides").Key(hostname), override, "must be valid JSON"))
+		}
+	}
+	return allErrs
+}
+
+// validateDurations checks that the duration fields are not negative and
+// are whole seconds, which is the precision the controller requeues with.
+func (r *AgentControlPlane) validateDurations() field.ErrorList {
+	var allErrs field.ErrorList
+	for _, f := range r.durationFields() {
+		if *f.value == nil {
+			continue
+		}
+		switch d := (*f.value).Duration; {
+		case d < 0:
+			allErrs = append(allErrs, field.Invalid(f.path, d.String(), "must not be negative"))
+		case d%time.Second != 0:
+			allErrs = append(allErrs, field.Invalid(f.path, d.String(), "must be a whole number of seconds"))
+		}
+	}
+	return allErrs
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAdditionalCAs checks that every additional CA holds PEM-encoded
+// certificates only, and at least one.
+func (r *AgentControlPlane) validateAdditionalCAs() field.ErrorList {
+	var allErrs field.ErrorList
+	for i, ca := range r.Spec.AdditionalCAs {
+		path := field.NewPath("spec", "additionalCAs").Index(i)
+		if err := validateCertificates(ca); err != nil {
+			allErrs = append(allErrs, field.Invalid(path, ca, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// validateInlineManifests checks that the inline manifests have unique file
+// names assisted-service accepts, and hold a YAML object.
+func (r *AgentControlPlane) validateInlineManifests() field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{}
+	for i, manifest := range r.Spec.InlineManifests {
+		path := field.NewPath("spec", "inlineManifests").Index(i)
+		switch {
+		case names[manifest.Name]:
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), manifest.Name))
+		case len(validation.IsConfigMapKey(manifest.Name)) > 0:
+			allErrs = append(allErrs, field.Invalid(path.Child("name"), manifest.Name,
+				strings.Join(validation.IsConfigMapKey(manifest.Name), "; ")))
+		case !strings.HasSuffix(manifest.Name, ".yaml") && !strings.HasSuffix(manifest.Name, ".yml") && !strings.HasSuffix(manifest.Name, ".json"):
+			allErrs = append(allErrs, field.Invalid(path.Child("name"), manifest.Name, "must end with .yaml, .yml or .json"))
+		}
+		names[manifest.Name] = true
+
+		content, err := manifest.Decode()
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("content"), manifest.Name, fmt.Sprintf("must be base64-encoded: %v", err)))
+			continue
+		}
+		var object map[string]interface{}
+		if err := yaml.Unmarshal([]byte(content), &object); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("content"), manifest.Name, fmt.Sprintf("must be a YAML object: %v", err)))
+		} else if len(object) == 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("content"), manifest.Name, "must be a YAML object"))
+		}
+	}
+	return allErrs
+}
+
+func validateCertificates(bundle string) error {
+	rest := []byte(bundle)
+	var count int
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("must only hold certificates, found a %s PEM block", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("holds an invalid certificate: %v", err)
+		}
+		count++
+	}
+	if count == 0 || len(bytes.TrimSpace(rest)) > 0 {
+		return fmt.Errorf("must be PEM-encoded certificates")
+	}
+	return nil
+}
+
+// validateDiskEncryption checks that Tang servers are set exactly when the
+// tang mode is used, with reachable URLs and well-formed thumbprints.
+func (r *AgentControlPlane) validateDiskEncryption() field.ErrorList {
+	diskEncryption := r.Spec.DiskEncryption
+	if diskEncryption == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	path := field.NewPath("spec", "diskEncryption")
+	switch {
+	case diskEncryption.Mode == "tang" && len(diskEncryption.TangServers) == 0:
+		allErrs = append(allErrs, field.Required(path.Child("tangServers"), "at least one tang server is required in tang mode"))
+	case diskEncryption.Mode != "tang" && len(diskEncryption.TangServers) > 0:
+		allErrs = append(allErrs, field.Forbidden(path.Child("tangServers"), "tang servers can only be set in tang mode"))
+	}
+
+	for i, server := range diskEncryption.TangServers {
+		serverPath := path.Child("tangServers").Index(i)
+		if u, err := url.Parse(server.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(serverPath.Child("url"), server.URL, "must be an http or https URL"))
+		}
+		if thumbprint, err := base64.RawURLEncoding.DecodeString(server.Thumbprint); err != nil || len(thumbprint) == 0 {
+			allErrs = append(allErrs, field.Invalid(serverPath.Child("thumbprint"), server.Thumbprint, "must be a base64url-encoded key thumbprint"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,821 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+var _ = Describe("AgentControlPlane webhook", func() {
+	ctx := context.Background()
+	validator := &agentControlPlaneValidator{}
+
+	var acp *AgentControlPlane
+
+	BeforeEach(func() {
+		acp = &AgentControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-acp", Namespace: "test-namespace"},
+			Spec: AgentControlPlaneSpec{
+				ClusterNetwork: []CIDRBlock{{CIDR: "10.128.0.0/14", HostPrefix: 23}},
+				ServiceNetwork: []string{"172.30.0.0/16"},
+				MachineNetwork: []string{"192.168.111.0/24"},
+			},
+		}
+	})
+
+	Context("replicas", func() {
+		BeforeEach(func() {
+			// Keep multi-node control planes valid without VIPs.
+			acp.Spec.Platform = PlatformNone
+		})
+
+		It("accepts the maximum number of replicas", func() {
+			acp.Spec.Replicas = ptr.To[int32](DefaultMaxReplicas)
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects more than the maximum number of replicas", func() {
+			acp.Spec.Replicas = ptr.To[int32](DefaultMaxReplicas + 2)
+
+			_, err := validator.ValidateUpdate(ctx, acp.DeepCopy(), acp)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("spec.replicas: Invalid value: 7: must be at most 5")))
+		})
+
+		It("rejects an even number of replicas", func() {
+			acp.Spec.Replicas = ptr.To[int32](2)
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("must be an odd number")))
+		})
+
+		It("rejects a negative number of replicas", func() {
+			acp.Spec.Replicas = ptr.To[int32](-1)
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.replicas: Invalid value: -1: must be at least 1")))
+		})
+
+		It("honors a configured maximum", func() {
+			acp.Spec.Replicas = ptr.To[int32](5)
+
+			_, err := (&agentControlPlaneValidator{maxReplicas: 3}).ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("must be at most 3")))
+		})
+	})
+
+	Context("network CIDRs", func() {
+		It("accepts valid, disjoint networks", func() {
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = validator.ValidateUpdate(ctx, acp.DeepCopy(), acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts IPv6 networks", func() {
+			acp.Spec.ClusterNetwork = append(acp.Spec.ClusterNetwork, CIDRBlock{CIDR: "fd01::/48", HostPrefix: 64})
+			acp.Spec.ServiceNetwork = append(acp.Spec.ServiceNetwork, "fd02::/112")
+			acp.Spec.MachineNetwork = append(acp.Spec.MachineNetwork, "fd03::/64")
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects a CIDR that cannot be parsed", func() {
+			acp.Spec.ServiceNetwork = []string{"172.30.0.0"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("spec.serviceNetwork[0]")))
+			Expect(err).To(MatchError(ContainSubstring("must be a valid CIDR")))
+		})
+
+		It("rejects overlapping networks", func() {
+			acp.Spec.MachineNetwork = []string{"10.130.0.0/16"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("spec.machineNetwork[0]")))
+			Expect(err).To(MatchError(ContainSubstring("overlaps with spec.clusterNetwork[0].cidr")))
+		})
+
+		It("rejects overlapping networks on update", func() {
+			updated := acp.DeepCopy()
+			updated.Spec.ServiceNetwork = append(updated.Spec.ServiceNetwork, "172.30.128.0/17")
+
+			_, err := validator.ValidateUpdate(ctx, acp, updated)
+			Expect(err).To(MatchError(ContainSubstring("overlaps with spec.serviceNetwork[0]")))
+		})
+	})
+
+	Context("VIPs", func() {
+		BeforeEach(func() {
+			acp.Spec.APIVIPs = []string{"192.168.111.5"}
+			acp.Spec.IngressVIPs = []string{"192.168.111.4"}
+		})
+
+		It("accepts single-stack VIPs within the machine network", func() {
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts dual-stack VIPs within the machine networks", func() {
+			acp.Spec.ClusterNetwork = append(acp.Spec.ClusterNetwork, CIDRBlock{CIDR: "fd01::/48", HostPrefix: 64})
+			acp.Spec.ServiceNetwork = append(acp.Spec.ServiceNetwork, "fd02::/112")
+			acp.Spec.MachineNetwork = append(acp.Spec.MachineNetwork, "fd2e:6f44:5dd8:c956::/120")
+			acp.Spec.APIVIPs = append(acp.Spec.APIVIPs, "fd2e:6f44:5dd8:c956::5")
+			acp.Spec.IngressVIPs = append(acp.Spec.IngressVIPs, "fd2e:6f44:5dd8:c956::4")
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects a VIP outside of the machine network", func() {
+			acp.Spec.APIVIPs = []string{"10.0.0.5"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("spec.apiVIPs[0]")))
+			Expect(err).To(MatchError(ContainSubstring("must be within one of spec.machineNetwork")))
+		})
+
+		It("rejects a VIP that is not an IP address", func() {
+			acp.Spec.IngressVIPs = []string{"ingress.example.com"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.ingressVIPs[0]")))
+			Expect(err).To(MatchError(ContainSubstring("must be a valid IP address")))
+		})
+
+		It("rejects two VIPs of the same IP family", func() {
+			acp.Spec.APIVIPs = []string{"192.168.111.5", "192.168.111.6"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.apiVIPs[1]")))
+			Expect(err).To(MatchError(ContainSubstring("at most one address per IP family")))
+		})
+	})
+
+	Context("platform", func() {
+		BeforeEach(func() {
+			acp.Spec.Replicas = ptr.To[int32](3)
+		})
+
+		It("requires VIPs on baremetal", func() {
+			acp.Spec.Platform = PlatformBareMetal
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("spec.apiVIPs: Required value")))
+			Expect(err).To(MatchError(ContainSubstring("spec.ingressVIPs: Required value")))
+
+			acp.Spec.APIVIPs = []string{"192.168.111.5"}
+			acp.Spec.IngressVIPs = []string{"192.168.111.4"}
+			_, err = validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does not require VIPs for a single-node control plane", func() {
+			acp.Spec.Replicas = ptr.To[int32](1)
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts none without VIPs", func() {
+			acp.Spec.Platform = PlatformNone
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects VIPs on none", func() {
+			acp.Spec.Platform = PlatformNone
+			acp.Spec.APIVIPs = []string{"192.168.111.5"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.apiVIPs: Forbidden: VIPs cannot be set on platform none")))
+		})
+	})
+
+	Context("NMStateConfig label selector", func() {
+		BeforeEach(func() {
+			acp.Spec.NMStateConfigLabelSelector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{"cluster": "test-acp"},
+			}
+		})
+
+		It("accepts a selector with static networking", func() {
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("requires static networking", func() {
+			acp.Spec.MachineNetwork = nil
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("spec.machineNetwork: Required value")))
+		})
+
+		It("rejects a selector along with static routes", func() {
+			acp.Spec.StaticRoutesRef = &corev1.LocalObjectReference{Name: "static-routes"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.nmStateConfigLabelSelector: Forbidden")))
+		})
+
+		It("rejects empty and invalid selectors", func() {
+			acp.Spec.NMStateConfigLabelSelector = &metav1.LabelSelector{}
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.nmStateConfigLabelSelector: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("must not be empty")))
+
+			acp.Spec.NMStateConfigLabelSelector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "cluster", Operator: "Matches"}},
+			}
+			_, err = validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.nmStateConfigLabelSelector: Invalid value")))
+		})
+	})
+
+	Context("consistency of the spec fields", func() {
+		dualStack := func(acp *AgentControlPlane) {
+			acp.Spec.ClusterNetwork = append(acp.Spec.ClusterNetwork, CIDRBlock{CIDR: "fd01::/48", HostPrefix: 64})
+			acp.Spec.ServiceNetwork = append(acp.Spec.ServiceNetwork, "fd02::/112")
+			acp.Spec.MachineNetwork = append(acp.Spec.MachineNetwork, "fd03::/64")
+		}
+
+		DescribeTable("validating combinations of fields",
+			func(mutate func(*AgentControlPlane), errMessages ...string) {
+				mutate(acp)
+
+				_, err := validator.ValidateCreate(ctx, acp)
+				if len(errMessages) == 0 {
+					Expect(err).NotTo(HaveOccurred())
+					return
+				}
+				Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				for _, message := range errMessages {
+					Expect(err).To(MatchError(ContainSubstring(message)))
+				}
+			},
+			Entry("VIPs on platform baremetal", func(acp *AgentControlPlane) {
+				acp.Spec.Platform = PlatformBareMetal
+				acp.Spec.Replicas = ptr.To[int32](3)
+				acp.Spec.APIVIPs = []string{"192.168.111.5"}
+				acp.Spec.IngressVIPs = []string{"192.168.111.4"}
+			}),
+			Entry("VIPs on platform none", func(acp *AgentControlPlane) {
+				acp.Spec.Platform = PlatformNone
+				acp.Spec.APIVIPs = []string{"192.168.111.5"}
+			}, "spec.apiVIPs: Forbidden: VIPs cannot be set on platform none"),
+			Entry("single-stack IPv4 networks", func(acp *AgentControlPlane) {}),
+			Entry("dual-stack networks", dualStack),
+			Entry("dual-stack networks with a VIP per family", func(acp *AgentControlPlane) {
+				dualStack(acp)
+				acp.Spec.Replicas = ptr.To[int32](3)
+				acp.Spec.APIVIPs = []string{"192.168.111.5", "fd03::5"}
+				acp.Spec.IngressVIPs = []string{"192.168.111.4", "fd03::4"}
+			}),
+			Entry("a dual-stack cluster network with a single-stack machine network", func(acp *AgentControlPlane) {
+				dualStack(acp)
+				acp.Spec.MachineNetwork = []string{"192.168.111.0/24"}
+			}, "spec.machineNetwork: Invalid value", "must have both an IPv4 and an IPv6 CIDR in a dual-stack cluster"),
+			Entry("dual-stack networks of different primary families", func(acp *AgentControlPlane) {
+				dualStack(acp)
+				acp.Spec.ServiceNetwork = []string{"fd02::/112", "172.30.0.0/16"}
+			}, `spec.serviceNetwork[0]: Invalid value: "fd02::/112": must be an IPv4 CIDR`),
+			Entry("dual-stack VIPs starting with the secondary family", func(acp *AgentControlPlane) {
+				dualStack(acp)
+				acp.Spec.Replicas = ptr.To[int32](3)
+				acp.Spec.APIVIPs = []string{"fd03::5", "192.168.111.5"}
+				acp.Spec.IngressVIPs = []string{"192.168.111.4"}
+			}, `spec.apiVIPs[0]: Invalid value: "fd03::5": must be an IPv4 address, the primary IP family`),
+		)
+	})
+
+	Context("release image", func() {
+		It("accepts fully qualified references pinned by digest or tag", func() {
+			for _, image := range []string{
+				"mirror.example.com:5000/ocp/release@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				"quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+			} {
+				acp.Spec.ReleaseImage = image
+				_, err := validator.ValidateCreate(ctx, acp)
+				Expect(err).NotTo(HaveOccurred(), image)
+			}
+		})
+
+		It("rejects references without a registry", func() {
+			acp.Spec.ReleaseImage = "ocp-release:4.15.0"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.releaseImage")))
+			Expect(err).To(MatchError(ContainSubstring("must be a fully qualified image reference")))
+		})
+
+		It("rejects references without a tag or digest", func() {
+			acp.Spec.ReleaseImage = "quay.io/openshift-release-dev/ocp-release"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("must include a tag or digest")))
+		})
+	})
+
+	Context("ClusterImageSet reference", func() {
+		It("accepts a reference alone", func() {
+			acp.Spec.ClusterImageSetRef = &corev1.LocalObjectReference{Name: "openshift-v4.16.0"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects a reference along with a version or release image", func() {
+			acp.Spec.ClusterImageSetRef = &corev1.LocalObjectReference{Name: "openshift-v4.16.0"}
+			acp.Spec.Version = "4.15.0"
+			acp.Spec.ReleaseImage = "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("must not be set along with spec.version")))
+			Expect(err).To(MatchError(ContainSubstring("must not be set along with spec.releaseImage")))
+		})
+
+		It("rejects an unnamed reference", func() {
+			acp.Spec.ClusterImageSetRef = &corev1.LocalObjectReference{}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.clusterImageSetRef.name")))
+		})
+	})
+
+	Context("FIPS", func() {
+		It("accepts FIPS mode on x86_64 releases", func() {
+			acp.Spec.FIPS = true
+			acp.Spec.Version = "4.15.0"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+
+			acp.Spec.Version = ""
+			acp.Spec.ReleaseImage = "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64"
+			warnings, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("warns that the architecture of releases pinned by digest or in a ClusterImageSet is not checked", func() {
+			acp.Spec.FIPS = true
+			acp.Spec.ReleaseImage = "quay.io/openshift-release-dev/ocp-release@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+			warnings, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("release image pinned by digest is not checked")))
+
+			acp.Spec.ReleaseImage = ""
+			acp.Spec.ClusterImageSetRef = &corev1.LocalObjectReference{Name: "openshift-v4.16.0"}
+			warnings, err = validator.ValidateUpdate(ctx, acp, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("release of ClusterImageSet openshift-v4.16.0 is not checked")))
+		})
+
+		It("rejects FIPS mode on aarch64 releases", func() {
+			acp.Spec.FIPS = true
+			acp.Spec.ReleaseImage = "quay.io/openshift-release-dev/ocp-release:4.15.0-aarch64"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.fips")))
+			Expect(err).To(MatchError(ContainSubstring("not available on aarch64 releases")))
+		})
+	})
+
+	Context("node SSH key", func() {
+		newKey := func() string {
+			public, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			key, err := ssh.NewPublicKey(public)
+			Expect(err).NotTo(HaveOccurred())
+			return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))) + " admin@example.com"
+		}
+
+		It("accepts a public key in authorized_keys format", func() {
+			acp.Spec.NodeSSHAuthorizedKey = newKey()
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects malformed keys", func() {
+			acp.Spec.NodeSSHAuthorizedKey = "ssh-ed25519 not-base64"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.nodeSSHAuthorizedKey")))
+			Expect(err).To(MatchError(ContainSubstring("must be an SSH public key in authorized_keys format")))
+		})
+
+		It("rejects several keys", func() {
+			acp.Spec.NodeSSHAuthorizedKey = newKey() + "\n" + newKey()
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("must be a single SSH public key")))
+		})
+	})
+
+	Context("iPXE script type", func() {
+		It("accepts the known script types with the ipxe boot method", func() {
+			acp.Spec.BootMethod = BootMethodIPXE
+			for _, scriptType := range []string{IPXEScriptTypeDiscoveryImageAlways, IPXEScriptTypeBootOrderControl} {
+				acp.Spec.IPXEScriptType = scriptType
+
+				_, err := validator.ValidateCreate(ctx, acp)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("rejects unknown script types", func() {
+			acp.Spec.BootMethod = BootMethodIPXE
+			acp.Spec.IPXEScriptType = "Embedded"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring(`spec.ipxeScriptType: Unsupported value: "Embedded"`)))
+		})
+
+		It("rejects a script type without the ipxe boot method", func() {
+			acp.Spec.BootMethod = BootMethodISO
+			acp.Spec.IPXEScriptType = IPXEScriptTypeBootOrderControl
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.ipxeScriptType: Forbidden")))
+		})
+	})
+
+	Context("disk encryption", func() {
+		It("accepts tpmv2 without tang servers", func() {
+			acp.Spec.DiskEncryption = &DiskEncryption{EnableOn: "all", Mode: "tpmv2"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts tang with valid servers", func() {
+			acp.Spec.DiskEncryption = &DiskEncryption{
+				EnableOn:    "masters",
+				Mode:        "tang",
+				TangServers: []TangServer{{URL: "http://tang.example.com:7500", Thumbprint: "PLjNyRdGw03zlRoGjQYMahSZGu9"}},
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("requires tang servers in tang mode", func() {
+			acp.Spec.DiskEncryption = &DiskEncryption{EnableOn: "all", Mode: "tang"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.diskEncryption.tangServers: Required value")))
+		})
+
+		It("rejects tang servers outside of tang mode", func() {
+			acp.Spec.DiskEncryption = &DiskEncryption{
+				Mode:        "tpmv2",
+				TangServers: []TangServer{{URL: "http://tang.example.com:7500", Thumbprint: "PLjNyRdGw03zlRoGjQYMahSZGu9"}},
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.diskEncryption.tangServers: Forbidden")))
+		})
+
+		It("rejects invalid tang server URLs and thumbprints", func() {
+			acp.Spec.DiskEncryption = &DiskEncryption{
+				Mode: "tang",
+				TangServers: []TangServer{
+					{URL: "tang.example.com:7500", Thumbprint: "PLjNyRdGw03zlRoGjQYMahSZGu9"},
+					{URL: "https://tang.example.com", Thumbprint: "not a thumbprint!"},
+				},
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.diskEncryption.tangServers[0].url")))
+			Expect(err).To(MatchError(ContainSubstring("spec.diskEncryption.tangServers[1].thumbprint")))
+		})
+	})
+
+	Context("hostname template", func() {
+		BeforeEach(func() {
+			acp.Spec.Platform = PlatformNone
+			acp.Spec.Replicas = ptr.To[int32](3)
+		})
+
+		It("accepts a template rendering valid hostnames", func() {
+			acp.Spec.HostnameTemplate = "cp-{ordinal}.example.com"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("requires the ordinal placeholder", func() {
+			acp.Spec.HostnameTemplate = "cp"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.hostnameTemplate")))
+			Expect(err).To(MatchError(ContainSubstring("must contain {ordinal}")))
+		})
+
+		It("rejects templates rendering invalid hostnames", func() {
+			acp.Spec.HostnameTemplate = "CP_{ordinal}"
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring(`renders to "CP_0"`)))
+		})
+	})
+
+	Context("health check labels", func() {
+		BeforeEach(func() {
+			acp.Spec.MachineTemplate = &AgentControlPlaneMachineTemplate{
+				HealthCheckLabels: map[string]string{"mhc.example.com/target": "control-plane"},
+			}
+		})
+
+		It("accepts labels of their own", func() {
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects the reserved control plane label", func() {
+			acp.Spec.MachineTemplate.HealthCheckLabels[clusterv1.MachineControlPlaneLabel] = ""
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.machineTemplate.healthCheckLabels[cluster.x-k8s.io/control-plane]")))
+			Expect(err).To(MatchError(ContainSubstring("is reserved")))
+		})
+	})
+
+	Context("host ignition overrides", func() {
+		It("accepts JSON overrides", func() {
+			acp.Spec.HostIgnitionOverrides = map[string]string{"master-0": `{"ignition":{"version":"3.2.0"}}`}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects an override that is not JSON", func() {
+			acp.Spec.HostIgnitionOverrides = map[string]string{
+				"master-0": `{"ignition":{"version":"3.2.0"}}`,
+				"master-1": "ignition: {}",
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.hostIgnitionOverrides[master-1]")))
+			Expect(err).To(MatchError(ContainSubstring("must be valid JSON")))
+		})
+	})
+
+	Context("additional CAs", func() {
+		BeforeEach(func() {
+			acp.Spec.Platform = PlatformNone
+		})
+
+		newCA := func() string {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			template := &x509.Certificate{
+				SerialNumber:          big.NewInt(1),
+				Subject:               pkix.Name{CommonName: "test-ca"},
+				NotBefore:             time.Now(),
+				NotAfter:              time.Now().Add(time.Hour),
+				IsCA:                  true,
+				BasicConstraintsValid: true,
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).NotTo(HaveOccurred())
+			return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+		}
+
+		It("accepts PEM-encoded certificates", func() {
+			acp.Spec.AdditionalCAs = []string{newCA(), newCA() + newCA()}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects entries that are not PEM-encoded", func() {
+			acp.Spec.AdditionalCAs = []string{newCA(), "not a certificate"}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.additionalCAs[1]")))
+			Expect(err).To(MatchError(ContainSubstring("must be PEM-encoded certificates")))
+		})
+
+		It("rejects invalid certificates and other PEM blocks", func() {
+			acp.Spec.AdditionalCAs = []string{
+				string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})),
+				string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")})),
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.additionalCAs[0]")))
+			Expect(err).To(MatchError(ContainSubstring("holds an invalid certificate")))
+			Expect(err).To(MatchError(ContainSubstring("found a PRIVATE KEY PEM block")))
+		})
+	})
+
+	Context("inline manifests", func() {
+		const manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: chrony\n"
+
+		It("accepts YAML manifests, plain or base64-encoded", func() {
+			acp.Spec.InlineManifests = []Manifest{
+				{Name: "50-chrony.yaml", Content: manifest},
+				{Name: "60-encoded.yml", Content: base64.StdEncoding.EncodeToString([]byte(manifest)), Encoding: ManifestEncodingBase64},
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects malformed YAML", func() {
+			acp.Spec.InlineManifests = []Manifest{
+				{Name: "50-chrony.yaml", Content: manifest},
+				{Name: "60-broken.yaml", Content: "kind: ConfigMap\n  metadata: [name"},
+				{Name: "70-scalar.yaml", Content: "just a string"},
+				{Name: "80-encoded.yaml", Content: "not base64!", Encoding: ManifestEncodingBase64},
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).NotTo(MatchError(ContainSubstring("spec.inlineManifests[0]")))
+			Expect(err).To(MatchError(ContainSubstring("spec.inlineManifests[1].content")))
+			Expect(err).To(MatchError(ContainSubstring("spec.inlineManifests[2].content")))
+			Expect(err).To(MatchError(ContainSubstring("spec.inlineManifests[3].content")))
+			Expect(err).To(MatchError(ContainSubstring("must be base64-encoded")))
+		})
+
+		It("rejects duplicate and invalid names", func() {
+			acp.Spec.InlineManifests = []Manifest{
+				{Name: "50-chrony.yaml", Content: manifest},
+				{Name: "50-chrony.yaml", Content: manifest},
+				{Name: "chrony.conf", Content: manifest},
+				{Name: "etc/chrony.yaml", Content: manifest},
+			}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring(`spec.inlineManifests[1].name: Duplicate value: "50-chrony.yaml"`)))
+			Expect(err).To(MatchError(ContainSubstring("spec.inlineManifests[2].name")))
+			Expect(err).To(MatchError(ContainSubstring("spec.inlineManifests[3].name")))
+		})
+	})
+
+	Context("durations", func() {
+		defaulter := &agentControlPlaneDefaulter{}
+
+		It("writes durations in their canonical form", func() {
+			Expect(json.Unmarshal([]byte(`{"spec":{"failureGracePeriod":"90m"}}`), acp)).To(Succeed())
+
+			Expect(defaulter.Default(ctx, acp)).To(Succeed())
+			data, err := json.Marshal(acp.Spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"failureGracePeriod":"1h30m0s"`))
+		})
+
+		It("unsets zero durations", func() {
+			acp.Spec.FailureGracePeriod = &metav1.Duration{}
+
+			Expect(defaulter.Default(ctx, acp)).To(Succeed())
+			Expect(acp.Spec.FailureGracePeriod).To(BeNil())
+		})
+
+		It("defaults the unset Machine timeouts", func() {
+			acp.Spec.MachineTemplate = &AgentControlPlaneMachineTemplate{
+				NodeDrainTimeout: &metav1.Duration{Duration: 2 * time.Minute},
+				// Zero waits forever rather than standing for unset.
+				NodeVolumeDetachTimeout: &metav1.Duration{},
+			}
+
+			Expect(defaulter.Default(ctx, acp)).To(Succeed())
+			Expect(acp.Spec.MachineTemplate.NodeDrainTimeout).To(Equal(&metav1.Duration{Duration: 2 * time.Minute}))
+			Expect(acp.Spec.MachineTemplate.NodeVolumeDetachTimeout).To(Equal(&metav1.Duration{}))
+			Expect(acp.Spec.MachineTemplate.NodeDeletionTimeout).To(Equal(&metav1.Duration{Duration: DefaultNodeDeletionTimeout}))
+		})
+
+		It("defaults every Machine timeout of an empty Machine template", func() {
+			acp.Spec.MachineTemplate = &AgentControlPlaneMachineTemplate{}
+
+			Expect(defaulter.Default(ctx, acp)).To(Succeed())
+			Expect(acp.Spec.MachineTemplate.NodeDrainTimeout).To(Equal(&metav1.Duration{Duration: DefaultNodeDrainTimeout}))
+			Expect(acp.Spec.MachineTemplate.NodeVolumeDetachTimeout).To(Equal(&metav1.Duration{Duration: DefaultNodeVolumeDetachTimeout}))
+			Expect(acp.Spec.MachineTemplate.NodeDeletionTimeout).To(Equal(&metav1.Duration{Duration: DefaultNodeDeletionTimeout}))
+		})
+
+		It("leaves the Machine template unset", func() {
+			acp.Spec.MachineTemplate = nil
+
+			Expect(defaulter.Default(ctx, acp)).To(Succeed())
+			Expect(acp.Spec.MachineTemplate).To(BeNil())
+		})
+
+		It("rejects a negative Machine timeout", func() {
+			acp.Spec.MachineTemplate = &AgentControlPlaneMachineTemplate{NodeDrainTimeout: &metav1.Duration{Duration: -time.Minute}}
+
+			_, err := validator.ValidateCreate(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("spec.machineTemplate.nodeDrainTimeout")))
+			Expect(err).To(MatchError(ContainSubstring("must not be negative")))
+		})
+
+		DescribeTable("validating the precision",
+			func(d time.Duration, message string) {
+				acp.Spec.FailureGracePeriod = &metav1.Duration{Duration: d}
+
+				_, err := validator.ValidateCreate(ctx, acp)
+				if message == "" {
+					Expect(err).NotTo(HaveOccurred())
+					return
+				}
+				Expect(err).To(MatchError(ContainSubstring("spec.failureGracePeriod")))
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("whole seconds", 10*time.Minute, ""),
+			Entry("sub-second precision", 1500*time.Millisecond, "must be a whole number of seconds"),
+			Entry("a negative duration", -time.Minute, "must not be negative"),
+		)
+	})
+
+	Context("deletion", func() {
+		BeforeEach(func() {
+			conditions.MarkFalse(acp, InstallCompleteCondition, InstallInProgressReason, clusterv1.ConditionSeverityInfo, "installing")
+		})
+
+		It("is refused while the control plane is installing", func() {
+			_, err := validator.ValidateDelete(ctx, acp)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring(ForceDeleteAnnotation)))
+
+			conditions.MarkFalse(acp, InstallCompleteCondition, InstallRetryingReason, clusterv1.ConditionSeverityWarning, "retrying")
+			_, err = validator.ValidateDelete(ctx, acp)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		})
+
+		It("is allowed with the force annotation", func() {
+			acp.Annotations = map[string]string{ForceDeleteAnnotation: "true"}
+
+			warnings, err := validator.ValidateDelete(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(HaveLen(1))
+		})
+
+		It("is allowed before the install started", func() {
+			conditions.MarkFalse(acp, InstallCompleteCondition, InstallPendingReason, clusterv1.ConditionSeverityInfo, "Waiting for the install to start")
+
+			warnings, err := validator.ValidateDelete(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("is allowed once the install finished or failed", func() {
+			conditions.MarkTrue(acp, InstallCompleteCondition)
+			_, err := validator.ValidateDelete(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+
+			conditions.MarkFalse(acp, InstallCompleteCondition, InstallFailedReason, clusterv1.ConditionSeverityError, "failed")
+			_, err = validator.ValidateDelete(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,78 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentControlPlaneConfigName is the name of the AgentControlPlaneConfig the
+// controller reads. AgentControlPlaneConfigs of any other name are ignored.
+const AgentControlPlaneConfigName = "cluster"
+
+// AgentControlPlaneConfigSpec defines the defaults inherited by all
+// AgentControlPlanes. A field set on an AgentControlPlane takes precedence
+// over its default here.
+type AgentControlPlaneConfigSpec struct {
+	// BaseDomain is the default of spec.baseDomain.
+	// +optional
+	BaseDomain string `json:"baseDomain,omitempty"`
+
+	// PullSecretRef is the default of spec.pullSecretRef. It names a secret
+	// in the namespace of each AgentControlPlane inheriting it.
+	// +optional
+	PullSecretRef *corev1.LocalObjectReference `json:"pullSecretRef,omitempty"`
+
+	// Proxy is the default of spec.proxy. It is only inherited by the
+	// AgentControlPlanes setting neither spec.proxy nor spec.proxyFrom.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+
+	// AdditionalNTPSources is the default of spec.additionalNTPSources. It
+	// is only inherited by the AgentControlPlanes setting no NTP sources.
+	// +optional
+	AdditionalNTPSources []string `json:"additionalNTPSources,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:validation:XValidation:rule="self.metadata.name == 'cluster'",message="the AgentControlPlaneConfig must be named cluster"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// AgentControlPlaneConfig is the Schema for the agentcontrolplaneconfigs API.
+// It holds the cluster-wide defaults of the AgentControlPlanes, so fleets do
+// not repeat the same settings in each of them.
+type AgentControlPlaneConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AgentControlPlaneConfigSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AgentControlPlaneConfigList contains a list of AgentControlPlaneConfig
+type AgentControlPlaneConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentControlPlaneConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentControlPlaneConfig{}, &AgentControlPlaneConfigList{})
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+const (
+	// DependenciesReadyCondition reports whether the APIs this provider relies
+	// on are served by the management cluster.
+	DependenciesReadyCondition clusterv1.ConditionType = "DependenciesReady"
+
+	// InfraEnvCRDMissingReason (Severity=Warning) documents that the InfraEnv
+	// CRD is not installed, usually because assisted-service is not deployed.
+	InfraEnvCRDMissingReason = "InfraEnvCRDMissing"
+
+	// AgentCRDMissingReason (Severity=Warning) documents that the Agent CRD is
+	// not installed, usually because assisted-service is not deployed.
+	AgentCRDMissingReason = "AgentCRDMissing"
+
+	// AgentClusterInstallCRDMissingReason (Severity=Warning) documents that the
+	// AgentClusterInstall CRD is not installed, usually because
+	// assisted-service is not deployed.
+	AgentClusterInstallCRDMissingReason = "AgentClusterInstallCRDMissing"
+
+	// HiveCRDMissingReason (Severity=Warning) documents that a hive CRD such as
+	// ClusterDeployment is not installed.
+	HiveCRDMissingReason = "HiveCRDMissing"
+)
+
+const (
+	// DependencyVersionCondition documents that the APIs this provider relies
+	// on are served at the versions it was built against.
+	DependencyVersionCondition clusterv1.ConditionType = "DependencyVersion"
+
+	// DependencyVersionUnsupportedReason (Severity=Error) documents that an
+	// API this provider relies on is only served at versions it does not
+	// support, usually because the component serving it was upgraded past
+	// what this release of the provider supports.
+	DependencyVersionUnsupportedReason = "DependencyVersionUnsupported"
+)
+
+const (
+	// OwnerClusterAvailableCondition reports whether the Cluster owning the
+	// AgentControlPlane is known.
+	OwnerClusterAvailableCondition clusterv1.ConditionType = "OwnerClusterAvailable"
+
+	// WaitingForClusterReason (Severity=Info) documents that the
+	// AgentControlPlane has no owner Cluster yet, or that the Cluster it
+	// references does not exist.
+	WaitingForClusterReason = "WaitingForCluster"
+
+	// OrphanedReason (Severity=Warning) documents that the owner Cluster's
+	// spec.controlPlaneRef references another control plane, so that the
+	// AgentControlPlane is no longer the control plane of any Cluster.
+	OrphanedReason = "Orphaned"
+)
+
+const (
+	// InstallCompleteCondition mirrors the Completed condition of the
+	// AgentClusterInstall installing the control plane.
+	InstallCompleteCondition clusterv1.ConditionType = "InstallComplete"
+
+	// InstallPendingReason (Severity=Info) documents that the install has not
+	// started yet, e.g. while waiting for the hosts to be discovered.
+	InstallPendingReason = "InstallPending"
+
+	// InstallInProgressReason (Severity=Info) documents that the install has
+	// started but not completed yet.
+	InstallInProgressReason = "InstallInProgress"
+
+	// InstallFailedReason (Severity=Error) documents that the install failed.
+	InstallFailedReason = "InstallFailed"
+
+	// InstallTimeoutReason (Severity=Error) documents that the install timed
+	// out. The install is not retried, but restarted once the spec changes.
+	InstallTimeoutReason = "InstallTimeout"
+
+	// InstallRetryingReason (Severity=Warning) documents that the install
+	// failed and is being retried, within spec.maxInstallRetries, or that a
+	// timed out install is being restarted after the spec changed.
+	InstallRetryingReason = "InstallRetrying"
+)
+
+const (
+	// ManifestsAvailableCondition documents that the ConfigMaps referenced by
+	// spec.manifestsConfigMapRefs exist.
+	ManifestsAvailableCondition clusterv1.ConditionType = "ManifestsAvailable"
+
+	// ManifestsConfigMapMissingReason (Severity=Warning) documents that a
+	// referenced manifests ConfigMap does not exist. assisted-service does not
+	// start the install until it is created.
+	ManifestsConfigMapMissingReason = "ManifestsConfigMapMissing"
+)
+
+const (
+	// HostsValidatedCondition documents that the hosts bound to the control
+	// plane pass the assisted-service host validations.
+	HostsValidatedCondition clusterv1.ConditionType = "HostsValidated"
+
+	// HostValidationFailedReason (Severity=Warning) documents that at least
+	// one bound host fails a validation, e.g. because it does not have enough
+	// CPU, memory or disk. The install does not start until it passes.
+	HostValidationFailedReason = "HostValidationFailed"
+)
+
+const (
+	// HostRequirementsMetCondition documents that the discovered hosts
+	// considered for binding meet spec.minimumHostRequirements.
+	HostRequirementsMetCondition clusterv1.ConditionType = "HostRequirementsMet"
+
+	// InsufficientHostResourcesReason (Severity=Warning) documents that
+	// unbound hosts were skipped because they fall short of the minimum
+	// requirements, or have not reported their inventory yet.
+	InsufficientHostResourcesReason = "InsufficientHostResources"
+)
+
+const (
+	// InfrastructureReadyCondition documents that the infrastructure template
+	// referenced by spec.machineTemplate exists and can be cloned into control
+	// plane Machines.
+	InfrastructureReadyCondition clusterv1.ConditionType = "InfrastructureReady"
+
+	// InfrastructureTemplateNotFoundReason (Severity=Error) documents that the
+	// referenced infrastructure template does not exist.
+	InfrastructureTemplateNotFoundReason = "InfrastructureTemplateNotFound"
+
+	// InfrastructureTemplateDeletingReason (Severity=Error) documents that the
+	// referenced infrastructure template is being deleted.
+	InfrastructureTemplateDeletingReason = "InfrastructureTemplateDeleting"
+)
+
+const (
+	// MachinesCreatedCondition documents that the control plane Machines can
+	// be created.
+	MachinesCreatedCondition clusterv1.ConditionType = "MachinesCreated"
+
+	// WaitingForClusterInfrastructureReason (Severity=Info) documents that
+	// Machines are not created until the owner Cluster's infrastructure is
+	// ready.
+	WaitingForClusterInfrastructureReason = "WaitingForClusterInfrastructure"
+
+	// WaitingForAgentsReason (Severity=Info) documents that no more Machines
+	// are created until more matching Agents are available to back them.
+	WaitingForAgentsReason = "WaitingForAgents"
+
+	// CreatingMachinesReason (Severity=Info) documents that control plane
+	// Machines are being created, one per reconcile.
+	CreatingMachinesReason = "CreatingMachines"
+)
+
+const (
+	// MachineVersionsConvergedCondition documents that all the control plane
+	// Machines run the same version.
+	MachineVersionsConvergedCondition clusterv1.ConditionType = "MachineVersionsConverged"
+
+	// VersionSkewReason (Severity=Info) documents that the control plane
+	// Machines run different versions, as reported in status.versionSkew.
+	VersionSkewReason = "VersionSkew"
+)
+
+const (
+	// MachinesExpectedCondition documents that the control plane Machines of
+	// the Cluster do not outnumber the desired replicas because of Machines
+	// the controller did not create.
+	MachinesExpectedCondition clusterv1.ConditionType = "MachinesExpected"
+
+	// UnexpectedMachinesReason (Severity=Warning) documents that Machines
+	// created by someone else carry the control plane label of the Cluster,
+	// bringing the control plane Machines beyond the desired replicas. The
+	// controller does not delete them.
+	UnexpectedMachinesReason = "UnexpectedMachines"
+)
+
+const (
+	// ControlPlaneReachableCondition documents that the API server of the
+	// installed control plane accepts connections on the Cluster's control
+	// plane endpoint.
+	ControlPlaneReachableCondition clusterv1.ConditionType = "ControlPlaneReachable"
+
+	// ControlPlaneUnreachableReason (Severity=Warning) documents that the
+	// control plane endpoint is not set or does not accept connections.
+	ControlPlaneUnreachableReason = "ControlPlaneUnreachable"
+)
+
+const (
+	// ControlPlaneEndpointSetCondition documents that the owner Cluster has a
+	// control plane endpoint.
+	ControlPlaneEndpointSetCondition clusterv1.ConditionType = "ControlPlaneEndpointSet"
+
+	// WaitingForAPIVIPReason (Severity=Info) documents that the control plane
+	// endpoint cannot be derived because spec.apiVIPs is not set. It should
+	// then be set on the Cluster directly.
+	WaitingForAPIVIPReason = "WaitingForAPIVIP"
+)
+
+const (
+	// MirrorRegistryConfiguredCondition documents that the ConfigMap
+	// referenced by spec.mirrorRegistryRef holds a usable registry mirror
+	// configuration.
+	MirrorRegistryConfiguredCondition clusterv1.ConditionType = "MirrorRegistryConfigured"
+
+	// MirrorRegistryConfigMapMissingReason (Severity=Warning) documents that
+	// the referenced mirror registry ConfigMap does not exist.
+	MirrorRegistryConfigMapMissingReason = "MirrorRegistryConfigMapMissing"
+
+	// MirrorRegistryConfigMapInvalidReason (Severity=Error) documents that the
+	// referenced mirror registry ConfigMap lacks the registries.conf key or
+	// holds malformed values. Discovered hosts cannot pull images from the
+	// mirrors until it is fixed.
+	MirrorRegistryConfigMapInvalidReason = "MirrorRegistryConfigMapInvalid"
+)
+
+const (
+	// StaticRoutesConfiguredCondition documents that the static routes held
+	// by the ConfigMap referenced by spec.staticRoutesRef are applied to the
+	// discovered hosts.
+	StaticRoutesConfiguredCondition clusterv1.ConditionType = "StaticRoutesConfigured"
+
+	// StaticRoutesConfigMapMissingReason (Severity=Warning) documents that
+	// the referenced static routes ConfigMap does not exist.
+	StaticRoutesConfigMapMissingReason = "StaticRoutesConfigMapMissing"
+
+	// StaticRoutesConfigMapInvalidReason (Severity=Error) documents that the
+	// referenced static routes ConfigMap lacks the routes key or holds
+	// malformed routes. The routes applied last are kept until it is fixed.
+	StaticRoutesConfigMapInvalidReason = "StaticRoutesConfigMapInvalid"
+)
+
+const (
+	// KubeconfigAvailableCondition documents that the <cluster>-kubeconfig
+	// Secret holds the admin kubeconfig of the installed control plane.
+	KubeconfigAvailableCondition clusterv1.ConditionType = "KubeconfigAvailable"
+
+	// WaitingForKubeconfigReason (Severity=Info) documents that the install
+	// has not produced the admin kubeconfig yet.
+	WaitingForKubeconfigReason = "WaitingForKubeconfig"
+)
+
+const (
+	// InfraEnvClaimedCondition documents that the InfraEnv of the
+	// AgentControlPlane is claimed by no other AgentControlPlane, so that it
+	// can be created or adopted.
+	InfraEnvClaimedCondition clusterv1.ConditionType = "InfraEnvClaimed"
+
+	// InfraEnvConflictReason (Severity=Error) documents that the InfraEnv is
+	// controlled by, or annotated for, another AgentControlPlane, e.g. one of
+	// the same name in another namespace keeping its InfraEnv in the same
+	// spec.infraEnvNamespace.
+	InfraEnvConflictReason = "InfraEnvConflict"
+)
+
+const (
+	// BootArtifactsAvailableCondition documents that status.bootArtifacts
+	// holds the URLs needed to boot hosts with the configured boot method.
+	BootArtifactsAvailableCondition clusterv1.ConditionType = "BootArtifactsAvailable"
+
+	// WaitingForBootArtifactsReason (Severity=Info) documents that the
+	// InfraEnv does not report the URLs of the boot artifacts yet.
+	WaitingForBootArtifactsReason = "WaitingForBootArtifacts"
+)
+
+const (
+	// ISOPropagatedCondition documents that the infrastructure template
+	// referenced by spec.machineTemplate boots the discovery ISO of
+	// status.bootArtifacts.
+	ISOPropagatedCondition clusterv1.ConditionType = "ISOPropagated"
+
+	// ISOPropagationFailedReason (Severity=Warning) documents that patching
+	// the infrastructure template with the discovery ISO failed.
+	ISOPropagationFailedReason = "ISOPropagationFailed"
+)
+
+const (
+	// PullSecretValidCondition documents that the Secret referenced by
+	// spec.pullSecretRef holds a usable docker config.
+	PullSecretValidCondition clusterv1.ConditionType = "PullSecretValid"
+
+	// PullSecretNotFoundReason (Severity=Warning) documents that no pull
+	// secret is referenced, or that the referenced Secret does not exist.
+	PullSecretNotFoundReason = "PullSecretNotFound"
+
+	// PullSecretInvalidReason (Severity=Error) documents that the pull secret
+	// lacks the .dockerconfigjson key, or that it does not hold a docker
+	// config with credentials.
+	PullSecretInvalidReason = "PullSecretInvalid"
+)
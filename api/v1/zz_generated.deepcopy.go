@@ -21,7 +21,10 @@ limitations under the License.
 package v1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -29,8 +32,8 @@ func (in *AgentControlPlane) DeepCopyInto(out *AgentControlPlane) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentControlPlane.
@@ -51,6 +54,94 @@ func (in *AgentControlPlane) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentControlPlaneConfig) DeepCopyInto(out *AgentControlPlaneConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentControlPlaneConfig.
+func (in *AgentControlPlaneConfig) DeepCopy() *AgentControlPlaneConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentControlPlaneConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentControlPlaneConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentControlPlaneConfigList) DeepCopyInto(out *AgentControlPlaneConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AgentControlPlaneConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentControlPlaneConfigList.
+func (in *AgentControlPlaneConfigList) DeepCopy() *AgentControlPlaneConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentControlPlaneConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentControlPlaneConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentControlPlaneConfigSpec) DeepCopyInto(out *AgentControlPlaneConfigSpec) {
+	*out = *in
+	if in.PullSecretRef != nil {
+		in, out := &in.PullSecretRef, &out.PullSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		**out = **in
+	}
+	if in.AdditionalNTPSources != nil {
+		in, out := &in.AdditionalNTPSources, &out.AdditionalNTPSources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentControlPlaneConfigSpec.
+func (in *AgentControlPlaneConfigSpec) DeepCopy() *AgentControlPlaneConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentControlPlaneConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentControlPlaneList) DeepCopyInto(out *AgentControlPlaneList) {
 	*out = *in
@@ -83,9 +174,217 @@ func (in *AgentControlPlaneList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentControlPlaneMachineTemplate) DeepCopyInto(out *AgentControlPlaneMachineTemplate) {
+	*out = *in
+	out.InfrastructureRef = in.InfrastructureRef
+	if in.HealthCheckLabels != nil {
+		in, out := &in.HealthCheckLabels, &out.HealthCheckLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FailureDomains != nil {
+		in, out := &in.FailureDomains, &out.FailureDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeDrainTimeout != nil {
+		in, out := &in.NodeDrainTimeout, &out.NodeDrainTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.NodeVolumeDetachTimeout != nil {
+		in, out := &in.NodeVolumeDetachTimeout, &out.NodeVolumeDetachTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.NodeDeletionTimeout != nil {
+		in, out := &in.NodeDeletionTimeout, &out.NodeDeletionTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentControlPlaneMachineTemplate.
+func (in *AgentControlPlaneMachineTemplate) DeepCopy() *AgentControlPlaneMachineTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentControlPlaneMachineTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentControlPlaneSpec) DeepCopyInto(out *AgentControlPlaneSpec) {
 	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MachineTemplate != nil {
+		in, out := &in.MachineTemplate, &out.MachineTemplate
+		*out = new(AgentControlPlaneMachineTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManageMachines != nil {
+		in, out := &in.ManageMachines, &out.ManageMachines
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ClusterImageSetRef != nil {
+		in, out := &in.ClusterImageSetRef, &out.ClusterImageSetRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.ClusterNetwork != nil {
+		in, out := &in.ClusterNetwork, &out.ClusterNetwork
+		*out = make([]CIDRBlock, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceNetwork != nil {
+		in, out := &in.ServiceNetwork, &out.ServiceNetwork
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MachineNetwork != nil {
+		in, out := &in.MachineNetwork, &out.MachineNetwork
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.APIVIPs != nil {
+		in, out := &in.APIVIPs, &out.APIVIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IngressVIPs != nil {
+		in, out := &in.IngressVIPs, &out.IngressVIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DiskEncryption != nil {
+		in, out := &in.DiskEncryption, &out.DiskEncryption
+		*out = new(DiskEncryption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManifestsConfigMapRefs != nil {
+		in, out := &in.ManifestsConfigMapRefs, &out.ManifestsConfigMapRefs
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.InlineManifests != nil {
+		in, out := &in.InlineManifests, &out.InlineManifests
+		*out = make([]Manifest, len(*in))
+		copy(*out, *in)
+	}
+	if in.PullSecretRef != nil {
+		in, out := &in.PullSecretRef, &out.PullSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.BootArtifactsGeneration != nil {
+		in, out := &in.BootArtifactsGeneration, &out.BootArtifactsGeneration
+		*out = new(int64)
+		**out = **in
+	}
+	if in.KernelArguments != nil {
+		in, out := &in.KernelArguments, &out.KernelArguments
+		*out = make([]KernelArgument, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalCAs != nil {
+		in, out := &in.AdditionalCAs, &out.AdditionalCAs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalTrustBundleConfigMapRef != nil {
+		in, out := &in.AdditionalTrustBundleConfigMapRef, &out.AdditionalTrustBundleConfigMapRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MirrorRegistryRef != nil {
+		in, out := &in.MirrorRegistryRef, &out.MirrorRegistryRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.StaticRoutesRef != nil {
+		in, out := &in.StaticRoutesRef, &out.StaticRoutesRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.NMStateConfigLabelSelector != nil {
+		in, out := &in.NMStateConfigLabelSelector, &out.NMStateConfigLabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		**out = **in
+	}
+	if in.ProxyFrom != nil {
+		in, out := &in.ProxyFrom, &out.ProxyFrom
+		*out = new(ProxySource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdditionalNTPSources != nil {
+		in, out := &in.AdditionalNTPSources, &out.AdditionalNTPSources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostSelector != nil {
+		in, out := &in.HostSelector, &out.HostSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InstallationDisks != nil {
+		in, out := &in.InstallationDisks, &out.InstallationDisks
+		*out = make([]InstallationDiskHint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MinimumHostRequirements != nil {
+		in, out := &in.MinimumHostRequirements, &out.MinimumHostRequirements
+		*out = new(HostRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostIgnitionOverrides != nil {
+		in, out := &in.HostIgnitionOverrides, &out.HostIgnitionOverrides
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FailureGracePeriod != nil {
+		in, out := &in.FailureGracePeriod, &out.FailureGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxInstallRetries != nil {
+		in, out := &in.MaxInstallRetries, &out.MaxInstallRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodeLabels != nil {
+		in, out := &in.NodeLabels, &out.NodeLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeTaints != nil {
+		in, out := &in.NodeTaints, &out.NodeTaints
+		*out = make([]corev1.Taint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentControlPlaneSpec.
@@ -101,6 +400,37 @@ func (in *AgentControlPlaneSpec) DeepCopy() *AgentControlPlaneSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentControlPlaneStatus) DeepCopyInto(out *AgentControlPlaneStatus) {
 	*out = *in
+	if in.BootArtifacts != nil {
+		in, out := &in.BootArtifacts, &out.BootArtifacts
+		*out = new(BootArtifacts)
+		**out = **in
+	}
+	if in.Agents != nil {
+		in, out := &in.Agents, &out.Agents
+		*out = make([]AgentSummary, len(*in))
+		copy(*out, *in)
+	}
+	if in.VersionSkew != nil {
+		in, out := &in.VersionSkew, &out.VersionSkew
+		*out = new(VersionSkew)
+		**out = **in
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+	if in.FailingSince != nil {
+		in, out := &in.FailingSince, &out.FailingSince
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentControlPlaneStatus.
@@ -112,3 +442,220 @@ func (in *AgentControlPlaneStatus) DeepCopy() *AgentControlPlaneStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentSummary) DeepCopyInto(out *AgentSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentSummary.
+func (in *AgentSummary) DeepCopy() *AgentSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootArtifacts) DeepCopyInto(out *BootArtifacts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootArtifacts.
+func (in *BootArtifacts) DeepCopy() *BootArtifacts {
+	if in == nil {
+		return nil
+	}
+	out := new(BootArtifacts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlock) DeepCopyInto(out *CIDRBlock) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlock.
+func (in *CIDRBlock) DeepCopy() *CIDRBlock {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskEncryption) DeepCopyInto(out *DiskEncryption) {
+	*out = *in
+	if in.TangServers != nil {
+		in, out := &in.TangServers, &out.TangServers
+		*out = make([]TangServer, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskEncryption.
+func (in *DiskEncryption) DeepCopy() *DiskEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostRequirements) DeepCopyInto(out *HostRequirements) {
+	*out = *in
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostRequirements.
+func (in *HostRequirements) DeepCopy() *HostRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(HostRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallationDiskHint) DeepCopyInto(out *InstallationDiskHint) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallationDiskHint.
+func (in *InstallationDiskHint) DeepCopy() *InstallationDiskHint {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallationDiskHint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelArgument) DeepCopyInto(out *KernelArgument) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelArgument.
+func (in *KernelArgument) DeepCopy() *KernelArgument {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelArgument)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Manifest) DeepCopyInto(out *Manifest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Manifest.
+func (in *Manifest) DeepCopy() *Manifest {
+	if in == nil {
+		return nil
+	}
+	out := new(Manifest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proxy) DeepCopyInto(out *Proxy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
+	if in == nil {
+		return nil
+	}
+	out := new(Proxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxySource) DeepCopyInto(out *ProxySource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxySource.
+func (in *ProxySource) DeepCopy() *ProxySource {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TangServer) DeepCopyInto(out *TangServer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TangServer.
+func (in *TangServer) DeepCopy() *TangServer {
+	if in == nil {
+		return nil
+	}
+	out := new(TangServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionSkew) DeepCopyInto(out *VersionSkew) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionSkew.
+func (in *VersionSkew) DeepCopy() *VersionSkew {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionSkew)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookOptions) DeepCopyInto(out *WebhookOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookOptions.
+func (in *WebhookOptions) DeepCopy() *WebhookOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookOptions)
+	in.DeepCopyInto(out)
+	return out
+}
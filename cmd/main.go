@@ -17,18 +17,30 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -36,6 +48,9 @@ import (
 
 	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
 	"github.com/openshift-assisted/agent-controlplane-provider/internal/controller"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -47,7 +62,11 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(clusterv1.AddToScheme(scheme))
 	utilruntime.Must(controlplanev1.AddToScheme(scheme))
+	utilruntime.Must(aiv1beta1.AddToScheme(scheme))
+	utilruntime.Must(hiveext.AddToScheme(scheme))
+	utilruntime.Must(hivev1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -57,6 +76,14 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var gcStaleMachines bool
+	var infraEnvPatchInterval time.Duration
+	var imageRefreshInterval time.Duration
+	var maxReplicas int
+	var printConfig bool
+	var featureGates string
+	var controllerVersion string
+	var labelSelector string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -66,6 +93,29 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&gcStaleMachines, "gc-stale-machines", false,
+		"If set, control plane Machines that no longer match the current control plane are deleted "+
+			"once the desired replicas are available.")
+	flag.DurationVar(&infraEnvPatchInterval, "infraenv-patch-interval", time.Minute,
+		"The minimum time between two InfraEnv spec updates, each of which regenerates the discovery image. "+
+			"Changes made in between are coalesced. Set to 0 to disable.")
+	flag.DurationVar(&imageRefreshInterval, "image-refresh-interval", 0,
+		"The age at which a discovery image is regenerated, so that its download URLs are refreshed before they expire. "+
+			"It should be shorter than the validity of the URLs assisted-service generates. Set to 0 to disable.")
+	flag.IntVar(&maxReplicas, "max-control-plane-replicas", controlplanev1.DefaultMaxReplicas,
+		"The largest number of replicas the webhook allows for an AgentControlPlane. Must be odd.")
+	flag.StringVar(&featureGates, "feature-gates", "",
+		"A comma-separated list of Feature=true|false pairs enabling or disabling experimental features. "+
+			"Known features: ClusterStatus, ReconcileCache.")
+	flag.StringVar(&controllerVersion, "controller-version", "",
+		"The semantic version of this controller, e.g. the image tag. If set, it is stamped on the AgentControlPlanes "+
+			"it reconciles, and those already stamped by a newer version are skipped, so that an old and a new "+
+			"controller running side by side during a rollout do not fight over them.")
+	flag.StringVar(&labelSelector, "label-selector", "",
+		"If set, only the AgentControlPlanes matching this label selector, e.g. controller=tenant-a, are reconciled, "+
+			"so that several controllers can share a cluster.")
+	flag.BoolVar(&printConfig, "print-config", false,
+		"Print the effective configuration as JSON, with secrets redacted, and exit.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -74,6 +124,55 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	gates, err := controller.ParseFeatureGates(featureGates)
+	if err != nil {
+		setupLog.Error(err, "invalid --feature-gates")
+		os.Exit(1)
+	}
+	setupLog.Info("feature gates", "enabled", gates.EnabledFeatures())
+
+	var version *utilversion.Version
+	if controllerVersion != "" {
+		version, err = utilversion.ParseSemantic(controllerVersion)
+		if err != nil {
+			setupLog.Error(err, "invalid --controller-version")
+			os.Exit(1)
+		}
+	}
+
+	var selector labels.Selector
+	if labelSelector != "" {
+		selector, err = labels.Parse(labelSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid --label-selector")
+			os.Exit(1)
+		}
+	}
+
+	config := (&controller.AgentControlPlaneReconciler{
+		GarbageCollectStaleMachines: gcStaleMachines,
+		InfraEnvPatchInterval:       infraEnvPatchInterval,
+		ImageRefreshInterval:        imageRefreshInterval,
+		FeatureGates:                gates,
+		ControllerVersion:           version,
+		LabelSelector:               selector,
+	}).Config()
+	config.MaxReplicas = int32(maxReplicas)
+	config.Tracing = controller.TracingConfig{
+		Endpoint: firstEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"),
+		Headers:  controller.Redacted(firstEnv("OTEL_EXPORTER_OTLP_TRACES_HEADERS", "OTEL_EXPORTER_OTLP_HEADERS")),
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		setupLog.Error(err, "unable to serialize the configuration")
+		os.Exit(1)
+	}
+	if printConfig {
+		fmt.Println(string(configJSON))
+		return
+	}
+	setupLog.Info("effective configuration", "config", string(configJSON))
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -122,13 +221,55 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer shutdownTracing()
+
+	tracker, err := remote.NewClusterCacheTracker(mgr, remote.ClusterCacheTrackerOptions{
+		ControllerName: "agentcontrolplane",
+		Log:            &ctrl.Log,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create cluster cache tracker")
+		os.Exit(1)
+	}
+	if err = (&remote.ClusterCacheReconciler{
+		Client:  mgr.GetClient(),
+		Tracker: tracker,
+	}).SetupWithManager(ctx, mgr, ctrlcontroller.Options{}); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterCacheReconciler")
+		os.Exit(1)
+	}
+
 	if err = (&controller.AgentControlPlaneReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                      mgr.GetClient(),
+		APIReader:                   mgr.GetAPIReader(),
+		Scheme:                      mgr.GetScheme(),
+		GarbageCollectStaleMachines: gcStaleMachines,
+		InfraEnvPatchInterval:       infraEnvPatchInterval,
+		ImageRefreshInterval:        imageRefreshInterval,
+		FeatureGates:                gates,
+		ControllerVersion:           version,
+		LabelSelector:               selector,
+		Recorder:                    mgr.GetEventRecorderFor("agentcontrolplane-controller"),
+		WorkloadClusters:            tracker,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AgentControlPlane")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&controlplanev1.AgentControlPlane{}).SetupWebhookWithManager(mgr, controlplanev1.WebhookOptions{
+			MaxReplicas: int32(maxReplicas),
+		}); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AgentControlPlane")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -139,10 +280,52 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("informers",
+		controller.InformersSynced(mgr.GetCache(), &aiv1beta1.InfraEnv{}, &clusterv1.Cluster{})); err != nil {
+		setupLog.Error(err, "unable to set up informers ready check")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
+		shutdownTracing()
 		os.Exit(1)
 	}
 }
+
+// firstEnv returns the value of the first of the environment variables that
+// is set.
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// setupTracing exports reconcile spans over OTLP when an endpoint is
+// configured through the standard OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT environment variables. Otherwise the
+// global no-op TracerProvider is kept. The returned function flushes
+// pending spans.
+func setupTracing(ctx context.Context) (func(), error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func() {}, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(tp)
+	setupLog.Info("exporting traces over OTLP")
+
+	return func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			setupLog.Error(err, "unable to flush traces")
+		}
+	}, nil
+}
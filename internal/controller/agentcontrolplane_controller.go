@@ -18,45 +18,510 @@ package controller
 
 import (
 	"context"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
 )
 
+const (
+	// agentControlPlaneAnnotation is set on child objects to point back at the
+	// AgentControlPlane managing them. The value is "<namespace>/<name>";
+	// write and read it with setACPAnnotation and acpKeyFromAnnotation.
+	agentControlPlaneAnnotation = "controlplane.openshift.io/agent-control-plane"
+
+	// dependencyRequeueInterval is how long to wait before checking again
+	// for an API the controller depends on that is not installed yet.
+	dependencyRequeueInterval = time.Minute
+
+	// ownerClusterMinRequeueInterval and ownerClusterMaxRequeueInterval
+	// bound how long to wait before checking again for the owner Cluster.
+	// The wait doubles in between, as it lasts as long as the wait so far.
+	ownerClusterMinRequeueInterval = 5 * time.Second
+	ownerClusterMaxRequeueInterval = 2 * time.Minute
+
+	// infrastructureRequeueInterval is how long to wait before checking again
+	// whether the owner Cluster's infrastructure is ready.
+	infrastructureRequeueInterval = 20 * time.Second
+
+	// agentRequeueInterval is how long to wait before checking again for
+	// Agents to back the Machines that are still missing.
+	agentRequeueInterval = 30 * time.Second
+
+	// infraEnvConflictRequeueInterval is how long to wait before checking
+	// again whether the InfraEnv claimed by another AgentControlPlane was
+	// released. Its events only enqueue the claimant.
+	infraEnvConflictRequeueInterval = time.Minute
+
+	// reachabilityRequeueInterval is how long to wait before probing an
+	// unreachable control plane endpoint again.
+	reachabilityRequeueInterval = 30 * time.Second
+
+	// recreateCooldown is how long a child object seen deleted is left alone
+	// before it is recreated, so that a deletion racing with the reconcile,
+	// such as garbage collection after the AgentControlPlane is deleted,
+	// settles first.
+	recreateCooldown = 5 * time.Second
+
+	// installRetryRequeueInterval is how long to wait before checking again
+	// whether the AgentClusterInstall of a retried install is deleted, so
+	// that it can be recreated.
+	installRetryRequeueInterval = 10 * time.Second
+
+	// deleteRequeueInterval is how long to wait before checking again
+	// whether the control plane Machines of a deleted AgentControlPlane are
+	// gone.
+	deleteRequeueInterval = 10 * time.Second
+)
+
+// ownedConditions are the AgentControlPlane conditions set by this
+// controller. When patching status, its values take precedence over
+// concurrent changes to them, while conditions set by others are kept.
+var ownedConditions = []clusterv1.ConditionType{
+	controlplanev1.DependenciesReadyCondition,
+	controlplanev1.DependencyVersionCondition,
+	controlplanev1.OwnerClusterAvailableCondition,
+	controlplanev1.InstallCompleteCondition,
+	controlplanev1.ManifestsAvailableCondition,
+	controlplanev1.HostsValidatedCondition,
+	controlplanev1.HostRequirementsMetCondition,
+	controlplanev1.InfrastructureReadyCondition,
+	controlplanev1.ISOPropagatedCondition,
+	controlplanev1.MachinesCreatedCondition,
+	controlplanev1.MachineVersionsConvergedCondition,
+	controlplanev1.MachinesExpectedCondition,
+	controlplanev1.ControlPlaneReachableCondition,
+	controlplanev1.ControlPlaneEndpointSetCondition,
+	controlplanev1.MirrorRegistryConfiguredCondition,
+	controlplanev1.StaticRoutesConfiguredCondition,
+	controlplanev1.KubeconfigAvailableCondition,
+	controlplanev1.InfraEnvClaimedCondition,
+	controlplanev1.BootArtifactsAvailableCondition,
+	controlplanev1.PullSecretValidCondition,
+}
+
 // AgentControlPlaneReconciler reconciles a AgentControlPlane object
 type AgentControlPlaneReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// GarbageCollectStaleMachines enables deleting control plane Machines
+	// that no longer match the current control plane.
+	GarbageCollectStaleMachines bool
+
+	// Recorder records events on AgentControlPlanes. Events are not recorded
+	// when it is nil.
+	Recorder record.EventRecorder
+
+	// APIReader reads objects from the API server, bypassing the cache, where
+	// stale content must be avoided. Client is used when it is nil.
+	APIReader client.Reader
+
+	// WorkloadClusters provides clients for the workload clusters. Node
+	// reconciliation is skipped when it is nil.
+	WorkloadClusters WorkloadClusterClientGetter
+
+	// InfraEnvPatchInterval is the minimum time between two patches changing
+	// the spec of the same InfraEnv. Each such patch regenerates the
+	// discovery image, so changes made within the interval are coalesced
+	// into a single patch once it elapses. Zero disables the limit.
+	InfraEnvPatchInterval time.Duration
+
+	// ImageRefreshInterval is the age at which a discovery image is
+	// regenerated, so that its download URLs are refreshed before they
+	// expire. It should be shorter than the validity of the URLs
+	// assisted-service generates. Zero disables the refresh.
+	ImageRefreshInterval time.Duration
+
+	// ControllerVersion is the version of this controller. When set, it is
+	// stamped on the AgentControlPlanes it reconciles, and those stamped by
+	// a newer version are skipped.
+	ControllerVersion *version.Version
+
+	// FeatureGates enables or disables the experimental features. Features
+	// have their default when it is nil.
+	FeatureGates FeatureGates
+
+	// LabelSelector restricts the AgentControlPlanes this controller
+	// reconciles to those it matches, so that several controllers can share
+	// a cluster. All of them are reconciled when it is nil.
+	LabelSelector labels.Selector
+
+	// infraEnvPatches tracks when InfraEnv specs were last patched.
+	infraEnvPatches patchTracker
+
+	// infraEnvDeletions tracks when InfraEnvs were seen deleted, to hold off
+	// recreating them for recreateCooldown.
+	infraEnvDeletions patchTracker
+
+	// reconciles remembers the reconciles that left nothing to do, to skip
+	// those that would start from the same state.
+	reconciles reconcileCache
+
+	// TracerProvider provides the tracer for reconcile spans. The global
+	// TracerProvider is used when it is nil.
+	TracerProvider trace.TracerProvider
+
+	// now returns the current time; time.Now when nil.
+	now func() time.Time
+}
+
+// apiReader returns APIReader, or Client when it is nil.
+func (r *AgentControlPlaneReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// eventf records an event on acp if a Recorder is set.
+func (r *AgentControlPlaneReconciler) eventf(acp *controlplanev1.AgentControlPlane, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(acp, eventType, reason, messageFmt, args...)
+	}
+}
+
+func (r *AgentControlPlaneReconciler) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
 }
 
 //+kubebuilder:rbac:groups=controlplane.openshift.io,resources=agentcontrolplanes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=controlplane.openshift.io,resources=agentcontrolplanes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=controlplane.openshift.io,resources=agentcontrolplanes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=controlplane.openshift.io,resources=agentcontrolplaneconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=agent-install.openshift.io,resources=infraenvs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=agent-install.openshift.io,resources=agents,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=agent-install.openshift.io,resources=nmstateconfigs,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups=extensions.hive.openshift.io,resources=agentclusterinstalls,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=hive.openshift.io,resources=clusterdeployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=hive.openshift.io,resources=clusterimagesets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters/status,verbs=get;patch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=*,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;patch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the AgentControlPlane object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
 //
 // For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.2/pkg/reconcile
-func (r *AgentControlPlaneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.3/pkg/reconcile
+func (r *AgentControlPlaneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, rerr error) {
+	start := time.Now()
+	ctx, span := r.tracer().Start(ctx, "AgentControlPlane.Reconcile", trace.WithAttributes(
+		attribute.String("namespace", req.Namespace),
+		attribute.String("name", req.Name),
+	))
+	defer func() {
+		recordError(span, rerr)
+		span.End()
+		observeReconcile(start, res, rerr)
+	}()
+	ctx = withConfig(ctx, r.Config())
+	log := log.FromContext(ctx)
+
+	acp := &controlplanev1.AgentControlPlane{}
+	if err := r.Get(ctx, req.NamespacedName, acp); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// The watches of the owned objects enqueue their AgentControlPlane
+	// whatever its labels.
+	if !r.selects(acp) {
+		log.V(1).Info("the AgentControlPlane does not match the label selector, ignoring it")
+		return ctrl.Result{}, nil
+	}
+	// During a rollout, an older controller must not undo what a newer one
+	// did, nor the other way around.
+	if newer := r.newerVersion(acp); newer != nil {
+		log.Info("leaving the AgentControlPlane to a newer controller",
+			"version", r.ControllerVersion.String(), "newerVersion", newer.String())
+		return ctrl.Result{}, nil
+	}
+	// Defaulted before the patch helper takes its snapshot, so that the
+	// defaults are not persisted.
+	if err := r.inheritDefaults(ctx, acp); err != nil {
+		return ctrl.Result{}, err
+	}
+	applyDefaults(acp)
+
+	if acp.DeletionTimestamp.IsZero() && r.FeatureGates.Enabled(ReconcileCacheFeature) {
+		state, known := r.reconciledState(ctx, acp)
+		if known && r.reconciles.unchanged(acp, state) {
+			log.V(1).Info("nothing changed since the last reconcile")
+			return ctrl.Result{}, nil
+		}
+		// Registered before the status patch so that its errors are seen.
+		defer func() {
+			if known && rerr == nil && res.IsZero() {
+				r.reconciles.record(acp, state)
+				return
+			}
+			r.reconciles.forget(acp)
+		}()
+	}
+
+	// Registered before the status patch so that the patched status is
+	// summarized.
+	defer func() {
+		if log := log.V(1); log.Enabled() {
+			log.Info("reconcile summary", reconcileSummary(acp, res, rerr)...)
+		}
+	}()
+
+	patchHelper, err := patch.NewHelper(acp, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	defer func() {
+		updatePhase(acp)
+		if err := r.traced(ctx, "patchStatus", func(ctx context.Context) error {
+			return patchHelper.Patch(ctx, acp, patch.WithOwnedConditions{Conditions: ownedConditions})
+		}); err != nil {
+			rerr = kerrors.NewAggregate([]error{rerr, err})
+		}
+	}()
+
+	r.stampVersion(acp)
+
+	if !acp.DeletionTimestamp.IsZero() {
+		var result ctrl.Result
+		err := r.traced(ctx, "reconcileDelete", func(ctx context.Context) (err error) {
+			result, err = r.reconcileDelete(ctx, acp)
+			return err
+		})
+		return result, err
+	}
+	controllerutil.AddFinalizer(acp, controlplanev1.AgentControlPlaneFinalizer)
+
+	var ready bool
+	if err := r.traced(ctx, "reconcileDependencies", func(ctx context.Context) (err error) {
+		ready, err = r.reconcileDependencies(ctx, acp)
+		return err
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !ready {
+		log.Info("waiting for dependencies to be installed", "requeueAfter", dependencyRequeueInterval)
+		return ctrl.Result{RequeueAfter: dependencyRequeueInterval}, nil
+	}
+
+	var result ctrl.Result
+	if err := r.traced(ctx, "reconcilePullSecret", func(ctx context.Context) (err error) {
+		result, err = r.reconcilePullSecret(ctx, acp)
+		return err
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.traced(ctx, "reconcileInfraEnv", func(ctx context.Context) error {
+		infraEnvResult, err := r.reconcileInfraEnv(ctx, acp)
+		result = util.LowestNonZeroResult(result, infraEnvResult)
+		return err
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	// The Agents of a claimed InfraEnv are those of its claimant.
+	if conditions.IsFalse(acp, controlplanev1.InfraEnvClaimedCondition) {
+		log.Info("the InfraEnv is claimed by another AgentControlPlane", "requeueAfter", infraEnvConflictRequeueInterval)
+		return util.LowestNonZeroResult(result, ctrl.Result{RequeueAfter: infraEnvConflictRequeueInterval}), nil
+	}
+
+	cluster, err := r.ownerCluster(ctx, acp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster == nil {
+		requeueAfter := ownerClusterRequeueInterval(acp, r.clock())
+		log.Info("waiting for the owner Cluster", "requeueAfter", requeueAfter)
+		return util.LowestNonZeroResult(result, ctrl.Result{RequeueAfter: requeueAfter}), nil
+	}
+
+	if err := r.traced(ctx, "reconcileControlPlaneEndpoint", func(ctx context.Context) error {
+		return r.reconcileControlPlaneEndpoint(ctx, acp, cluster)
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.traced(ctx, "reconcileClusterInstall", func(ctx context.Context) error {
+		installResult, err := r.reconcileClusterInstall(ctx, acp, cluster)
+		result = util.LowestNonZeroResult(result, installResult)
+		return err
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	// TODO(user): your logic here
+	if err := r.traced(ctx, "reconcileKubeconfig", func(ctx context.Context) error {
+		return r.reconcileKubeconfig(ctx, acp, cluster)
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	return ctrl.Result{}, nil
+	if err := r.traced(ctx, "reconcileReachability", func(ctx context.Context) error {
+		result = util.LowestNonZeroResult(result, r.reconcileReachability(ctx, acp, cluster))
+		return nil
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.traced(ctx, "reconcileAgents", func(ctx context.Context) error {
+		return r.reconcileAgents(ctx, acp)
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.traced(ctx, "reconcileMachines", func(ctx context.Context) error {
+		machinesResult, err := r.reconcileMachines(ctx, acp, cluster)
+		result = util.LowestNonZeroResult(result, machinesResult)
+		return err
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.traced(ctx, "reconcileUnexpectedMachines", func(ctx context.Context) error {
+		return r.reconcileUnexpectedMachines(ctx, acp, cluster)
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if r.reconcileConfig(ctx).GarbageCollectStaleMachines && managesMachines(acp) {
+		if err := r.traced(ctx, "reconcileStaleMachines", func(ctx context.Context) error {
+			return r.reconcileStaleMachines(ctx, acp, cluster)
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.traced(ctx, "reconcileNodes", func(ctx context.Context) error {
+		return r.reconcileNodes(ctx, acp, cluster)
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if r.FeatureGates.Enabled(ClusterStatusFeature) {
+		if err := r.traced(ctx, "reconcileClusterStatus", func(ctx context.Context) error {
+			return r.reconcileClusterStatus(ctx, acp, cluster)
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// reconcileSummary returns the key-value pairs logging the outcome of a
+// reconcile of acp at a glance: its replicas, whether its discovery image can
+// be booted, and the summary of its conditions, worst first.
+func reconcileSummary(acp *controlplanev1.AgentControlPlane, res ctrl.Result, err error) []any {
+	// Summarized on a copy, so that no Ready condition is persisted.
+	summarized := acp.DeepCopy()
+	conditions.SetSummary(summarized, conditions.WithConditions(ownedConditions...))
+	summary := conditions.Get(summarized, clusterv1.ReadyCondition)
+	if summary == nil {
+		summary = &clusterv1.Condition{Status: corev1.ConditionUnknown}
+	}
+	keysAndValues := []any{
+		"phase", acp.Status.Phase,
+		"ready", acp.Status.Ready,
+		"replicas", acp.Status.Replicas,
+		"desiredReplicas", desiredReplicas(acp),
+		"infraEnvCreated", acp.Status.InfraEnvCreated,
+		"bootArtifactsAvailable", conditions.IsTrue(acp, controlplanev1.BootArtifactsAvailableCondition),
+		"conditionStatus", summary.Status,
+		"conditionReason", summary.Reason,
+		"requeueAfter", res.RequeueAfter,
+	}
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err.Error())
+	}
+	return keysAndValues
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AgentControlPlaneReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&controlplanev1.AgentControlPlane{}).
-		Complete(r)
+	ctx := context.Background()
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1.AgentControlPlane{}, builder.WithPredicates(r.selected(), specOrMetadataChanged())).
+		Watches(&clusterv1.Machine{}, r.ownerHandler(mgr)).
+		Watches(&clusterv1.Cluster{}, r.reconciles.invalidating(handler.EnqueueRequestsFromMapFunc(r.clusterToAgentControlPlane)))
+
+	// Watching a kind whose CRD is missing would keep the manager from
+	// starting. Without the watch, reconciles still recover through the
+	// dependency requeue once the CRD is installed.
+	for _, w := range []struct {
+		gvk   schema.GroupVersionKind
+		watch func(*builder.Builder) *builder.Builder
+	}{
+		{agentControlPlaneConfigGVK, func(b *builder.Builder) *builder.Builder {
+			return b.Watches(&controlplanev1.AgentControlPlaneConfig{}, r.reconciles.invalidating(handler.EnqueueRequestsFromMapFunc(r.configToAgentControlPlanes)))
+		}},
+		{infraEnvGVK, func(b *builder.Builder) *builder.Builder {
+			return b.Watches(&aiv1beta1.InfraEnv{}, r.reconciles.invalidating(
+				debouncing(handler.EnqueueRequestsFromMapFunc(r.infraEnvToAgentControlPlane), infraEnvEventDelay)))
+		}},
+		{agentGVK, func(b *builder.Builder) *builder.Builder {
+			return b.Watches(&aiv1beta1.Agent{}, r.reconciles.invalidating(handler.EnqueueRequestsFromMapFunc(r.agentToAgentControlPlane)))
+		}},
+		{agentClusterInstallGVK, func(b *builder.Builder) *builder.Builder {
+			return b.Watches(&hiveext.AgentClusterInstall{}, r.ownerHandler(mgr))
+		}},
+		{clusterDeploymentGVK, func(b *builder.Builder) *builder.Builder {
+			return b.Watches(&hivev1.ClusterDeployment{}, r.reconciles.invalidating(handler.EnqueueRequestsFromMapFunc(r.clusterDeploymentToAgentControlPlane)))
+		}},
+	} {
+		installed, err := crdInstalled(mgr.GetRESTMapper(), w.gvk)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			mgr.GetLogger().Info("CRD is not installed, changes will not be watched until the controller restarts", "groupVersionKind", w.gvk)
+			continue
+		}
+		b = w.watch(b)
+	}
+
+	if installed, err := crdInstalled(mgr.GetRESTMapper(), clusterDeploymentGVK); err != nil {
+		return err
+	} else if installed {
+		if err := mgr.GetFieldIndexer().IndexField(ctx, &hivev1.ClusterDeployment{},
+			clusterDeploymentOwnerField, indexClusterDeploymentByOwner); err != nil {
+			return err
+		}
+	}
+
+	return b.Complete(r)
+}
+
+// ownerHandler enqueues the AgentControlPlane controlling an object, like
+// Owns does, dropping what its reconcile cache remembers about it.
+func (r *AgentControlPlaneReconciler) ownerHandler(mgr ctrl.Manager) handler.EventHandler {
+	return r.reconciles.invalidating(handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(),
+		&controlplanev1.AgentControlPlane{}, handler.OnlyControllerOwner()))
 }
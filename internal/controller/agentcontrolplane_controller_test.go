@@ -18,11 +18,18 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -82,3 +89,96 @@ var _ = Describe("AgentControlPlane Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("Status patching", func() {
+	ctx := context.Background()
+
+	It("keeps conditions set concurrently by others", func() {
+		acp := newAgentControlPlane("test-acp")
+
+		// Another writer updates the conditions right after this reconcile
+		// read the AgentControlPlane: it sets its own condition and a stale
+		// value for one owned by this controller.
+		var updated bool
+		c := newFakeClientBuilder(newTestScheme(), acp).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					if err := c.Get(ctx, key, obj, opts...); err != nil {
+						return err
+					}
+					if _, ok := obj.(*controlplanev1.AgentControlPlane); !ok || updated {
+						return nil
+					}
+					updated = true
+					other := &controlplanev1.AgentControlPlane{}
+					Expect(c.Get(ctx, key, other)).To(Succeed())
+					conditions.MarkTrue(other, "External")
+					conditions.MarkFalse(other, controlplanev1.DependenciesReadyCondition, "Stale", clusterv1.ConditionSeverityInfo, "")
+					return c.Status().Update(ctx, other)
+				},
+			}).
+			Build()
+
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		patched := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), patched)).To(Succeed())
+		Expect(conditions.IsTrue(patched, "External")).To(BeTrue())
+		Expect(conditions.IsTrue(patched, controlplanev1.DependenciesReadyCondition)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Reconcile summary", func() {
+	ctx := context.Background()
+
+	reconcileLogging := func(verbosity int) []map[string]any {
+		acp := newAgentControlPlane("test-acp")
+		cluster := newCluster("test-cluster")
+		setOwnerCluster(acp, cluster)
+		c := newFakeClient(newTestScheme(), acp, cluster, newPullSecret())
+
+		var lines []map[string]any
+		logger := funcr.NewJSON(func(obj string) {
+			line := map[string]any{}
+			Expect(json.Unmarshal([]byte(obj), &line)).To(Succeed())
+			lines = append(lines, line)
+		}, funcr.Options{Verbosity: verbosity})
+
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(log.IntoContext(ctx, logger), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		return lines
+	}
+
+	summaries := func(lines []map[string]any) []map[string]any {
+		var summaries []map[string]any
+		for _, line := range lines {
+			if line["msg"] == "reconcile summary" {
+				summaries = append(summaries, line)
+			}
+		}
+		return summaries
+	}
+
+	It("ends each reconcile with a summary line", func() {
+		summary := summaries(reconcileLogging(1))
+		Expect(summary).To(HaveLen(1))
+
+		Expect(summary[0]).To(HaveKeyWithValue("phase", string(controlplanev1.AgentControlPlanePhaseProvisioning)))
+		Expect(summary[0]).To(HaveKeyWithValue("ready", false))
+		Expect(summary[0]).To(HaveKeyWithValue("replicas", BeNumerically("==", 0)))
+		Expect(summary[0]).To(HaveKeyWithValue("desiredReplicas", BeNumerically("==", 1)))
+		Expect(summary[0]).To(HaveKeyWithValue("infraEnvCreated", true))
+		Expect(summary[0]).To(HaveKeyWithValue("bootArtifactsAvailable", false))
+		Expect(summary[0]).To(HaveKeyWithValue("conditionStatus", "False"))
+		Expect(summary[0]).To(HaveKeyWithValue("conditionReason", Not(BeEmpty())))
+		Expect(summary[0]).To(HaveKey("requeueAfter"))
+		Expect(summary[0]).NotTo(HaveKey("error"))
+	})
+
+	It("logs the summary at debug verbosity only", func() {
+		Expect(summaries(reconcileLogging(0))).To(BeEmpty())
+	})
+})
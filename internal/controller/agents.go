@@ -0,0 +1,351 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+// agentSelector returns the labels an Agent must carry to be considered for
+// acp: the hosts discovered through its InfraEnv, narrowed down by
+// spec.hostSelector.
+func agentSelector(acp *controlplanev1.AgentControlPlane) map[string]string {
+	selector := make(map[string]string, len(acp.Spec.HostSelector)+1)
+	for k, v := range acp.Spec.HostSelector {
+		selector[k] = v
+	}
+	selector[aiv1beta1.InfraEnvNameLabel] = acp.Name
+	return selector
+}
+
+// reconcileAgents approves and binds discovered Agents matching
+// agentSelector to the control plane's ClusterDeployment until the desired
+// number of replicas is bound. Agents already bound elsewhere are left alone,
+// as are those falling short of spec.minimumHostRequirements, which are
+// reported on the HostRequirementsMetCondition.
+func (r *AgentControlPlaneReconciler) reconcileAgents(ctx context.Context, acp *controlplanev1.AgentControlPlane) error {
+	log := log.FromContext(ctx)
+
+	agents := &aiv1beta1.AgentList{}
+	if err := r.List(ctx, agents, client.InNamespace(infraEnvKey(acp).Namespace), client.MatchingLabels(agentSelector(acp))); err != nil {
+		return err
+	}
+
+	clusterDeployment := aiv1beta1.ClusterReference{Name: acp.Name, Namespace: acp.Namespace}
+	var bound int32
+	var unbound []*aiv1beta1.Agent
+	for i := range agents.Items {
+		agent := &agents.Items[i]
+		switch ref := agent.Spec.ClusterDeploymentName; {
+		case ref == nil:
+			unbound = append(unbound, agent)
+		case *ref == clusterDeployment:
+			bound++
+		}
+	}
+
+	var shortfalls []string
+	for _, agent := range unbound {
+		if bound >= desiredReplicas(acp) {
+			break
+		}
+		if shortfall := hostShortfall(acp, agent); shortfall != "" {
+			shortfalls = append(shortfalls, fmt.Sprintf("%s: %s", agent.Name, shortfall))
+			continue
+		}
+		log.Info("binding Agent to the control plane", "agent", client.ObjectKeyFromObject(agent))
+		patch := client.MergeFrom(agent.DeepCopy())
+		agent.Spec.ClusterDeploymentName = &clusterDeployment
+		agent.Spec.Role = aiv1beta1.HostRoleMaster
+		agent.Spec.Approved = true
+		if err := r.Patch(ctx, agent, patch); err != nil {
+			return err
+		}
+		bound++
+	}
+	updateHostRequirementsMetCondition(acp, shortfalls)
+
+	var boundAgents []*aiv1beta1.Agent
+	for i := range agents.Items {
+		if ref := agents.Items[i].Spec.ClusterDeploymentName; ref != nil && *ref == clusterDeployment {
+			boundAgents = append(boundAgents, &agents.Items[i])
+		}
+	}
+	if err := r.reconcileHostnames(ctx, acp, boundAgents); err != nil {
+		return err
+	}
+	for _, agent := range boundAgents {
+		if err := r.reconcileInstallationDisk(ctx, acp, agent); err != nil {
+			return err
+		}
+		if err := r.reconcileIgnitionOverride(ctx, acp, agent); err != nil {
+			return err
+		}
+	}
+	updateHostsValidatedCondition(acp, boundAgents)
+	acp.Status.Agents = agentSummaries(boundAgents)
+	return nil
+}
+
+// agentSummaries summarizes agents for the status, sorted by name and capped
+// at MaxAgentSummaries.
+func agentSummaries(agents []*aiv1beta1.Agent) []controlplanev1.AgentSummary {
+	var summaries []controlplanev1.AgentSummary
+	for _, agent := range agents {
+		summary := controlplanev1.AgentSummary{
+			Name:  agent.Name,
+			Role:  string(agent.Spec.Role),
+			Stage: agent.Status.Progress.CurrentStage,
+		}
+		for _, condition := range agent.Status.Conditions {
+			if condition.Type == aiv1beta1.RequirementsMetCondition {
+				summary.Ready = condition.Status == corev1.ConditionTrue
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	if len(summaries) > controlplanev1.MaxAgentSummaries {
+		summaries = summaries[:controlplanev1.MaxAgentSummaries]
+	}
+	return summaries
+}
+
+// availableAgents returns the Agents matching agentSelector that can back a
+// control plane Machine of acp: those bound to it and those not bound yet
+// that meet the minimum host requirements.
+func (r *AgentControlPlaneReconciler) availableAgents(ctx context.Context, acp *controlplanev1.AgentControlPlane) ([]aiv1beta1.Agent, error) {
+	agents := &aiv1beta1.AgentList{}
+	if err := r.List(ctx, agents, client.InNamespace(infraEnvKey(acp).Namespace), client.MatchingLabels(agentSelector(acp))); err != nil {
+		return nil, err
+	}
+
+	clusterDeployment := aiv1beta1.ClusterReference{Name: acp.Name, Namespace: acp.Namespace}
+	var available []aiv1beta1.Agent
+	for i := range agents.Items {
+		switch ref := agents.Items[i].Spec.ClusterDeploymentName; {
+		case ref == nil && hostShortfall(acp, &agents.Items[i]) == "":
+			available = append(available, agents.Items[i])
+		case ref != nil && *ref == clusterDeployment:
+			available = append(available, agents.Items[i])
+		}
+	}
+	return available, nil
+}
+
+// hostShortfall describes how agent falls short of the minimum host
+// requirements of acp, or returns "" when it meets them. A host that has not
+// reported its inventory yet falls short of any requirement.
+func hostShortfall(acp *controlplanev1.AgentControlPlane, agent *aiv1beta1.Agent) string {
+	requirements := acp.Spec.MinimumHostRequirements
+	if requirements == nil {
+		return ""
+	}
+
+	var shortfalls []string
+	inventory := agent.Status.Inventory
+	if requirements.CPUCores > 0 && inventory.Cpu.Count < requirements.CPUCores {
+		shortfalls = append(shortfalls, fmt.Sprintf("%d CPU cores, %d required", inventory.Cpu.Count, requirements.CPUCores))
+	}
+	if requirements.Memory != nil && inventory.Memory.PhysicalBytes < requirements.Memory.Value() {
+		memory := resource.NewQuantity(inventory.Memory.PhysicalBytes, resource.BinarySI)
+		shortfalls = append(shortfalls, fmt.Sprintf("%s memory, %s required", memory, requirements.Memory))
+	}
+	return strings.Join(shortfalls, ", ")
+}
+
+// updateHostRequirementsMetCondition sets the HostRequirementsMetCondition
+// from the shortfalls of the Agents skipped while binding.
+func updateHostRequirementsMetCondition(acp *controlplanev1.AgentControlPlane, shortfalls []string) {
+	switch {
+	case acp.Spec.MinimumHostRequirements == nil:
+		conditions.Delete(acp, controlplanev1.HostRequirementsMetCondition)
+	case len(shortfalls) > 0:
+		conditions.MarkFalse(acp, controlplanev1.HostRequirementsMetCondition, controlplanev1.InsufficientHostResourcesReason,
+			clusterv1.ConditionSeverityWarning, "Hosts below the minimum requirements: %s", strings.Join(shortfalls, "; "))
+	default:
+		conditions.MarkTrue(acp, controlplanev1.HostRequirementsMetCondition)
+	}
+}
+
+// reconcileHostnames names the bound Agents after spec.hostnameTemplate. An
+// Agent already carrying the hostname of an ordinal keeps it; the others, in
+// name order, get the lowest ordinals left.
+func (r *AgentControlPlaneReconciler) reconcileHostnames(ctx context.Context, acp *controlplanev1.AgentControlPlane, agents []*aiv1beta1.Agent) error {
+	if acp.Spec.HostnameTemplate == "" {
+		return nil
+	}
+
+	ordinals := make(map[string]int, desiredReplicas(acp))
+	for i := 0; i < int(desiredReplicas(acp)); i++ {
+		ordinals[controlplanev1.RenderHostname(acp.Spec.HostnameTemplate, i)] = i
+	}
+	taken := sets.New[int]()
+	var unnamed []*aiv1beta1.Agent
+	for _, agent := range agents {
+		if i, ok := ordinals[agent.Spec.Hostname]; ok && !taken.Has(i) {
+			taken.Insert(i)
+			continue
+		}
+		unnamed = append(unnamed, agent)
+	}
+	sort.Slice(unnamed, func(i, j int) bool { return unnamed[i].Name < unnamed[j].Name })
+
+	ordinal := 0
+	for _, agent := range unnamed {
+		for taken.Has(ordinal) {
+			ordinal++
+		}
+		taken.Insert(ordinal)
+		hostname := controlplanev1.RenderHostname(acp.Spec.HostnameTemplate, ordinal)
+
+		log.FromContext(ctx).Info("setting Agent hostname", "agent", client.ObjectKeyFromObject(agent), "hostname", hostname)
+		patch := client.MergeFrom(agent.DeepCopy())
+		agent.Spec.Hostname = hostname
+		if err := r.Patch(ctx, agent, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileInstallationDisk sets the installation disk of agent from the
+// first matching spec.installationDisks hint, if any.
+func (r *AgentControlPlaneReconciler) reconcileInstallationDisk(ctx context.Context, acp *controlplanev1.AgentControlPlane, agent *aiv1beta1.Agent) error {
+	hint := installationDiskHint(acp, agent)
+	if hint == nil || agent.Spec.InstallationDiskID == hint.DiskID {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("setting Agent installation disk", "agent", client.ObjectKeyFromObject(agent), "diskID", hint.DiskID)
+	patch := client.MergeFrom(agent.DeepCopy())
+	agent.Spec.InstallationDiskID = hint.DiskID
+	return r.Patch(ctx, agent, patch)
+}
+
+// reconcileIgnitionOverride sets the ignition override of agent from its
+// spec.hostIgnitionOverrides entry, if any.
+func (r *AgentControlPlaneReconciler) reconcileIgnitionOverride(ctx context.Context, acp *controlplanev1.AgentControlPlane, agent *aiv1beta1.Agent) error {
+	override, ok := acp.Spec.HostIgnitionOverrides[agentHostname(agent)]
+	if !ok || agent.Spec.IgnitionConfigOverrides == override {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("setting Agent ignition override", "agent", client.ObjectKeyFromObject(agent))
+	patch := client.MergeFrom(agent.DeepCopy())
+	agent.Spec.IgnitionConfigOverrides = override
+	return r.Patch(ctx, agent, patch)
+}
+
+// agentHostname returns the hostname assigned to agent, or else the one it
+// reports.
+func agentHostname(agent *aiv1beta1.Agent) string {
+	if agent.Spec.Hostname != "" {
+		return agent.Spec.Hostname
+	}
+	return agent.Status.Inventory.Hostname
+}
+
+// installationDiskHint returns the first spec.installationDisks hint matching
+// agent by hostname or labels, or nil.
+func installationDiskHint(acp *controlplanev1.AgentControlPlane, agent *aiv1beta1.Agent) *controlplanev1.InstallationDiskHint {
+	hostname := agentHostname(agent)
+	for i := range acp.Spec.InstallationDisks {
+		hint := &acp.Spec.InstallationDisks[i]
+		if hint.Hostname != "" && hint.Hostname == hostname {
+			return hint
+		}
+		if len(hint.Selector) > 0 && labels.SelectorFromSet(hint.Selector).Matches(labels.Set(agent.Labels)) {
+			return hint
+		}
+	}
+	return nil
+}
+
+// updateHostsValidatedCondition sets the HostsValidatedCondition from the
+// validation results of the Agents bound to acp, listing the failing
+// validations of each Agent.
+func updateHostsValidatedCondition(acp *controlplanev1.AgentControlPlane, agents []*aiv1beta1.Agent) {
+	if len(agents) == 0 {
+		conditions.Delete(acp, controlplanev1.HostsValidatedCondition)
+		return
+	}
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	var failures []string
+	for _, agent := range agents {
+		categories := make([]string, 0, len(agent.Status.ValidationsInfo))
+		for category := range agent.Status.ValidationsInfo {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+
+		var failed []string
+		for _, category := range categories {
+			for _, validation := range agent.Status.ValidationsInfo[category] {
+				if validation.Status == aiv1beta1.ValidationFailure {
+					failed = append(failed, fmt.Sprintf("%s (%s)", validation.ID, validation.Message))
+				}
+			}
+		}
+		if len(failed) > 0 {
+			failures = append(failures, fmt.Sprintf("%s: %s", agent.Name, strings.Join(failed, ", ")))
+		}
+	}
+
+	if len(failures) > 0 {
+		conditions.MarkFalse(acp, controlplanev1.HostsValidatedCondition, controlplanev1.HostValidationFailedReason,
+			clusterv1.ConditionSeverityWarning, "Hosts failing validations: %s", strings.Join(failures, "; "))
+		return
+	}
+	conditions.MarkTrue(acp, controlplanev1.HostsValidatedCondition)
+}
+
+// agentToAgentControlPlane maps an Agent to the AgentControlPlane owning the
+// InfraEnv it was discovered through. That is the AgentControlPlane named in
+// the InfraEnv's agentControlPlaneAnnotation, which may live in another
+// namespace, or else the one of the InfraEnv's name in the Agent's namespace.
+func (r *AgentControlPlaneReconciler) agentToAgentControlPlane(ctx context.Context, obj client.Object) []ctrl.Request {
+	name, ok := obj.GetLabels()[aiv1beta1.InfraEnvNameLabel]
+	if !ok || name == "" {
+		return nil
+	}
+	infraEnv := &aiv1beta1.InfraEnv{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}, infraEnv); err == nil {
+		if requests := r.infraEnvToAgentControlPlane(ctx, infraEnv); len(requests) > 0 {
+			return requests
+		}
+	}
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+var _ = Describe("Agent binding", func() {
+	ctx := context.Background()
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		cluster := newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.Replicas = ptr.To[int32](3)
+		acp.Spec.HostSelector = map[string]string{"rack": "r1"}
+		setOwnerCluster(acp, cluster)
+	})
+
+	newAgent := func(name string, labels map[string]string) *aiv1beta1.Agent {
+		return &aiv1beta1.Agent{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: labels},
+		}
+	}
+
+	reconcileAgents := func(objs ...client.Object) client.Client {
+		c := newFakeClient(newTestScheme(), append(objs, acp, newCluster("test-cluster"))...)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		return c
+	}
+
+	getAgent := func(c client.Client, name string) *aiv1beta1.Agent {
+		agent := &aiv1beta1.Agent{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: name}, agent)).To(Succeed())
+		return agent
+	}
+
+	It("only binds Agents matching the host selector", func() {
+		c := reconcileAgents(
+			newAgent("matching", map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1"}),
+			newAgent("other-rack", map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r2"}),
+			newAgent("no-rack", map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name}),
+			newAgent("other-infraenv", map[string]string{aiv1beta1.InfraEnvNameLabel: "other", "rack": "r1"}),
+		)
+
+		matching := getAgent(c, "matching")
+		Expect(matching.Spec.ClusterDeploymentName).To(HaveValue(Equal(
+			aiv1beta1.ClusterReference{Name: acp.Name, Namespace: acp.Namespace})))
+		Expect(matching.Spec.Approved).To(BeTrue())
+		Expect(matching.Spec.Role).To(Equal(aiv1beta1.HostRoleMaster))
+
+		for _, name := range []string{"other-rack", "no-rack", "other-infraenv"} {
+			agent := getAgent(c, name)
+			Expect(agent.Spec.ClusterDeploymentName).To(BeNil(), name)
+			Expect(agent.Spec.Approved).To(BeFalse(), name)
+		}
+
+		clusterDeployment := &hivev1.ClusterDeployment{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), clusterDeployment)).To(Succeed())
+		Expect(clusterDeployment.Spec.Platform.AgentBareMetal.AgentSelector.MatchLabels).To(Equal(map[string]string{
+			aiv1beta1.InfraEnvNameLabel: acp.Name,
+			"rack":                      "r1",
+		}))
+	})
+
+	It("stops binding once the desired replicas are bound", func() {
+		acp.Spec.Replicas = ptr.To[int32](1)
+		labels := map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1"}
+		c := reconcileAgents(newAgent("agent-a", labels), newAgent("agent-b", labels))
+
+		var bound int
+		for _, name := range []string{"agent-a", "agent-b"} {
+			if getAgent(c, name).Spec.ClusterDeploymentName != nil {
+				bound++
+			}
+		}
+		Expect(bound).To(Equal(1))
+	})
+
+	It("summarizes the bound Agents in the status", func() {
+		acp.Spec.Replicas = ptr.To[int32](2)
+		labels := map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1"}
+		installing := newAgent("agent-b", labels)
+		installing.Status.Progress.CurrentStage = "Writing image to disk"
+		installing.Status.Conditions = []aiv1beta1.AgentCondition{
+			{Type: aiv1beta1.RequirementsMetCondition, Status: corev1.ConditionTrue},
+		}
+		waiting := newAgent("agent-a", labels)
+		waiting.Status.Conditions = []aiv1beta1.AgentCondition{
+			{Type: aiv1beta1.RequirementsMetCondition, Status: corev1.ConditionFalse},
+		}
+		c := reconcileAgents(installing, waiting)
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(updated.Status.Agents).To(Equal([]controlplanev1.AgentSummary{
+			{Name: "agent-a", Role: "master"},
+			{Name: "agent-b", Role: "master", Stage: "Writing image to disk", Ready: true},
+		}))
+	})
+
+	Context("host validations", func() {
+		var labels map[string]string
+
+		BeforeEach(func() {
+			labels = map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1"}
+		})
+
+		getACP := func(c client.Client) *controlplanev1.AgentControlPlane {
+			updated := &controlplanev1.AgentControlPlane{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+			return updated
+		}
+
+		It("reports the failing validations of bound Agents", func() {
+			failing := newAgent("agent-a", labels)
+			failing.Status.ValidationsInfo = aiv1beta1.ValidationsInfo{
+				"hardware": {
+					{ID: "has-cpu-cores-for-role", Status: "success", Message: "Sufficient CPU cores for role master"},
+					{ID: "has-min-valid-disks", Status: aiv1beta1.ValidationFailure, Message: "No eligible disks were found"},
+				},
+				"network": {
+					{ID: "belongs-to-machine-cidr", Status: "success", Message: "Host belongs to all machine network CIDRs"},
+				},
+			}
+			passing := newAgent("agent-b", labels)
+			passing.Status.ValidationsInfo = aiv1beta1.ValidationsInfo{
+				"hardware": {{ID: "has-min-valid-disks", Status: "success", Message: "Sufficient disk capacity"}},
+			}
+			c := reconcileAgents(failing, passing)
+
+			condition := conditions.Get(getACP(c), controlplanev1.HostsValidatedCondition)
+			Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(controlplanev1.HostValidationFailedReason))
+			Expect(condition.Message).To(Equal("Hosts failing validations: agent-a: has-min-valid-disks (No eligible disks were found)"))
+		})
+
+		It("marks the hosts validated once every bound Agent passes", func() {
+			agent := newAgent("agent-a", labels)
+			agent.Status.ValidationsInfo = aiv1beta1.ValidationsInfo{
+				"hardware": {{ID: "has-min-valid-disks", Status: "success", Message: "Sufficient disk capacity"}},
+			}
+			c := reconcileAgents(agent)
+
+			Expect(conditions.IsTrue(getACP(c), controlplanev1.HostsValidatedCondition)).To(BeTrue())
+		})
+
+		It("ignores Agents that are not bound to the control plane", func() {
+			agent := newAgent("agent-a", map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r2"})
+			agent.Status.ValidationsInfo = aiv1beta1.ValidationsInfo{
+				"hardware": {{ID: "has-min-valid-disks", Status: aiv1beta1.ValidationFailure, Message: "No eligible disks were found"}},
+			}
+			c := reconcileAgents(agent)
+
+			Expect(conditions.Has(getACP(c), controlplanev1.HostsValidatedCondition)).To(BeFalse())
+		})
+	})
+	Context("minimum host requirements", func() {
+		var labels map[string]string
+
+		BeforeEach(func() {
+			labels = map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1"}
+			acp.Spec.MinimumHostRequirements = &controlplanev1.HostRequirements{
+				CPUCores: 4,
+				Memory:   ptr.To(resource.MustParse("16Gi")),
+			}
+		})
+
+		newSizedAgent := func(name string, cpus int64, memory string) *aiv1beta1.Agent {
+			agent := newAgent(name, labels)
+			agent.Status.Inventory.Cpu.Count = cpus
+			quantity := resource.MustParse(memory)
+			agent.Status.Inventory.Memory.PhysicalBytes = quantity.Value()
+			return agent
+		}
+
+		It("binds only the Agents meeting the requirements", func() {
+			c := reconcileAgents(newSizedAgent("agent-a", 2, "8Gi"), newSizedAgent("agent-b", 8, "32Gi"))
+
+			Expect(getAgent(c, "agent-a").Spec.ClusterDeploymentName).To(BeNil())
+			Expect(getAgent(c, "agent-b").Spec.ClusterDeploymentName).NotTo(BeNil())
+
+			updated := &controlplanev1.AgentControlPlane{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+			condition := conditions.Get(updated, controlplanev1.HostRequirementsMetCondition)
+			Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(controlplanev1.InsufficientHostResourcesReason))
+			Expect(condition.Message).To(Equal(
+				"Hosts below the minimum requirements: agent-a: 2 CPU cores, 4 required, 8Gi memory, 16Gi required"))
+		})
+
+		It("marks the requirements met when no Agent falls short", func() {
+			c := reconcileAgents(newSizedAgent("agent-a", 4, "16Gi"))
+
+			Expect(getAgent(c, "agent-a").Spec.ClusterDeploymentName).NotTo(BeNil())
+			updated := &controlplanev1.AgentControlPlane{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+			Expect(conditions.IsTrue(updated, controlplanev1.HostRequirementsMetCondition)).To(BeTrue())
+		})
+	})
+
+	Context("hostnames", func() {
+		var labels map[string]string
+
+		BeforeEach(func() {
+			labels = map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1"}
+			acp.Spec.HostnameTemplate = "cp-{ordinal}"
+		})
+
+		It("gives the bound Agents sequential hostnames", func() {
+			acp.Spec.Replicas = ptr.To[int32](2)
+			c := reconcileAgents(newAgent("agent-a", labels), newAgent("agent-b", labels))
+
+			Expect(getAgent(c, "agent-a").Spec.Hostname).To(Equal("cp-0"))
+			Expect(getAgent(c, "agent-b").Spec.Hostname).To(Equal("cp-1"))
+		})
+
+		It("keeps the ordinals already assigned", func() {
+			named := newAgent("agent-b", labels)
+			named.Spec.Hostname = "cp-0"
+			renamed := newAgent("agent-a", labels)
+			renamed.Spec.Hostname = "localhost"
+			c := reconcileAgents(named, renamed, newAgent("agent-c", labels))
+
+			Expect(getAgent(c, "agent-b").Spec.Hostname).To(Equal("cp-0"))
+			Expect(getAgent(c, "agent-a").Spec.Hostname).To(Equal("cp-1"))
+			Expect(getAgent(c, "agent-c").Spec.Hostname).To(Equal("cp-2"))
+		})
+	})
+
+	Context("installation disks", func() {
+		var labels map[string]string
+
+		BeforeEach(func() {
+			labels = map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1"}
+		})
+
+		It("sets the installation disk of the Agent matching a hint", func() {
+			acp.Spec.InstallationDisks = []controlplanev1.InstallationDiskHint{
+				{Hostname: "master-0", DiskID: "/dev/disk/by-id/wwn-0x5000c50015ea71ac"},
+				{Hostname: "unknown", DiskID: "/dev/sdb"},
+			}
+			matching := newAgent("agent-a", labels)
+			matching.Status.Inventory.Hostname = "master-0"
+			other := newAgent("agent-b", labels)
+			other.Status.Inventory.Hostname = "master-1"
+			c := reconcileAgents(matching, other)
+
+			Expect(getAgent(c, "agent-a").Spec.InstallationDiskID).To(Equal("/dev/disk/by-id/wwn-0x5000c50015ea71ac"))
+			Expect(getAgent(c, "agent-b").Spec.InstallationDiskID).To(BeEmpty())
+		})
+
+		It("matches hints by label selector and prefers the hostname override", func() {
+			acp.Spec.InstallationDisks = []controlplanev1.InstallationDiskHint{
+				{Hostname: "renamed", DiskID: "/dev/sda"},
+				{Selector: map[string]string{"disk": "nvme"}, DiskID: "/dev/nvme0n1"},
+			}
+			renamed := newAgent("agent-a", labels)
+			renamed.Spec.Hostname = "renamed"
+			renamed.Status.Inventory.Hostname = "localhost"
+			selected := newAgent("agent-b", map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name, "rack": "r1", "disk": "nvme"})
+			c := reconcileAgents(renamed, selected)
+
+			Expect(getAgent(c, "agent-a").Spec.InstallationDiskID).To(Equal("/dev/sda"))
+			Expect(getAgent(c, "agent-b").Spec.InstallationDiskID).To(Equal("/dev/nvme0n1"))
+		})
+
+		It("sets the ignition override of the Agent matching a hostname", func() {
+			acp.Spec.HostIgnitionOverrides = map[string]string{"master-0": `{"ignition":{"version":"3.2.0"}}`}
+			matching := newAgent("agent-a", labels)
+			matching.Status.Inventory.Hostname = "master-0"
+			other := newAgent("agent-b", labels)
+			other.Status.Inventory.Hostname = "master-1"
+			other.Spec.IgnitionConfigOverrides = `{"ignition":{"version":"3.1.0"}}`
+			c := reconcileAgents(matching, other)
+
+			Expect(getAgent(c, "agent-a").Spec.IgnitionConfigOverrides).To(Equal(`{"ignition":{"version":"3.2.0"}}`))
+			Expect(getAgent(c, "agent-b").Spec.IgnitionConfigOverrides).To(Equal(`{"ignition":{"version":"3.1.0"}}`))
+		})
+	})
+})
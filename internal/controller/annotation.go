@@ -0,0 +1,56 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// setACPAnnotation points obj back at acp through the
+// agentControlPlaneAnnotation. acpKeyFromAnnotation reads it back.
+func setACPAnnotation(obj metav1.Object, acp *controlplanev1.AgentControlPlane) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[agentControlPlaneAnnotation] = client.ObjectKeyFromObject(acp).String()
+	obj.SetAnnotations(annotations)
+}
+
+// acpKeyFromAnnotation returns the key of the AgentControlPlane obj points
+// back at through the agentControlPlaneAnnotation, and whether it carries
+// one. It decodes both "<namespace>/<name>" and the bare "<name>" of a key
+// without a namespace, as written by client.ObjectKey.String.
+func acpKeyFromAnnotation(obj metav1.Object) (client.ObjectKey, bool) {
+	value, ok := obj.GetAnnotations()[agentControlPlaneAnnotation]
+	if !ok {
+		return client.ObjectKey{}, false
+	}
+	namespace, name, found := strings.Cut(value, "/")
+	if !found {
+		namespace, name = "", value
+	}
+	if name == "" || strings.Contains(name, "/") {
+		return client.ObjectKey{}, false
+	}
+	return client.ObjectKey{Namespace: namespace, Name: name}, true
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("AgentControlPlane annotation", func() {
+	DescribeTable("round-trips the key of the AgentControlPlane",
+		func(namespace string) {
+			acp := newAgentControlPlane("test-acp")
+			acp.Namespace = namespace
+			obj := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"other": "kept"}}}
+
+			setACPAnnotation(obj, acp)
+
+			key, ok := acpKeyFromAnnotation(obj)
+			Expect(ok).To(BeTrue())
+			Expect(key).To(Equal(client.ObjectKeyFromObject(acp)))
+			Expect(obj.Annotations).To(HaveKeyWithValue("other", "kept"))
+		},
+		Entry("namespaced", testNamespace),
+		Entry("cluster-scoped", ""),
+	)
+
+	It("annotates an object without annotations", func() {
+		obj := &corev1.Secret{}
+
+		setACPAnnotation(obj, newAgentControlPlane("test-acp"))
+
+		Expect(obj.Annotations).To(HaveKeyWithValue(agentControlPlaneAnnotation, testNamespace+"/test-acp"))
+	})
+
+	DescribeTable("rejects missing and malformed annotations",
+		func(annotations map[string]string) {
+			_, ok := acpKeyFromAnnotation(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+			Expect(ok).To(BeFalse())
+		},
+		Entry("no annotations", nil),
+		Entry("an empty value", map[string]string{agentControlPlaneAnnotation: ""}),
+		Entry("no name", map[string]string{agentControlPlaneAnnotation: testNamespace + "/"}),
+		Entry("too many segments", map[string]string{agentControlPlaneAnnotation: "a/b/c"}),
+	)
+})
@@ -0,0 +1,185 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// fieldManager is the field manager of the server-side applies of this
+// controller. It must not change, or the fields applied under the previous
+// name would be left behind with a second owner.
+const fieldManager = "agent-controlplane-provider"
+
+// applyConfiguration returns the apply configuration of obj: the object with
+// its kind set and without status, the creation timestamp and the given
+// fields, which are owned by other controllers even though the Go type of obj
+// cannot leave them out.
+func (r *AgentControlPlaneReconciler) applyConfiguration(obj client.Object, unowned ...[]string) (*unstructured.Unstructured, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	config := &unstructured.Unstructured{Object: content}
+	config.SetGroupVersionKind(gvk)
+	unstructured.RemoveNestedField(config.Object, "status")
+	unstructured.RemoveNestedField(config.Object, "metadata", "creationTimestamp")
+	for _, path := range unowned {
+		unstructured.RemoveNestedField(config.Object, path...)
+	}
+	pruneNull(config.Object)
+	return config, nil
+}
+
+// pruneNull removes the null fields of object, which the Go types produce for
+// unset pointers: this controller does not set them.
+func pruneNull(object map[string]interface{}) {
+	for key, value := range object {
+		switch value := value.(type) {
+		case nil:
+			delete(object, key)
+		case map[string]interface{}:
+			pruneNull(value)
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					pruneNull(item)
+				}
+			}
+		}
+	}
+}
+
+// applyObject server-side applies desired and stores the resulting object
+// into obj, an object of the same type. The existing object is passed to
+// check first, if set; nothing is sent when it already has the fields of
+// desired.
+func (r *AgentControlPlaneReconciler) applyObject(ctx context.Context, desired, obj client.Object, check func(existing client.Object) error) error {
+	config, err := r.applyConfiguration(desired)
+	if err != nil {
+		return err
+	}
+
+	err = r.Get(ctx, client.ObjectKeyFromObject(desired), obj)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return err
+	default:
+		if check != nil {
+			if err := check(obj); err != nil {
+				return err
+			}
+		}
+		if applied, err := isApplied(obj, config); err != nil || applied {
+			return err
+		}
+	}
+
+	log.FromContext(ctx).Info("applying "+config.GetKind(), "object", client.ObjectKeyFromObject(desired))
+	return r.apply(ctx, config, obj)
+}
+
+// apply server-side applies config, forcing the ownership of its fields, and
+// stores the resulting object into obj. Fields set by other managers are left
+// alone.
+func (r *AgentControlPlaneReconciler) apply(ctx context.Context, config *unstructured.Unstructured, obj client.Object) error {
+	if err := r.Patch(ctx, config, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(config.Object, obj)
+}
+
+// isApplied reports whether applying config to current would change nothing.
+// current is converted the way applyConfiguration converts objects.
+func isApplied(current client.Object, config *unstructured.Unstructured) (bool, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(current)
+	if err != nil {
+		return false, err
+	}
+	return containsFields(content, config.Object), nil
+}
+
+// containsFields reports whether current holds every field set in applied
+// with the same value. Lists of objects with a uid, such as owner references,
+// are matched by uid the way the API server merges them; other lists must be
+// equal.
+func containsFields(current, applied interface{}) bool {
+	switch applied := applied.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		current, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range applied {
+			if !containsFields(current[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		current, ok := current.([]interface{})
+		if !ok {
+			return len(applied) == 0 && current == nil
+		}
+		if !listedByUID(applied) {
+			return reflect.DeepEqual(current, applied)
+		}
+		for _, item := range applied {
+			uid := item.(map[string]interface{})["uid"]
+			if !containsFields(findByUID(current, uid), item) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(current, applied)
+	}
+}
+
+func listedByUID(items []interface{}) bool {
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok || object["uid"] == nil {
+			return false
+		}
+	}
+	return len(items) > 0
+}
+
+func findByUID(items []interface{}, uid interface{}) interface{} {
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok && object["uid"] == uid {
+			return object
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// clusterDeploymentOwnerField indexes ClusterDeployments by the
+// "<namespace>/<name>" of the AgentControlPlane controlling them, so the
+// ClusterDeployments of a control plane can be listed from the cache without
+// scanning the namespace.
+const clusterDeploymentOwnerField = "metadata.controller.agentControlPlane"
+
+// controllingAgentControlPlane returns the AgentControlPlane set as the
+// controller of obj, if any.
+func controllingAgentControlPlane(obj metav1.Object) (types.NamespacedName, bool) {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil || ref.Kind != "AgentControlPlane" {
+		return types.NamespacedName{}, false
+	}
+	if gv, err := schema.ParseGroupVersion(ref.APIVersion); err != nil || gv.Group != controlplanev1.GroupVersion.Group {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: obj.GetNamespace(), Name: ref.Name}, true
+}
+
+// checkNotControlledByOther returns an error if obj is controlled by anything
+// but acp. Controller references are compared by UID, so the children of a
+// deleted AgentControlPlane are not taken over by a new one reusing its name,
+// nor are objects managed by another AgentControlPlane in the namespace.
+func checkNotControlledByOther(acp *controlplanev1.AgentControlPlane, obj metav1.Object) error {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil || ref.UID == acp.UID {
+		return nil
+	}
+	return fmt.Errorf("%s/%s is controlled by %s %s (uid %s), not by AgentControlPlane %s",
+		obj.GetNamespace(), obj.GetName(), ref.Kind, ref.Name, ref.UID, acp.Name)
+}
+
+// checkNotAnnotatedForOther returns an error if obj carries the
+// agentControlPlaneAnnotation of another AgentControlPlane. Objects outside
+// the AgentControlPlane's namespace have no controller reference to check.
+func checkNotAnnotatedForOther(acp *controlplanev1.AgentControlPlane, obj metav1.Object) error {
+	key, ok := acpKeyFromAnnotation(obj)
+	if !ok || key == client.ObjectKeyFromObject(acp) {
+		return nil
+	}
+	return fmt.Errorf("%s/%s belongs to AgentControlPlane %s", obj.GetNamespace(), obj.GetName(), key)
+}
+
+// indexClusterDeploymentByOwner is the indexer for clusterDeploymentOwnerField.
+func indexClusterDeploymentByOwner(obj client.Object) []string {
+	owner, ok := controllingAgentControlPlane(obj)
+	if !ok {
+		return nil
+	}
+	return []string{owner.String()}
+}
+
+// clusterDeploymentToAgentControlPlane maps a ClusterDeployment to the
+// AgentControlPlane controlling it. ClusterDeployments not created by this
+// provider map to nothing.
+func (r *AgentControlPlaneReconciler) clusterDeploymentToAgentControlPlane(_ context.Context, obj client.Object) []ctrl.Request {
+	owner, ok := controllingAgentControlPlane(obj)
+	if !ok {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: owner}}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+var _ = Describe("ClusterDeployment watch", func() {
+	ctx := context.Background()
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+	})
+
+	newClusterDeployment := func(owners ...metav1.OwnerReference) *hivev1.ClusterDeployment {
+		return &hivev1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test-cd",
+				Namespace:       testNamespace,
+				OwnerReferences: owners,
+			},
+		}
+	}
+
+	acpControllerRef := func() metav1.OwnerReference {
+		return metav1.OwnerReference{
+			APIVersion: controlplanev1.GroupVersion.String(),
+			Kind:       "AgentControlPlane",
+			Name:       acp.Name,
+			UID:        acp.UID,
+			Controller: ptr.To(true),
+		}
+	}
+
+	It("maps a ClusterDeployment to the AgentControlPlane controlling it", func() {
+		r := &AgentControlPlaneReconciler{}
+		Expect(r.clusterDeploymentToAgentControlPlane(ctx, newClusterDeployment(acpControllerRef()))).To(ConsistOf(
+			reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)},
+		))
+	})
+
+	It("maps ClusterDeployments not owned by this provider to nothing", func() {
+		r := &AgentControlPlaneReconciler{}
+		Expect(r.clusterDeploymentToAgentControlPlane(ctx, newClusterDeployment())).To(BeEmpty())
+
+		notController := acpControllerRef()
+		notController.Controller = nil
+		Expect(r.clusterDeploymentToAgentControlPlane(ctx, newClusterDeployment(notController))).To(BeEmpty())
+
+		otherGroup := acpControllerRef()
+		otherGroup.APIVersion = "example.com/v1"
+		Expect(r.clusterDeploymentToAgentControlPlane(ctx, newClusterDeployment(otherGroup))).To(BeEmpty())
+
+		otherKind := acpControllerRef()
+		otherKind.APIVersion = clusterv1.GroupVersion.String()
+		otherKind.Kind = "Cluster"
+		Expect(r.clusterDeploymentToAgentControlPlane(ctx, newClusterDeployment(otherKind))).To(BeEmpty())
+	})
+
+	It("finds the owned ClusterDeployment through the index and honors its installed flag", func() {
+		cluster := newCluster("test-cluster")
+		setOwnerCluster(acp, cluster)
+		clusterDeployment := newClusterDeployment(acpControllerRef())
+		clusterDeployment.Spec.Installed = true
+		c := newFakeClient(newTestScheme(), acp, cluster, clusterDeployment)
+
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		// The differently named ClusterDeployment is used instead of creating
+		// one named after the AgentControlPlane.
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &hivev1.ClusterDeployment{})).NotTo(Succeed())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(conditions.IsTrue(updated, controlplanev1.InstallCompleteCondition)).To(BeTrue())
+	})
+})
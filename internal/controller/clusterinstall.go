@@ -0,0 +1,617 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+// releaseImageFormat builds the default release image pull spec for an
+// OpenShift version.
+const releaseImageFormat = "quay.io/openshift-release-dev/ocp-release:%s-x86_64"
+
+// installConfigOverridesAnnotation carries a JSON document assisted-service
+// merges into the install config of an AgentClusterInstall.
+const installConfigOverridesAnnotation = "agent-install.openshift.io/install-config-overrides"
+
+// clusterImageSetName returns the name of the ClusterImageSet created for acp.
+// ClusterImageSets are cluster scoped, so the name includes the namespace.
+func clusterImageSetName(acp *controlplanev1.AgentControlPlane) string {
+	return acp.Namespace + "-" + acp.Name
+}
+
+// reconcileClusterInstall ensures the ClusterImageSet, ClusterDeployment and
+// AgentClusterInstall driving the assisted install of the control plane
+// exist and follow the spec of acp, the AgentClusterInstall until its install
+// started, and mirrors the progress of the install onto acp. The ClusterDeployment
+// and AgentClusterInstall share the AgentControlPlane's name and namespace.
+// The returned result requeues for when a tolerated install failure is due to
+// be recorded. Failed installs are retried up to spec.maxInstallRetries times
+// before the failure is recorded.
+func (r *AgentControlPlaneReconciler) reconcileClusterInstall(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) (ctrl.Result, error) {
+	if err := r.reconcileClusterImageSet(ctx, acp); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	clusterDeployment := &hivev1.ClusterDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: acp.Name, Namespace: acp.Namespace},
+		Spec: hivev1.ClusterDeploymentSpec{
+			ClusterName:   cluster.Name,
+			BaseDomain:    acp.Spec.BaseDomain,
+			PullSecretRef: acp.Spec.PullSecretRef,
+			Platform: hivev1.Platform{
+				AgentBareMetal: &hivev1.AgentBareMetalPlatform{
+					AgentSelector: metav1.LabelSelector{MatchLabels: agentSelector(acp)},
+				},
+			},
+			ClusterInstallRef: &hivev1.ClusterInstallLocalReference{
+				Group:   hiveext.GroupVersion.Group,
+				Version: hiveext.GroupVersion.Version,
+				Kind:    agentClusterInstallGVK.Kind,
+				Name:    acp.Name,
+			},
+		},
+	}
+	existingClusterDeployment, err := r.applyClusterDeployment(ctx, acp, clusterDeployment)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	agentClusterInstall := &hiveext.AgentClusterInstall{
+		ObjectMeta: metav1.ObjectMeta{Name: acp.Name, Namespace: acp.Namespace},
+		Spec: hiveext.AgentClusterInstallSpec{
+			ClusterDeploymentRef: corev1.LocalObjectReference{Name: existingClusterDeployment.Name},
+			Networking:           agentClusterInstallNetworking(acp),
+			PlatformType:         agentClusterInstallPlatform(acp),
+			APIVIPs:              acp.Spec.APIVIPs,
+			IngressVIPs:          acp.Spec.IngressVIPs,
+			SSHPublicKey:         acp.Spec.NodeSSHAuthorizedKey,
+			ProvisionRequirements: hiveext.ProvisionRequirements{
+				ControlPlaneAgents: int(desiredReplicas(acp)),
+			},
+		},
+	}
+	// VIPs are rejected by the webhook on platform none; without it, they
+	// would fail the install instead.
+	if acp.Spec.Platform == controlplanev1.PlatformNone {
+		agentClusterInstall.Spec.APIVIPs = nil
+		agentClusterInstall.Spec.IngressVIPs = nil
+	}
+	for _, ref := range acp.Spec.ManifestsConfigMapRefs {
+		agentClusterInstall.Spec.ManifestsConfigMapRefs = append(agentClusterInstall.Spec.ManifestsConfigMapRefs,
+			hiveext.ManifestsConfigMapReference{Name: ref.Name})
+	}
+	if err := r.reconcileManifestsConfigMaps(ctx, acp); err != nil {
+		return ctrl.Result{}, err
+	}
+	additionalCAs, err := r.reconcileAdditionalCAs(ctx, acp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if additionalCAs != nil {
+		agentClusterInstall.Spec.ManifestsConfigMapRefs = append(agentClusterInstall.Spec.ManifestsConfigMapRefs, *additionalCAs)
+	}
+	inlineManifests, err := r.reconcileInlineManifests(ctx, acp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if inlineManifests != nil {
+		agentClusterInstall.Spec.ManifestsConfigMapRefs = append(agentClusterInstall.Spec.ManifestsConfigMapRefs, *inlineManifests)
+	}
+	diskEncryption, err := agentClusterInstallDiskEncryption(acp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	agentClusterInstall.Spec.DiskEncryption = diskEncryption
+	if acp.Spec.FIPS {
+		agentClusterInstall.Annotations = map[string]string{installConfigOverridesAnnotation: `{"fips":true}`}
+	}
+	agentClusterInstall.Spec.ImageSetRef = imageSetRef(acp)
+	existing, err := r.applyAgentClusterInstall(ctx, acp, agentClusterInstall)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !existing.GetDeletionTimestamp().IsZero() {
+		conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallRetryingReason,
+			clusterv1.ConditionSeverityWarning, "Waiting for AgentClusterInstall %s to be deleted before retrying the install", existing.GetName())
+		return ctrl.Result{RequeueAfter: installRetryRequeueInterval}, nil
+	}
+
+	timedOut := acp.Status.FailureReason == controlplanev1.InstallTimeoutReason
+	if timedOut && acp.Status.FailedGeneration != acp.Generation {
+		return r.restartTimedOutInstall(ctx, acp, existing)
+	}
+	result := updateInstallStatus(acp, existingClusterDeployment, existing, r.clock())
+	if !timedOut && acp.Status.FailureReason == controlplanev1.InstallTimeoutReason {
+		r.eventf(acp, corev1.EventTypeWarning, controlplanev1.InstallTimeoutReason, "%s", *acp.Status.FailureMessage)
+	}
+	if acp.Status.FailureReason == controlplanev1.InstallFailedReason &&
+		acp.Status.InstallRetries < ptr.Deref(acp.Spec.MaxInstallRetries, 0) {
+		return r.retryInstall(ctx, acp, existing)
+	}
+	return result, nil
+}
+
+// retryInstall deletes the AgentClusterInstall of the failed install of acp,
+// for the next reconcile to recreate it, and clears the failure. The attempt
+// is counted in status.installRetries.
+func (r *AgentControlPlaneReconciler) retryInstall(ctx context.Context, acp *controlplanev1.AgentControlPlane, agentClusterInstall client.Object) (ctrl.Result, error) {
+	message := ptr.Deref(acp.Status.FailureMessage, "")
+	if err := r.restartInstall(ctx, acp, agentClusterInstall); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	acp.Status.InstallRetries++
+	log.FromContext(ctx).Info("retrying the failed install",
+		"retry", acp.Status.InstallRetries, "maxRetries", *acp.Spec.MaxInstallRetries)
+	conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallRetryingReason,
+		clusterv1.ConditionSeverityWarning, "Retrying the install (%d of %d) after it failed: %s",
+		acp.Status.InstallRetries, *acp.Spec.MaxInstallRetries, message)
+	r.eventf(acp, corev1.EventTypeWarning, controlplanev1.InstallRetryingReason,
+		"Retrying the install (%d of %d) after it failed: %s", acp.Status.InstallRetries, *acp.Spec.MaxInstallRetries, message)
+	return ctrl.Result{RequeueAfter: installRetryRequeueInterval}, nil
+}
+
+// restartTimedOutInstall deletes the AgentClusterInstall of the timed out
+// install of acp once its spec changed, for the next reconcile to recreate it
+// from the new spec, and clears the failure. It is not counted as a retry.
+func (r *AgentControlPlaneReconciler) restartTimedOutInstall(ctx context.Context, acp *controlplanev1.AgentControlPlane, agentClusterInstall client.Object) (ctrl.Result, error) {
+	if err := r.restartInstall(ctx, acp, agentClusterInstall); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.FromContext(ctx).Info("restarting the timed out install after the spec changed")
+	conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallRetryingReason,
+		clusterv1.ConditionSeverityWarning, "Restarting the install after the spec changed")
+	r.eventf(acp, corev1.EventTypeNormal, controlplanev1.InstallRetryingReason, "Restarting the timed out install after the spec changed")
+	return ctrl.Result{RequeueAfter: installRetryRequeueInterval}, nil
+}
+
+// restartInstall deletes agentClusterInstall, the AgentClusterInstall of the
+// failed install of acp, for the next reconcile to recreate it, and clears the
+// failure.
+func (r *AgentControlPlaneReconciler) restartInstall(ctx context.Context, acp *controlplanev1.AgentControlPlane, agentClusterInstall client.Object) error {
+	uid := agentClusterInstall.GetUID()
+	if err := r.Delete(ctx, agentClusterInstall, client.Preconditions{UID: &uid}); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting the failed AgentClusterInstall %s: %w", agentClusterInstall.GetName(), err)
+	}
+
+	acp.Status.FailureReason = ""
+	acp.Status.FailureMessage = nil
+	acp.Status.FailingSince = nil
+	acp.Status.FailedGeneration = 0
+	return nil
+}
+
+// ownedClusterDeployment returns the ClusterDeployment controlled by acp, or
+// nil if there is none yet.
+func (r *AgentControlPlaneReconciler) ownedClusterDeployment(ctx context.Context, acp *controlplanev1.AgentControlPlane) (*hivev1.ClusterDeployment, error) {
+	clusterDeployments := &hivev1.ClusterDeploymentList{}
+	if err := r.List(ctx, clusterDeployments, client.InNamespace(acp.Namespace),
+		client.MatchingFields{clusterDeploymentOwnerField: client.ObjectKeyFromObject(acp).String()}); err != nil {
+		return nil, err
+	}
+	// The index is keyed by name, which a previous AgentControlPlane of the
+	// same name shared.
+	for i := range clusterDeployments.Items {
+		if metav1.IsControlledBy(&clusterDeployments.Items[i], acp) {
+			return &clusterDeployments.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// applyClusterDeployment server-side applies desired, controlled by acp, and
+// returns the resulting ClusterDeployment. Nothing is sent when the fields set
+// by this controller are up to date. spec.installed is left to hive.
+func (r *AgentControlPlaneReconciler) applyClusterDeployment(ctx context.Context, acp *controlplanev1.AgentControlPlane, desired *hivev1.ClusterDeployment) (*hivev1.ClusterDeployment, error) {
+	existing, err := r.ownedClusterDeployment(ctx, acp)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		existing = &hivev1.ClusterDeployment{}
+		err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+		switch {
+		case apierrors.IsNotFound(err):
+			existing = nil
+		case err != nil:
+			return nil, err
+		default:
+			if err := checkNotControlledByOther(acp, existing); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		desired.Name = existing.Name
+	}
+
+	setACPAnnotation(desired, acp)
+	if err := controllerutil.SetControllerReference(acp, desired, r.Scheme); err != nil {
+		return nil, err
+	}
+	config, err := r.applyConfiguration(desired, []string{"spec", "installed"})
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if applied, err := isApplied(existing, config); err != nil || applied {
+			return existing, err
+		}
+	}
+
+	log.FromContext(ctx).Info("applying ClusterDeployment", "object", client.ObjectKeyFromObject(desired))
+	applied := &hivev1.ClusterDeployment{}
+	return applied, r.apply(ctx, config, applied)
+}
+
+// agentClusterInstallNetworking translates the network CIDRs of acp into the
+// AgentClusterInstall networking configuration.
+func agentClusterInstallNetworking(acp *controlplanev1.AgentControlPlane) hiveext.Networking {
+	networking := hiveext.Networking{ServiceNetwork: acp.Spec.ServiceNetwork}
+	for _, block := range acp.Spec.ClusterNetwork {
+		networking.ClusterNetwork = append(networking.ClusterNetwork, hiveext.ClusterNetworkEntry{
+			CIDR:       block.CIDR,
+			HostPrefix: block.HostPrefix,
+		})
+	}
+	for _, cidr := range acp.Spec.MachineNetwork {
+		networking.MachineNetwork = append(networking.MachineNetwork, hiveext.MachineNetworkEntry{CIDR: cidr})
+	}
+	return networking
+}
+
+// reconcileManifestsConfigMaps records on the ManifestsAvailableCondition
+// whether the manifests ConfigMaps referenced by acp exist.
+func (r *AgentControlPlaneReconciler) reconcileManifestsConfigMaps(ctx context.Context, acp *controlplanev1.AgentControlPlane) error {
+	if len(acp.Spec.ManifestsConfigMapRefs) == 0 {
+		conditions.Delete(acp, controlplanev1.ManifestsAvailableCondition)
+		return nil
+	}
+
+	var missing []string
+	for _, ref := range acp.Spec.ManifestsConfigMapRefs {
+		err := r.Get(ctx, client.ObjectKey{Namespace: acp.Namespace, Name: ref.Name}, &corev1.ConfigMap{})
+		if apierrors.IsNotFound(err) {
+			missing = append(missing, ref.Name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		conditions.MarkFalse(acp, controlplanev1.ManifestsAvailableCondition, controlplanev1.ManifestsConfigMapMissingReason,
+			clusterv1.ConditionSeverityWarning, "Manifests ConfigMaps not found: %s", strings.Join(missing, ", "))
+		return nil
+	}
+	conditions.MarkTrue(acp, controlplanev1.ManifestsAvailableCondition)
+	return nil
+}
+
+// inlineManifestsConfigMapName returns the name of the manifests ConfigMap
+// generated from the inline manifests of acp.
+func inlineManifestsConfigMapName(acp *controlplanev1.AgentControlPlane) string {
+	return acp.Name + "-inline-manifests"
+}
+
+// reconcileInlineManifests writes spec.inlineManifests, decoded, as a
+// manifests ConfigMap controlled by acp, keyed by their names. It returns the
+// reference to add to the AgentClusterInstall, or nil when acp has no inline
+// manifests.
+func (r *AgentControlPlaneReconciler) reconcileInlineManifests(ctx context.Context, acp *controlplanev1.AgentControlPlane) (*hiveext.ManifestsConfigMapReference, error) {
+	if len(acp.Spec.InlineManifests) == 0 {
+		return nil, nil
+	}
+
+	data := make(map[string]string, len(acp.Spec.InlineManifests))
+	for _, manifest := range acp.Spec.InlineManifests {
+		// The webhook rejects duplicates; without it, the first one wins.
+		if _, ok := data[manifest.Name]; ok {
+			continue
+		}
+		content, err := manifest.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("decoding inline manifest %s: %w", manifest.Name, err)
+		}
+		data[manifest.Name] = content
+	}
+	desired := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      inlineManifestsConfigMapName(acp),
+			Namespace: acp.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         controlplanev1.GroupVersion.String(),
+				Kind:               "AgentControlPlane",
+				Name:               acp.Name,
+				UID:                acp.UID,
+				Controller:         ptr.To(true),
+				BlockOwnerDeletion: ptr.To(true),
+			}},
+		},
+		Data: data,
+	}
+	if err := r.applyObject(ctx, desired, &corev1.ConfigMap{}, nil); err != nil {
+		return nil, fmt.Errorf("writing the inline manifests: %w", err)
+	}
+	return &hiveext.ManifestsConfigMapReference{Name: desired.Name}, nil
+}
+
+// agentClusterInstallPlatform returns the AgentClusterInstall platform type
+// of acp, defaulting to bare metal.
+func agentClusterInstallPlatform(acp *controlplanev1.AgentControlPlane) hiveext.PlatformType {
+	switch acp.Spec.Platform {
+	case controlplanev1.PlatformNone:
+		return hiveext.NonePlatformType
+	case controlplanev1.PlatformVSphere:
+		return hiveext.VSpherePlatformType
+	default:
+		return hiveext.BareMetalPlatformType
+	}
+}
+
+// agentClusterInstallDiskEncryption translates the disk encryption settings
+// of acp into the AgentClusterInstall ones, which carry the Tang servers as a
+// JSON document.
+func agentClusterInstallDiskEncryption(acp *controlplanev1.AgentControlPlane) (*hiveext.DiskEncryption, error) {
+	spec := acp.Spec.DiskEncryption
+	if spec == nil {
+		return nil, nil
+	}
+
+	diskEncryption := &hiveext.DiskEncryption{}
+	if spec.EnableOn != "" {
+		diskEncryption.EnableOn = ptr.To(spec.EnableOn)
+	}
+	if spec.Mode != "" {
+		diskEncryption.Mode = ptr.To(spec.Mode)
+	}
+	if len(spec.TangServers) > 0 {
+		tangServers, err := json.Marshal(spec.TangServers)
+		if err != nil {
+			return nil, fmt.Errorf("encoding tang servers: %w", err)
+		}
+		diskEncryption.TangServers = string(tangServers)
+	}
+	return diskEncryption, nil
+}
+
+// releaseImage returns the release image acp installs: spec.releaseImage, or
+// else the default release image of spec.version. It is empty if neither is
+// set.
+func releaseImage(acp *controlplanev1.AgentControlPlane) string {
+	switch {
+	case acp.Spec.ReleaseImage != "":
+		return acp.Spec.ReleaseImage
+	case acp.Spec.Version != "":
+		return fmt.Sprintf(releaseImageFormat, acp.Spec.Version)
+	default:
+		return ""
+	}
+}
+
+// imageSetRef returns the reference to the ClusterImageSet the release of
+// acp is installed from: spec.clusterImageSetRef, which takes precedence, or
+// the one created for its release image. It returns nil when neither is set.
+func imageSetRef(acp *controlplanev1.AgentControlPlane) *hivev1.ClusterImageSetReference {
+	switch {
+	case acp.Spec.ClusterImageSetRef != nil:
+		return &hivev1.ClusterImageSetReference{Name: acp.Spec.ClusterImageSetRef.Name}
+	case releaseImage(acp) != "":
+		return &hivev1.ClusterImageSetReference{Name: clusterImageSetName(acp)}
+	default:
+		return nil
+	}
+}
+
+// reconcileClusterImageSet ensures the ClusterImageSet referenced by the
+// AgentClusterInstall points at the release image of acp. A ClusterImageSet
+// referenced by spec.clusterImageSetRef is left alone, and so is one of the
+// same name that was not created for acp: cluster-scoped, it may have been
+// created by hand or for an AgentControlPlane whose namespace and name join
+// into the same name.
+func (r *AgentControlPlaneReconciler) reconcileClusterImageSet(ctx context.Context, acp *controlplanev1.AgentControlPlane) error {
+	releaseImage := releaseImage(acp)
+	if releaseImage == "" || acp.Spec.ClusterImageSetRef != nil {
+		return nil
+	}
+
+	imageSet := &hivev1.ClusterImageSet{}
+	err := r.Get(ctx, client.ObjectKey{Name: clusterImageSetName(acp)}, imageSet)
+	if apierrors.IsNotFound(err) {
+		imageSet = &hivev1.ClusterImageSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: clusterImageSetName(acp),
+			},
+			Spec: hivev1.ClusterImageSetSpec{ReleaseImage: releaseImage},
+		}
+		setACPAnnotation(imageSet, acp)
+		log.FromContext(ctx).Info("creating ClusterImageSet", "clusterImageSet", imageSet.Name, "releaseImage", releaseImage)
+		return r.Create(ctx, imageSet)
+	}
+	if err != nil {
+		return err
+	}
+	if key, ok := acpKeyFromAnnotation(imageSet); !ok || key != client.ObjectKeyFromObject(acp) {
+		return fmt.Errorf("ClusterImageSet %s was not created for AgentControlPlane %s", imageSet.Name, client.ObjectKeyFromObject(acp))
+	}
+	if imageSet.Spec.ReleaseImage == releaseImage {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("updating ClusterImageSet release image", "clusterImageSet", imageSet.Name, "releaseImage", releaseImage)
+	patch := client.MergeFrom(imageSet.DeepCopy())
+	imageSet.Spec.ReleaseImage = releaseImage
+	return r.Patch(ctx, imageSet, patch)
+}
+
+// applyAgentClusterInstall server-side applies desired, controlled by acp,
+// and returns the resulting AgentClusterInstall. Once assisted-service started
+// the install, or while the AgentClusterInstall is being deleted, it no longer
+// acts on spec changes, so the existing AgentClusterInstall is returned
+// unchanged. Nothing is sent when the fields set by this controller are up to
+// date.
+func (r *AgentControlPlaneReconciler) applyAgentClusterInstall(ctx context.Context, acp *controlplanev1.AgentControlPlane, desired *hiveext.AgentClusterInstall) (*hiveext.AgentClusterInstall, error) {
+	existing := &hiveext.AgentClusterInstall{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		existing = nil
+	case err != nil:
+		return nil, err
+	default:
+		if err := checkNotControlledByOther(acp, existing); err != nil {
+			return nil, err
+		}
+		if !existing.DeletionTimestamp.IsZero() || installStarted(existing) {
+			return existing, nil
+		}
+	}
+
+	setACPAnnotation(desired, acp)
+	if err := controllerutil.SetControllerReference(acp, desired, r.Scheme); err != nil {
+		return nil, err
+	}
+	config, err := r.applyConfiguration(desired)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if applied, err := isApplied(existing, config); err != nil || applied {
+			return existing, err
+		}
+	}
+
+	log.FromContext(ctx).Info("applying AgentClusterInstall", "object", client.ObjectKeyFromObject(desired))
+	applied := &hiveext.AgentClusterInstall{}
+	return applied, r.apply(ctx, config, applied)
+}
+
+// installStarted reports whether assisted-service started the install of
+// agentClusterInstall: it completed, failed, or is in progress.
+func installStarted(agentClusterInstall *hiveext.AgentClusterInstall) bool {
+	completed := findClusterInstallCondition(agentClusterInstall.Status.Conditions, hiveext.ClusterCompletedCondition)
+	failed := findClusterInstallCondition(agentClusterInstall.Status.Conditions, hiveext.ClusterFailedCondition)
+	return failed != nil && failed.Status == corev1.ConditionTrue ||
+		completed != nil && (completed.Status == corev1.ConditionTrue || completed.Reason == hiveext.ClusterInstallationInProgressReason)
+}
+
+// updateInstallStatus mirrors the AgentClusterInstall Completed and Failed
+// conditions onto the InstallCompleteCondition, and marks the control plane
+// initialized once the install completed. A ClusterDeployment hive
+// already marked installed counts as completed even if the
+// AgentClusterInstall conditions lag behind. A failure is only recorded as
+// terminal once it has lasted spec.failureGracePeriod; until then the
+// returned result requeues for when the period elapses. A timeout is recorded
+// right away, as waiting does not help, and kept until the spec changes, which
+// makes reconcileClusterInstall restart the install. Any other recorded
+// failure is cleared once the AgentClusterInstall no longer reports it.
+func updateInstallStatus(acp *controlplanev1.AgentControlPlane, clusterDeployment *hivev1.ClusterDeployment, agentClusterInstall *hiveext.AgentClusterInstall, now time.Time) ctrl.Result {
+	completed := findClusterInstallCondition(agentClusterInstall.Status.Conditions, hiveext.ClusterCompletedCondition)
+	failed := findClusterInstallCondition(agentClusterInstall.Status.Conditions, hiveext.ClusterFailedCondition)
+
+	timedOut := acp.Status.FailureReason == controlplanev1.InstallTimeoutReason
+	isFailed := failed != nil && failed.Status == corev1.ConditionTrue
+	switch {
+	case !isFailed:
+		acp.Status.FailingSince = nil
+		if !timedOut {
+			acp.Status.FailureReason = ""
+			acp.Status.FailureMessage = nil
+		}
+	case acp.Status.FailingSince == nil:
+		acp.Status.FailingSince = ptr.To(metav1.NewTime(now))
+	}
+
+	switch {
+	case clusterDeployment.Spec.Installed, completed != nil && completed.Status == corev1.ConditionTrue:
+		conditions.MarkTrue(acp, controlplanev1.InstallCompleteCondition)
+		acp.Status.Initialized = true
+		if !timedOut {
+			acp.Status.FailureReason = ""
+			acp.Status.FailureMessage = nil
+		}
+	case isFailed && isInstallTimeout(failed):
+		conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallTimeoutReason,
+			clusterv1.ConditionSeverityError, "%s", failed.Message)
+		acp.Status.FailureReason = controlplanev1.InstallTimeoutReason
+		acp.Status.FailureMessage = ptr.To(fmt.Sprintf(
+			"%s. The install is not retried: fix the cause, then change the AgentControlPlane spec to restart it.",
+			strings.TrimSuffix(failed.Message, ".")))
+		acp.Status.FailedGeneration = acp.Generation
+	case timedOut:
+		// The failure is kept even if the AgentClusterInstall no longer
+		// reports it.
+	case isFailed:
+		var gracePeriod time.Duration
+		if acp.Spec.FailureGracePeriod != nil {
+			gracePeriod = acp.Spec.FailureGracePeriod.Duration
+		}
+		if wait := acp.Status.FailingSince.Add(gracePeriod).Sub(now); wait > 0 {
+			conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallFailedReason,
+				clusterv1.ConditionSeverityWarning, "%s", failed.Message)
+			return ctrl.Result{RequeueAfter: wait}
+		}
+		conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallFailedReason,
+			clusterv1.ConditionSeverityError, "%s", failed.Message)
+		acp.Status.FailureReason = controlplanev1.InstallFailedReason
+		acp.Status.FailureMessage = ptr.To(failed.Message)
+	case completed != nil:
+		conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallInProgressReason,
+			clusterv1.ConditionSeverityInfo, "%s", completed.Message)
+	default:
+		conditions.MarkFalse(acp, controlplanev1.InstallCompleteCondition, controlplanev1.InstallPendingReason,
+			clusterv1.ConditionSeverityInfo, "Waiting for the install to start")
+	}
+	return ctrl.Result{}
+}
+
+// isInstallTimeout returns whether the Failed condition of an
+// AgentClusterInstall reports a timeout. assisted-service sets the same
+// reason for every failure, so timeouts are told apart by their message.
+func isInstallTimeout(failed *hivev1.ClusterInstallCondition) bool {
+	message := strings.ToLower(failed.Message)
+	return strings.Contains(message, "timeout") || strings.Contains(message, "timed out")
+}
+
+func findClusterInstallCondition(conds []hivev1.ClusterInstallCondition, conditionType hivev1.ClusterInstallConditionType) *hivev1.ClusterInstallCondition {
+	for i := range conds {
+		if conds[i].Type == conditionType {
+			return &conds[i]
+		}
+	}
+	return nil
+}
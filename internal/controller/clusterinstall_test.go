@@ -0,0 +1,667 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+var _ = Describe("Cluster install reconciliation", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.Replicas = ptr.To[int32](3)
+		acp.Spec.Version = "4.15.0"
+		acp.Spec.BaseDomain = "example.com"
+		acp.Spec.ClusterNetwork = []controlplanev1.CIDRBlock{{CIDR: "10.128.0.0/14", HostPrefix: 23}}
+		acp.Spec.ServiceNetwork = []string{"172.30.0.0/16"}
+		acp.Spec.MachineNetwork = []string{"192.168.111.0/24"}
+		acp.Spec.APIVIPs = []string{"192.168.111.5"}
+		acp.Spec.IngressVIPs = []string{"192.168.111.4"}
+		setOwnerCluster(acp, cluster)
+	})
+
+	reconcileACP := func(c client.Client) *controlplanev1.AgentControlPlane {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return updated
+	}
+
+	// agentClusterInstallWithConditions returns the AgentClusterInstall the
+	// controller would create, already reporting conds.
+	agentClusterInstallWithConditions := func(conds ...hivev1.ClusterInstallCondition) *hiveext.AgentClusterInstall {
+		return &hiveext.AgentClusterInstall{
+			ObjectMeta: acp.ObjectMeta,
+			Spec: hiveext.AgentClusterInstallSpec{
+				ClusterDeploymentRef: corev1.LocalObjectReference{Name: acp.Name},
+			},
+			Status: hiveext.AgentClusterInstallStatus{Conditions: conds},
+		}
+	}
+
+	getAgentClusterInstall := func(c client.Client) *hiveext.AgentClusterInstall {
+		agentClusterInstall := &hiveext.AgentClusterInstall{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), agentClusterInstall)).To(Succeed())
+		return agentClusterInstall
+	}
+
+	It("creates the ClusterImageSet, ClusterDeployment and AgentClusterInstall", func() {
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		imageSet := &hivev1.ClusterImageSet{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: testNamespace + "-test-acp"}, imageSet)).To(Succeed())
+		Expect(imageSet.Spec.ReleaseImage).To(Equal("quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64"))
+
+		clusterDeployment := &hivev1.ClusterDeployment{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), clusterDeployment)).To(Succeed())
+		Expect(clusterDeployment.Spec.ClusterName).To(Equal(cluster.Name))
+		Expect(clusterDeployment.Spec.BaseDomain).To(Equal("example.com"))
+		Expect(clusterDeployment.Spec.PullSecretRef).To(Equal(acp.Spec.PullSecretRef))
+		Expect(clusterDeployment.Spec.Platform.AgentBareMetal.AgentSelector.MatchLabels).To(
+			HaveKeyWithValue(aiv1beta1.InfraEnvNameLabel, acp.Name))
+		Expect(clusterDeployment.Spec.ClusterInstallRef.Name).To(Equal(acp.Name))
+		Expect(clusterDeployment.OwnerReferences[0].UID).To(Equal(acp.UID))
+
+		agentClusterInstall := &hiveext.AgentClusterInstall{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), agentClusterInstall)).To(Succeed())
+		Expect(agentClusterInstall.Spec.ClusterDeploymentRef.Name).To(Equal(clusterDeployment.Name))
+		Expect(agentClusterInstall.Spec.ImageSetRef.Name).To(Equal(imageSet.Name))
+		Expect(agentClusterInstall.Spec.ProvisionRequirements.ControlPlaneAgents).To(Equal(3))
+		Expect(agentClusterInstall.Spec.Networking).To(Equal(hiveext.Networking{
+			ClusterNetwork: []hiveext.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}},
+			ServiceNetwork: []string{"172.30.0.0/16"},
+			MachineNetwork: []hiveext.MachineNetworkEntry{{CIDR: "192.168.111.0/24"}},
+		}))
+		Expect(agentClusterInstall.Spec.PlatformType).To(Equal(hiveext.BareMetalPlatformType))
+		Expect(agentClusterInstall.Spec.APIVIPs).To(Equal([]string{"192.168.111.5"}))
+		Expect(agentClusterInstall.Spec.IngressVIPs).To(Equal([]string{"192.168.111.4"}))
+		Expect(agentClusterInstall.OwnerReferences[0].UID).To(Equal(acp.UID))
+	})
+
+	It("updates the AgentClusterInstall after the spec changes, until the install started", func() {
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		updateSpec := func(update func(*controlplanev1.AgentControlPlane)) {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), acp)).To(Succeed())
+			update(acp)
+			Expect(c.Update(ctx, acp)).To(Succeed())
+			reconcileACP(c)
+		}
+		updateSpec(func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.Replicas = ptr.To[int32](1)
+		})
+		agentClusterInstall := &hiveext.AgentClusterInstall{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), agentClusterInstall)).To(Succeed())
+		Expect(agentClusterInstall.Spec.ProvisionRequirements.ControlPlaneAgents).To(Equal(1))
+
+		agentClusterInstall.Status.Conditions = []hivev1.ClusterInstallCondition{{
+			Type:   hiveext.ClusterCompletedCondition,
+			Status: corev1.ConditionFalse,
+			Reason: hiveext.ClusterInstallationInProgressReason,
+		}}
+		Expect(c.Update(ctx, agentClusterInstall)).To(Succeed())
+		updateSpec(func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.Replicas = ptr.To[int32](3)
+		})
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), agentClusterInstall)).To(Succeed())
+		Expect(agentClusterInstall.Spec.ProvisionRequirements.ControlPlaneAgents).To(Equal(1))
+	})
+
+	DescribeTable("applies a spec field changed after the AgentClusterInstall was created",
+		func(update func(*controlplanev1.AgentControlPlane), check func(client.Client)) {
+			c := newFakeClient(newTestScheme(), acp, cluster)
+			reconcileACP(c)
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), acp)).To(Succeed())
+			update(acp)
+			Expect(c.Update(ctx, acp)).To(Succeed())
+			reconcileACP(c)
+			check(c)
+		},
+		Entry("networks", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.MachineNetwork = []string{"192.168.112.0/24"}
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Spec.Networking.MachineNetwork).To(Equal(
+				[]hiveext.MachineNetworkEntry{{CIDR: "192.168.112.0/24"}}))
+		}),
+		Entry("VIPs", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.APIVIPs = []string{"192.168.111.6"}
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Spec.APIVIPs).To(Equal([]string{"192.168.111.6"}))
+		}),
+		Entry("manifests ConfigMaps", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.ManifestsConfigMapRefs = []corev1.LocalObjectReference{{Name: "extra-manifests"}}
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Spec.ManifestsConfigMapRefs).To(Equal(
+				[]hiveext.ManifestsConfigMapReference{{Name: "extra-manifests"}}))
+		}),
+		Entry("release image", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.ReleaseImage = "mirror.example.com:5000/ocp/release@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		}, func(c client.Client) {
+			imageSet := &hivev1.ClusterImageSet{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: clusterImageSetName(acp)}, imageSet)).To(Succeed())
+			Expect(imageSet.Spec.ReleaseImage).To(Equal(acp.Spec.ReleaseImage))
+			Expect(getAgentClusterInstall(c).Spec.ImageSetRef).To(HaveValue(HaveField("Name", imageSet.Name)))
+		}),
+		Entry("additional CAs", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.AdditionalCAs = []string{string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("ca")}))}
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Spec.ManifestsConfigMapRefs).To(Equal(
+				[]hiveext.ManifestsConfigMapReference{{Name: "test-acp-additional-cas"}}))
+		}),
+		Entry("ClusterImageSet reference", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.ClusterImageSetRef = &corev1.LocalObjectReference{Name: "openshift-v4.16.0"}
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Spec.ImageSetRef).To(HaveValue(HaveField("Name", "openshift-v4.16.0")))
+		}),
+		Entry("FIPS", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.FIPS = true
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Annotations).To(HaveKeyWithValue(installConfigOverridesAnnotation, `{"fips":true}`))
+		}),
+		Entry("inline manifests", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.InlineManifests = []controlplanev1.Manifest{{Name: "50-chrony.yaml", Content: "kind: ConfigMap\n"}}
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Spec.ManifestsConfigMapRefs).To(Equal(
+				[]hiveext.ManifestsConfigMapReference{{Name: "test-acp-inline-manifests"}}))
+		}),
+		Entry("node SSH key", func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.NodeSSHAuthorizedKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINodes nodes"
+		}, func(c client.Client) {
+			Expect(getAgentClusterInstall(c).Spec.SSHPublicKey).To(Equal("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINodes nodes"))
+		}),
+	)
+
+	It("installs without VIPs on platform none", func() {
+		acp.Spec.Platform = controlplanev1.PlatformNone
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		agentClusterInstall := &hiveext.AgentClusterInstall{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), agentClusterInstall)).To(Succeed())
+		Expect(agentClusterInstall.Spec.PlatformType).To(Equal(hiveext.NonePlatformType))
+		Expect(agentClusterInstall.Spec.APIVIPs).To(BeEmpty())
+		Expect(agentClusterInstall.Spec.IngressVIPs).To(BeEmpty())
+	})
+
+	It("installs the pinned release image", func() {
+		acp.Spec.ReleaseImage = "mirror.example.com:5000/ocp/release@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		imageSet := &hivev1.ClusterImageSet{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: clusterImageSetName(acp)}, imageSet)).To(Succeed())
+		Expect(imageSet.Spec.ReleaseImage).To(Equal(acp.Spec.ReleaseImage))
+		Expect(getAgentClusterInstall(c).Spec.ImageSetRef).To(HaveValue(HaveField("Name", imageSet.Name)))
+	})
+
+	It("does not take over a ClusterImageSet of the same name it did not create", func() {
+		imageSet := &hivev1.ClusterImageSet{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterImageSetName(acp)},
+			Spec:       hivev1.ClusterImageSetSpec{ReleaseImage: "quay.io/example/release:4.14.0-x86_64"},
+		}
+		c := newFakeClient(newTestScheme(), acp, cluster, imageSet)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).To(MatchError(ContainSubstring("was not created for AgentControlPlane")))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(imageSet), imageSet)).To(Succeed())
+		Expect(imageSet.Spec.ReleaseImage).To(Equal("quay.io/example/release:4.14.0-x86_64"))
+		err = c.Get(ctx, client.ObjectKeyFromObject(acp), &hiveext.AgentClusterInstall{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("installs the release of a referenced ClusterImageSet", func() {
+		acp.Spec.Version = "4.15.0"
+		acp.Spec.ClusterImageSetRef = &corev1.LocalObjectReference{Name: "openshift-v4.16.0"}
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		Expect(getAgentClusterInstall(c).Spec.ImageSetRef).To(HaveValue(HaveField("Name", "openshift-v4.16.0")))
+		err := c.Get(ctx, client.ObjectKey{Name: clusterImageSetName(acp)}, &hivev1.ClusterImageSet{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("configures TPM 2.0 disk encryption", func() {
+		acp.Spec.DiskEncryption = &controlplanev1.DiskEncryption{EnableOn: "all", Mode: "tpmv2"}
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		Expect(getAgentClusterInstall(c).Spec.DiskEncryption).To(Equal(&hiveext.DiskEncryption{
+			EnableOn: ptr.To("all"),
+			Mode:     ptr.To("tpmv2"),
+		}))
+	})
+
+	It("configures Tang disk encryption", func() {
+		acp.Spec.DiskEncryption = &controlplanev1.DiskEncryption{
+			EnableOn: "masters",
+			Mode:     "tang",
+			TangServers: []controlplanev1.TangServer{
+				{URL: "http://tang.example.com:7500", Thumbprint: "PLjNyRdGw03zlRoGjQYMahSZGu9"},
+			},
+		}
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		diskEncryption := getAgentClusterInstall(c).Spec.DiskEncryption
+		Expect(diskEncryption.EnableOn).To(HaveValue(Equal("masters")))
+		Expect(diskEncryption.Mode).To(HaveValue(Equal("tang")))
+		Expect(diskEncryption.TangServers).To(MatchJSON(
+			`[{"url":"http://tang.example.com:7500","thumbprint":"PLjNyRdGw03zlRoGjQYMahSZGu9"}]`))
+	})
+
+	It("requests FIPS mode through the install config overrides", func() {
+		acp.Spec.FIPS = true
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		Expect(getAgentClusterInstall(c).Annotations).To(HaveKeyWithValue(installConfigOverridesAnnotation, `{"fips":true}`))
+	})
+
+	It("installs the node SSH key on the installed nodes", func() {
+		acp.Spec.SSHAuthorizedKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDiscovery discovery"
+		acp.Spec.NodeSSHAuthorizedKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINodes nodes"
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		Expect(getAgentClusterInstall(c).Spec.SSHPublicKey).To(Equal("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINodes nodes"))
+	})
+
+	Context("with manifests ConfigMaps", func() {
+		BeforeEach(func() {
+			acp.Spec.ManifestsConfigMapRefs = []corev1.LocalObjectReference{{Name: "extra-manifests"}, {Name: "install-config-overrides"}}
+		})
+
+		newConfigMap := func(name string) *corev1.ConfigMap {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace}}
+		}
+
+		It("references them from the AgentClusterInstall", func() {
+			c := newFakeClient(newTestScheme(), acp, cluster, newConfigMap("extra-manifests"), newConfigMap("install-config-overrides"))
+			updated := reconcileACP(c)
+
+			Expect(getAgentClusterInstall(c).Spec.ManifestsConfigMapRefs).To(Equal([]hiveext.ManifestsConfigMapReference{
+				{Name: "extra-manifests"}, {Name: "install-config-overrides"},
+			}))
+			Expect(conditions.IsTrue(updated, controlplanev1.ManifestsAvailableCondition)).To(BeTrue())
+		})
+
+		It("reports the ConfigMaps that are missing", func() {
+			c := newFakeClient(newTestScheme(), acp, cluster, newConfigMap("extra-manifests"))
+			updated := reconcileACP(c)
+
+			condition := conditions.Get(updated, controlplanev1.ManifestsAvailableCondition)
+			Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(controlplanev1.ManifestsConfigMapMissingReason))
+			Expect(condition.Message).To(Equal("Manifests ConfigMaps not found: install-config-overrides"))
+		})
+	})
+
+	It("installs the additional CAs through a generated manifest", func() {
+		ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("ca")}))
+		acp.Spec.AdditionalCAs = []string{ca}
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		Expect(getAgentClusterInstall(c).Spec.ManifestsConfigMapRefs).To(Equal([]hiveext.ManifestsConfigMapReference{
+			{Name: "test-acp-additional-cas"},
+		}))
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "test-acp-additional-cas"}, configMap)).To(Succeed())
+		Expect(metav1.IsControlledBy(configMap, acp)).To(BeTrue())
+
+		manifest := &corev1.ConfigMap{}
+		Expect(yaml.Unmarshal([]byte(configMap.Data["additional-trusted-cas.yaml"]), manifest)).To(Succeed())
+		Expect(manifest.Kind).To(Equal("ConfigMap"))
+		Expect(manifest.Namespace).To(Equal("openshift-config"))
+		Expect(manifest.Name).To(Equal("additional-trusted-cas"))
+		Expect(manifest.Data).To(Equal(map[string]string{"ca-bundle.crt": ca}))
+	})
+
+	It("installs the inline manifests through a generated manifest", func() {
+		chrony := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: chrony\n"
+		acp.Spec.InlineManifests = []controlplanev1.Manifest{
+			{Name: "50-chrony.yaml", Content: chrony},
+			{Name: "60-encoded.yaml", Content: base64.StdEncoding.EncodeToString([]byte(chrony)), Encoding: controlplanev1.ManifestEncodingBase64},
+		}
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+
+		Expect(getAgentClusterInstall(c).Spec.ManifestsConfigMapRefs).To(Equal([]hiveext.ManifestsConfigMapReference{
+			{Name: "test-acp-inline-manifests"},
+		}))
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "test-acp-inline-manifests"}, configMap)).To(Succeed())
+		Expect(metav1.IsControlledBy(configMap, acp)).To(BeTrue())
+		Expect(configMap.Data).To(Equal(map[string]string{"50-chrony.yaml": chrony, "60-encoded.yaml": chrony}))
+	})
+
+	It("reports the install as in progress until it completes", func() {
+		c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstallWithConditions(
+			hivev1.ClusterInstallCondition{
+				Type:    hiveext.ClusterCompletedCondition,
+				Status:  corev1.ConditionFalse,
+				Reason:  "InstallationInProgress",
+				Message: "Installation in progress: Finalizing cluster installation",
+			},
+		))
+
+		updated := reconcileACP(c)
+
+		condition := conditions.Get(updated, controlplanev1.InstallCompleteCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.InstallInProgressReason))
+		Expect(condition.Message).To(ContainSubstring("Finalizing cluster installation"))
+		Expect(updated.Status.Initialized).To(BeFalse())
+		Expect(updated.Status.Ready).To(BeFalse())
+	})
+
+	It("marks the control plane initialized once the install completed", func() {
+		// Without an API VIP the Cluster gets no control plane endpoint.
+		acp.Spec.APIVIPs = nil
+		c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstallWithConditions(
+			hivev1.ClusterInstallCondition{Type: hiveext.ClusterCompletedCondition, Status: corev1.ConditionTrue},
+			hivev1.ClusterInstallCondition{Type: hiveext.ClusterFailedCondition, Status: corev1.ConditionFalse},
+		))
+
+		updated := reconcileACP(c)
+
+		Expect(conditions.IsTrue(updated, controlplanev1.InstallCompleteCondition)).To(BeTrue())
+		Expect(updated.Status.Initialized).To(BeTrue())
+		// Ready also needs a reachable control plane endpoint, which the
+		// Cluster does not have.
+		Expect(updated.Status.Ready).To(BeFalse())
+		Expect(updated.Status.FailureMessage).To(BeNil())
+	})
+
+	It("surfaces the failure message of a failed install", func() {
+		c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstallWithConditions(
+			hivev1.ClusterInstallCondition{Type: hiveext.ClusterCompletedCondition, Status: corev1.ConditionFalse},
+			hivev1.ClusterInstallCondition{
+				Type:    hiveext.ClusterFailedCondition,
+				Status:  corev1.ConditionTrue,
+				Message: "The installation failed: cluster has hosts in error",
+			},
+		))
+
+		updated := reconcileACP(c)
+
+		condition := conditions.Get(updated, controlplanev1.InstallCompleteCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.InstallFailedReason))
+		Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityError))
+		Expect(condition.Message).To(Equal("The installation failed: cluster has hosts in error"))
+		Expect(updated.Status.FailureReason).To(Equal(controlplanev1.InstallFailedReason))
+		Expect(updated.Status.FailureMessage).To(HaveValue(Equal("The installation failed: cluster has hosts in error")))
+		Expect(updated.Status.Ready).To(BeFalse())
+	})
+
+	It("records a timed out install as a non-retryable failure until the spec changes", func() {
+		acp.Spec.FailureGracePeriod = &metav1.Duration{Duration: 10 * time.Minute}
+		agentClusterInstall := agentClusterInstallWithConditions(hivev1.ClusterInstallCondition{
+			Type:    hiveext.ClusterFailedCondition,
+			Status:  corev1.ConditionTrue,
+			Message: "The installation failed: Timeout while waiting for cluster version to be available",
+		})
+		c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstall, newPullSecret())
+		recorder := record.NewFakeRecorder(10)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), Recorder: recorder}
+		reconcileOnce := func() (reconcile.Result, *controlplanev1.AgentControlPlane) {
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &controlplanev1.AgentControlPlane{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+			return result, updated
+		}
+
+		// The grace period does not delay a timeout.
+		result, updated := reconcileOnce()
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(updated.Status.FailureReason).To(Equal(controlplanev1.InstallTimeoutReason))
+		Expect(updated.Status.FailureMessage).To(HaveValue(And(
+			HavePrefix("The installation failed: Timeout while waiting for cluster version to be available."),
+			HaveSuffix("change the AgentControlPlane spec to restart it."),
+		)))
+		condition := conditions.Get(updated, controlplanev1.InstallCompleteCondition)
+		Expect(condition.Reason).To(Equal(controlplanev1.InstallTimeoutReason))
+		Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityError))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning InstallTimeout ")))
+
+		// The failure outlives the AgentClusterInstall condition, without
+		// another event.
+		setFailedStatus := func(status corev1.ConditionStatus) {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), agentClusterInstall)).To(Succeed())
+			agentClusterInstall.Status.Conditions[0].Status = status
+			Expect(c.Update(ctx, agentClusterInstall)).To(Succeed())
+		}
+		setFailedStatus(corev1.ConditionFalse)
+		_, updated = reconcileOnce()
+		Expect(updated.Status.FailureReason).To(Equal(controlplanev1.InstallTimeoutReason))
+		Expect(recorder.Events).NotTo(Receive())
+
+		// A spec change restarts the install while the AgentClusterInstall
+		// still reports the timeout.
+		setFailedStatus(corev1.ConditionTrue)
+		updated.Spec.Replicas = ptr.To[int32](1)
+		updated.Generation++
+		Expect(c.Update(ctx, updated)).To(Succeed())
+		result, updated = reconcileOnce()
+		Expect(result.RequeueAfter).To(Equal(installRetryRequeueInterval))
+		Expect(updated.Status.FailureReason).To(BeEmpty())
+		Expect(updated.Status.FailureMessage).To(BeNil())
+		Expect(updated.Status.InstallRetries).To(BeZero())
+		Expect(conditions.GetReason(updated, controlplanev1.InstallCompleteCondition)).To(Equal(controlplanev1.InstallRetryingReason))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal InstallRetrying ")))
+		Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), &hiveext.AgentClusterInstall{}))).To(BeTrue())
+
+		// The AgentClusterInstall is recreated from the new spec.
+		_, updated = reconcileOnce()
+		Expect(updated.Status.FailureReason).To(BeEmpty())
+		Expect(conditions.GetReason(updated, controlplanev1.InstallCompleteCondition)).To(Equal(controlplanev1.InstallPendingReason))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), agentClusterInstall)).To(Succeed())
+		Expect(agentClusterInstall.Spec.ProvisionRequirements.ControlPlaneAgents).To(Equal(1))
+	})
+
+	It("retries a failed install up to the configured maximum", func() {
+		acp.Spec.MaxInstallRetries = ptr.To[int32](2)
+		failedCondition := hivev1.ClusterInstallCondition{
+			Type:    hiveext.ClusterFailedCondition,
+			Status:  corev1.ConditionTrue,
+			Message: "The installation failed: cluster has hosts in error",
+		}
+		agentClusterInstall := agentClusterInstallWithConditions(failedCondition)
+		c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstall, newPullSecret())
+		recorder := record.NewFakeRecorder(10)
+		reconcileOnce := func() *controlplanev1.AgentControlPlane {
+			// A fresh reconciler, as the fake client does not invalidate
+			// the reconcile cache on AgentClusterInstall changes.
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), Recorder: recorder}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &controlplanev1.AgentControlPlane{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+			return updated
+		}
+
+		for retry := int32(1); retry <= 2; retry++ {
+			updated := reconcileOnce()
+			Expect(updated.Status.InstallRetries).To(Equal(retry))
+			Expect(updated.Status.FailureReason).To(BeEmpty())
+			Expect(updated.Status.FailureMessage).To(BeNil())
+			condition := conditions.Get(updated, controlplanev1.InstallCompleteCondition)
+			Expect(condition.Reason).To(Equal(controlplanev1.InstallRetryingReason))
+			Expect(condition.Message).To(Equal(fmt.Sprintf(
+				"Retrying the install (%d of 2) after it failed: The installation failed: cluster has hosts in error", retry)))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning InstallRetrying ")))
+			Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), &hiveext.AgentClusterInstall{}))).To(BeTrue())
+
+			// The AgentClusterInstall is recreated, and fails again.
+			reconcileOnce()
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), agentClusterInstall)).To(Succeed())
+			agentClusterInstall.Status.Conditions = []hivev1.ClusterInstallCondition{failedCondition}
+			Expect(c.Update(ctx, agentClusterInstall)).To(Succeed())
+		}
+
+		updated := reconcileOnce()
+		Expect(updated.Status.InstallRetries).To(Equal(int32(2)))
+		Expect(updated.Status.FailureReason).To(Equal(controlplanev1.InstallFailedReason))
+		Expect(updated.Status.FailureMessage).To(HaveValue(Equal(failedCondition.Message)))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), &hiveext.AgentClusterInstall{})).To(Succeed())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("waits for the failed AgentClusterInstall to be deleted before recreating it", func() {
+		agentClusterInstall := agentClusterInstallWithConditions()
+		agentClusterInstall.Finalizers = []string{"test"}
+		agentClusterInstall.DeletionTimestamp = ptr.To(metav1.Now())
+		c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstall, newPullSecret())
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(installRetryRequeueInterval))
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(conditions.GetReason(updated, controlplanev1.InstallCompleteCondition)).To(Equal(controlplanev1.InstallRetryingReason))
+	})
+
+	Context("with a failure grace period", func() {
+		var now time.Time
+
+		failedCondition := hivev1.ClusterInstallCondition{
+			Type:    hiveext.ClusterFailedCondition,
+			Status:  corev1.ConditionTrue,
+			Message: "The installation failed: cluster has hosts in error",
+		}
+
+		BeforeEach(func() {
+			acp.Spec.FailureGracePeriod = &metav1.Duration{Duration: 10 * time.Minute}
+			now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		})
+
+		reconcileAt := func(c client.Client, at time.Time) (reconcile.Result, *controlplanev1.AgentControlPlane) {
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), now: func() time.Time { return at }}
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &controlplanev1.AgentControlPlane{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+			return result, updated
+		}
+
+		It("only records the failure once the grace period elapsed", func() {
+			c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstallWithConditions(failedCondition), newPullSecret())
+
+			result, updated := reconcileAt(c, now)
+			Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+			Expect(updated.Status.FailingSince).To(HaveValue(HaveField("Time", BeTemporally("==", now))))
+			Expect(updated.Status.FailureReason).To(BeEmpty())
+			Expect(updated.Status.FailureMessage).To(BeNil())
+			condition := conditions.Get(updated, controlplanev1.InstallCompleteCondition)
+			Expect(condition.Reason).To(Equal(controlplanev1.InstallFailedReason))
+			Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityWarning))
+
+			result, updated = reconcileAt(c, now.Add(4*time.Minute))
+			Expect(result.RequeueAfter).To(Equal(6 * time.Minute))
+			Expect(updated.Status.FailureMessage).To(BeNil())
+
+			_, updated = reconcileAt(c, now.Add(10*time.Minute))
+			Expect(updated.Status.FailureReason).To(Equal(controlplanev1.InstallFailedReason))
+			Expect(updated.Status.FailureMessage).To(HaveValue(Equal(failedCondition.Message)))
+			Expect(conditions.GetSeverity(updated, controlplanev1.InstallCompleteCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityError)))
+		})
+
+		It("resets the first-seen timestamp once the failure clears", func() {
+			agentClusterInstall := agentClusterInstallWithConditions(failedCondition)
+			c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstall)
+
+			_, updated := reconcileAt(c, now)
+			Expect(updated.Status.FailingSince).NotTo(BeNil())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), agentClusterInstall)).To(Succeed())
+			agentClusterInstall.Status.Conditions[0].Status = corev1.ConditionFalse
+			Expect(c.Update(ctx, agentClusterInstall)).To(Succeed())
+
+			_, updated = reconcileAt(c, now.Add(15*time.Minute))
+			Expect(updated.Status.FailingSince).To(BeNil())
+			Expect(updated.Status.FailureMessage).To(BeNil())
+		})
+
+		It("clears the recorded failure once the install recovers", func() {
+			agentClusterInstall := agentClusterInstallWithConditions(failedCondition)
+			c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstall, newPullSecret())
+
+			reconcileAt(c, now)
+			_, updated := reconcileAt(c, now.Add(10*time.Minute))
+			Expect(updated.Status.FailureReason).To(Equal(controlplanev1.InstallFailedReason))
+			Expect(updated.Status.FailureMessage).NotTo(BeNil())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(agentClusterInstall), agentClusterInstall)).To(Succeed())
+			agentClusterInstall.Status.Conditions = []hivev1.ClusterInstallCondition{
+				{Type: hiveext.ClusterFailedCondition, Status: corev1.ConditionFalse},
+				{Type: hiveext.ClusterCompletedCondition, Status: corev1.ConditionTrue},
+			}
+			Expect(c.Update(ctx, agentClusterInstall)).To(Succeed())
+
+			_, updated = reconcileAt(c, now.Add(20*time.Minute))
+			Expect(updated.Status.Initialized).To(BeTrue())
+			Expect(conditions.IsTrue(updated, controlplanev1.InstallCompleteCondition)).To(BeTrue())
+			Expect(updated.Status.FailingSince).To(BeNil())
+			Expect(updated.Status.FailureReason).To(BeEmpty())
+			Expect(updated.Status.FailureMessage).To(BeNil())
+		})
+	})
+})
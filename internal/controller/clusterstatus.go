@@ -0,0 +1,44 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// reconcileClusterStatus publishes the readiness of acp on the
+// ControlPlaneReady condition of its owner Cluster without waiting for the
+// Cluster controller to pick it up. That controller owns the condition, so
+// it is set to exactly the value the Cluster controller computes, mirroring
+// the Ready condition of acp and falling back to status.ready, and is not
+// claimed when patching: the two controllers never disagree, and concurrent
+// changes made by the Cluster controller win.
+func (r *AgentControlPlaneReconciler) reconcileClusterStatus(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) error {
+	patchHelper, err := patch.NewHelper(cluster, r.Client)
+	if err != nil {
+		return err
+	}
+	conditions.SetMirror(cluster, clusterv1.ControlPlaneReadyCondition, acp,
+		conditions.WithFallbackValue(acp.Status.Ready, clusterv1.WaitingForControlPlaneFallbackReason, clusterv1.ConditionSeverityInfo, ""))
+	return patchHelper.Patch(ctx, cluster)
+}
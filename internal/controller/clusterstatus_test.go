@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Cluster status", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+		c       client.Client
+		r       *AgentControlPlaneReconciler
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, cluster)
+		c = newFakeClient(newTestScheme(), acp, cluster, newPullSecret())
+		r = &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+	})
+
+	getCluster := func() *clusterv1.Cluster {
+		updated := &clusterv1.Cluster{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cluster), updated)).To(Succeed())
+		return updated
+	}
+
+	It("reports a control plane that is not ready yet", func() {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		condition := conditions.Get(getCluster(), clusterv1.ControlPlaneReadyCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(clusterv1.WaitingForControlPlaneFallbackReason))
+	})
+
+	It("reports the control plane ready once the AgentControlPlane is", func() {
+		acp.Status.Ready = true
+		Expect(r.reconcileClusterStatus(ctx, acp, getCluster())).To(Succeed())
+
+		Expect(conditions.IsTrue(getCluster(), clusterv1.ControlPlaneReadyCondition)).To(BeTrue())
+	})
+
+	It("leaves the other Cluster conditions alone", func() {
+		existing := getCluster()
+		conditions.MarkTrue(existing, clusterv1.InfrastructureReadyCondition)
+		Expect(c.Status().Update(ctx, existing)).To(Succeed())
+
+		acp.Status.Ready = true
+		Expect(r.reconcileClusterStatus(ctx, acp, cluster)).To(Succeed())
+
+		updated := getCluster()
+		Expect(conditions.IsTrue(updated, clusterv1.InfrastructureReadyCondition)).To(BeTrue())
+		Expect(conditions.IsTrue(updated, clusterv1.ControlPlaneReadyCondition)).To(BeTrue())
+	})
+
+	It("is not published when the ClusterStatus feature is disabled", func() {
+		r.FeatureGates = FeatureGates{ClusterStatusFeature: false}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(conditions.Has(getCluster(), clusterv1.ControlPlaneReadyCondition)).To(BeFalse())
+	})
+})
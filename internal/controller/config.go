@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// Config is the effective configuration of the controller, including the
+// built-in intervals and timeouts, as reported by the manager's
+// --print-config flag.
+type Config struct {
+	GarbageCollectStaleMachines bool            `json:"garbageCollectStaleMachines"`
+	InfraEnvPatchInterval       metav1.Duration `json:"infraEnvPatchInterval"`
+	ImageRefreshInterval        metav1.Duration `json:"imageRefreshInterval"`
+	MaxReplicas                 int32           `json:"maxReplicas"`
+	EnabledFeatures             []string        `json:"enabledFeatures"`
+	ControllerVersion           string          `json:"controllerVersion,omitempty"`
+	LabelSelector               string          `json:"labelSelector,omitempty"`
+
+	DependencyRequeueInterval      metav1.Duration `json:"dependencyRequeueInterval"`
+	OwnerClusterMaxRequeueInterval metav1.Duration `json:"ownerClusterMaxRequeueInterval"`
+	InfrastructureRequeueInterval  metav1.Duration `json:"infrastructureRequeueInterval"`
+	ReachabilityRequeueInterval    metav1.Duration `json:"reachabilityRequeueInterval"`
+	DeleteRequeueInterval          metav1.Duration `json:"deleteRequeueInterval"`
+	ControlPlaneDialTimeout        metav1.Duration `json:"controlPlaneDialTimeout"`
+	InfraEnvEventDelay             metav1.Duration `json:"infraEnvEventDelay"`
+
+	Tracing TracingConfig `json:"tracing"`
+}
+
+// TracingConfig is the OTLP trace export configuration.
+type TracingConfig struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	// Headers are sent with each export and usually carry credentials.
+	Headers Redacted `json:"headers,omitempty"`
+}
+
+// Redacted is a configuration value that must not be disclosed. It
+// serializes to a placeholder telling whether it is set.
+type Redacted string
+
+// MarshalJSON implements json.Marshaler.
+func (s Redacted) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return json.Marshal("")
+	}
+	return json.Marshal("REDACTED")
+}
+
+// String implements fmt.Stringer, so the value is not disclosed in logs
+// either.
+func (s Redacted) String() string {
+	if s == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+// Config returns the configuration of r. Settings held outside of the
+// reconciler, such as MaxReplicas and Tracing, are left for the caller to
+// fill in.
+func (r *AgentControlPlaneReconciler) Config() Config {
+	return Config{
+		GarbageCollectStaleMachines:    r.GarbageCollectStaleMachines,
+		EnabledFeatures:                r.FeatureGates.EnabledFeatures(),
+		ControllerVersion:              controllerVersion(r.ControllerVersion),
+		LabelSelector:                  labelSelector(r.LabelSelector),
+		InfraEnvPatchInterval:          metav1.Duration{Duration: r.InfraEnvPatchInterval},
+		ImageRefreshInterval:           metav1.Duration{Duration: r.ImageRefreshInterval},
+		DependencyRequeueInterval:      metav1.Duration{Duration: dependencyRequeueInterval},
+		OwnerClusterMaxRequeueInterval: metav1.Duration{Duration: ownerClusterMaxRequeueInterval},
+		InfrastructureRequeueInterval:  metav1.Duration{Duration: infrastructureRequeueInterval},
+		ReachabilityRequeueInterval:    metav1.Duration{Duration: reachabilityRequeueInterval},
+		DeleteRequeueInterval:          metav1.Duration{Duration: deleteRequeueInterval},
+		ControlPlaneDialTimeout:        metav1.Duration{Duration: controlPlaneDialTimeout},
+		InfraEnvEventDelay:             metav1.Duration{Duration: infraEnvEventDelay},
+	}
+}
+
+// configKey is the context key of the Config of a reconcile.
+type configKey struct{}
+
+// withConfig returns a copy of ctx carrying config. Reconcile resolves its
+// Config once and attaches it, so the steps it runs read the same settings
+// without them being threaded through.
+func withConfig(ctx context.Context, config Config) context.Context {
+	return context.WithValue(ctx, configKey{}, config)
+}
+
+// configFrom returns the Config carried by ctx, and whether it carries one.
+func configFrom(ctx context.Context) (Config, bool) {
+	config, ok := ctx.Value(configKey{}).(Config)
+	return config, ok
+}
+
+// reconcileConfig returns the Config of the reconcile ctx belongs to, or
+// else that of r, e.g. for steps run outside of Reconcile.
+func (r *AgentControlPlaneReconciler) reconcileConfig(ctx context.Context) Config {
+	if config, ok := configFrom(ctx); ok {
+		return config
+	}
+	return r.Config()
+}
+
+// controllerVersion returns v as a string, or "" when it is nil.
+func controllerVersion(v *version.Version) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// labelSelector returns the string form of s, empty when it is nil.
+func labelSelector(s labels.Selector) string {
+	if s == nil {
+		return ""
+	}
+	return s.String()
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("Effective configuration", func() {
+	It("reports the reconciler settings and built-in intervals", func() {
+		r := &AgentControlPlaneReconciler{GarbageCollectStaleMachines: true, InfraEnvPatchInterval: 2 * time.Minute}
+		data, err := json.Marshal(r.Config())
+		Expect(err).NotTo(HaveOccurred())
+
+		var fields map[string]any
+		Expect(json.Unmarshal(data, &fields)).To(Succeed())
+		Expect(fields).To(HaveKeyWithValue("garbageCollectStaleMachines", true))
+		Expect(fields).To(HaveKeyWithValue("infraEnvPatchInterval", "2m0s"))
+		Expect(fields).To(HaveKeyWithValue("reachabilityRequeueInterval", reachabilityRequeueInterval.String()))
+	})
+
+	It("does not disclose secret values", func() {
+		config := Config{Tracing: TracingConfig{
+			Endpoint: "https://otel.example.com:4317",
+			Headers:  Redacted("authorization=Bearer secret-token"),
+		}}
+		data, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(string(data)).NotTo(ContainSubstring("secret-token"))
+		Expect(string(data)).To(ContainSubstring(`"headers":"REDACTED"`))
+		Expect(string(data)).To(ContainSubstring("https://otel.example.com:4317"))
+		Expect(fmt.Sprintf("%v", config)).NotTo(ContainSubstring("secret-token"))
+	})
+
+	It("omits unset secrets", func() {
+		data, err := json.Marshal(Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("REDACTED"))
+	})
+
+	Context("carried by the context", func() {
+		ctx := context.Background()
+
+		It("is read by the reconcile steps over the reconciler settings", func() {
+			acp := newAgentControlPlane("test-acp")
+			c := newFakeClient(newTestScheme(), acp)
+			created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), now: func() time.Time { return created.Add(time.Hour) }}
+			_, err := r.reconcileInfraEnv(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			infraEnv.Status.CreatedTime = ptr.To(metav1.NewTime(created))
+			Expect(c.Update(ctx, infraEnv)).To(Succeed())
+
+			// The reconciler disables image refreshes, the context does not.
+			config := r.Config()
+			config.ImageRefreshInterval = metav1.Duration{Duration: 3 * time.Hour}
+			result, err := r.reconcileInfraEnv(withConfig(ctx, config), acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(2 * time.Hour))
+
+			result, err = r.reconcileInfraEnv(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+		})
+
+		It("falls back to the reconciler settings", func() {
+			r := &AgentControlPlaneReconciler{GarbageCollectStaleMachines: true}
+			_, ok := configFrom(ctx)
+			Expect(ok).To(BeFalse())
+			Expect(r.reconcileConfig(ctx)).To(Equal(r.Config()))
+
+			config, ok := configFrom(withConfig(ctx, Config{MaxReplicas: 5}))
+			Expect(ok).To(BeTrue())
+			Expect(config.MaxReplicas).To(BeEquivalentTo(5))
+		})
+	})
+})
@@ -0,0 +1,62 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// infraEnvEventDelay is how long the reconcile of an AgentControlPlane is
+// held back after an update of its InfraEnv, so that the bursts of status
+// updates the assisted service makes while generating the discovery image
+// collapse into a single reconcile.
+const infraEnvEventDelay = 2 * time.Second
+
+// debouncing wraps the handler of a watch so that the requests it enqueues
+// on update events are only added to the queue after delay. Requests already
+// waiting are not added again, so that rapid successive updates lead to a
+// single reconcile, which reads the latest state of the object. Create,
+// delete and generic events are enqueued right away.
+func debouncing(h handler.EventHandler, delay time.Duration) handler.EventHandler {
+	return debouncingHandler{EventHandler: h, delay: delay}
+}
+
+type debouncingHandler struct {
+	handler.EventHandler
+	delay time.Duration
+}
+
+func (h debouncingHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Update(ctx, e, debouncingQueue{RateLimitingInterface: q, delay: h.delay})
+}
+
+// debouncingQueue delays each request added by its delay. The delaying
+// queue keeps a single entry per request, at the earliest time it was asked
+// for.
+type debouncingQueue struct {
+	workqueue.RateLimitingInterface
+	delay time.Duration
+}
+
+func (q debouncingQueue) Add(item interface{}) {
+	q.RateLimitingInterface.AddAfter(item, q.delay)
+}
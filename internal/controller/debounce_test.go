@@ -0,0 +1,85 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("InfraEnv event debouncing", func() {
+	ctx := context.Background()
+
+	var (
+		clock    *clocktesting.FakeClock
+		queue    workqueue.RateLimitingInterface
+		h        handler.EventHandler
+		infraEnv *aiv1beta1.InfraEnv
+	)
+
+	BeforeEach(func() {
+		clock = clocktesting.NewFakeClock(time.Now())
+		queue = workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{Clock: clock})
+		DeferCleanup(queue.ShutDown)
+
+		r := &AgentControlPlaneReconciler{}
+		h = debouncing(handler.EnqueueRequestsFromMapFunc(r.infraEnvToAgentControlPlane), infraEnvEventDelay)
+		infraEnv = &aiv1beta1.InfraEnv{ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-acp",
+			Namespace:   testNamespace,
+			Annotations: map[string]string{agentControlPlaneAnnotation: testNamespace + "/test-acp"},
+		}}
+	})
+
+	It("collapses rapid successive updates into a single reconcile", func() {
+		for i := 0; i < 5; i++ {
+			updated := infraEnv.DeepCopy()
+			updated.Status.ISODownloadURL = fmt.Sprintf("https://example.com/isos/%d.iso", i)
+			h.Update(ctx, event.UpdateEvent{ObjectOld: infraEnv, ObjectNew: updated}, queue)
+			infraEnv = updated
+			clock.Step(infraEnvEventDelay / 10)
+		}
+		Expect(queue.Len()).To(BeZero())
+
+		clock.Step(infraEnvEventDelay)
+		Eventually(queue.Len).Should(Equal(1))
+		item, _ := queue.Get()
+		Expect(item).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "test-acp"}}))
+		queue.Done(item)
+		Consistently(queue.Len, 100*time.Millisecond).Should(BeZero())
+	})
+
+	It("enqueues creations right away", func() {
+		h.Create(ctx, event.CreateEvent{Object: infraEnv}, queue)
+		Expect(queue.Len()).To(Equal(1))
+	})
+})
@@ -0,0 +1,119 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+// applyDefaults sets the unset spec fields of acp that have a default. The
+// API server applies most of them from the CRD schema, but AgentControlPlanes
+// stored before a default was introduced, or served without the schema
+// defaults, keep them unset; the controller then behaves as if they were
+// set. The defaults are not persisted.
+func applyDefaults(acp *controlplanev1.AgentControlPlane) {
+	if acp.Spec.Replicas == nil {
+		acp.Spec.Replicas = ptr.To[int32](1)
+	}
+	if acp.Spec.Platform == "" {
+		acp.Spec.Platform = controlplanev1.PlatformBareMetal
+	}
+	if acp.Spec.ImageType == "" {
+		acp.Spec.ImageType = string(aiv1beta1.ImageTypeMinimalISO)
+	}
+	if acp.Spec.ManageMachines == nil {
+		acp.Spec.ManageMachines = ptr.To(true)
+	}
+	if acp.Spec.BootMethod == "" {
+		acp.Spec.BootMethod = controlplanev1.BootMethodISO
+	}
+	if acp.Spec.DiskEncryption != nil && acp.Spec.DiskEncryption.EnableOn == "" {
+		acp.Spec.DiskEncryption.EnableOn = "none"
+	}
+}
+
+// agentControlPlaneConfigGVK is the kind holding the cluster-wide defaults of
+// the AgentControlPlanes.
+var agentControlPlaneConfigGVK = controlplanev1.GroupVersion.WithKind("AgentControlPlaneConfig")
+
+// inheritDefaults sets the unset spec fields of acp that the
+// AgentControlPlaneConfig has a default for. Like those of applyDefaults, the
+// inherited defaults are not persisted, so that changing the
+// AgentControlPlaneConfig changes all the AgentControlPlanes not overriding
+// them. Without an AgentControlPlaneConfig, or its CRD, nothing is inherited.
+func (r *AgentControlPlaneReconciler) inheritDefaults(ctx context.Context, acp *controlplanev1.AgentControlPlane) error {
+	config := &controlplanev1.AgentControlPlaneConfig{}
+	err := r.Get(ctx, client.ObjectKey{Name: controlplanev1.AgentControlPlaneConfigName}, config)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("getting the AgentControlPlaneConfig: %w", err)
+	}
+	mergeConfigDefaults(acp, &config.Spec)
+	return nil
+}
+
+// mergeConfigDefaults sets the unset spec fields of acp from defaults. The
+// default proxy is only inherited when acp sets neither spec.proxy nor
+// spec.proxyFrom, which would otherwise be overridden by it.
+func mergeConfigDefaults(acp *controlplanev1.AgentControlPlane, defaults *controlplanev1.AgentControlPlaneConfigSpec) {
+	if acp.Spec.BaseDomain == "" {
+		acp.Spec.BaseDomain = defaults.BaseDomain
+	}
+	if acp.Spec.PullSecretRef == nil && defaults.PullSecretRef != nil {
+		acp.Spec.PullSecretRef = defaults.PullSecretRef.DeepCopy()
+	}
+	if acp.Spec.Proxy == nil && acp.Spec.ProxyFrom == nil && defaults.Proxy != nil {
+		acp.Spec.Proxy = defaults.Proxy.DeepCopy()
+	}
+	if len(acp.Spec.AdditionalNTPSources) == 0 {
+		acp.Spec.AdditionalNTPSources = slices.Clone(defaults.AdditionalNTPSources)
+	}
+}
+
+// configToAgentControlPlanes enqueues the AgentControlPlanes inheriting from
+// the AgentControlPlaneConfig obj, that is all the selected ones.
+func (r *AgentControlPlaneReconciler) configToAgentControlPlanes(ctx context.Context, obj client.Object) []ctrl.Request {
+	if obj.GetName() != controlplanev1.AgentControlPlaneConfigName {
+		return nil
+	}
+	acps := &controlplanev1.AgentControlPlaneList{}
+	if err := r.List(ctx, acps); err != nil {
+		log.FromContext(ctx).Error(err, "listing the AgentControlPlanes inheriting from the AgentControlPlaneConfig")
+		return nil
+	}
+	var requests []ctrl.Request
+	for i := range acps.Items {
+		if r.selects(&acps.Items[i]) {
+			requests = append(requests, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&acps.Items[i])})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+var _ = Describe("Defaulting", func() {
+	ctx := context.Background()
+
+	It("applies the defaults without a webhook, and without persisting them", func() {
+		cluster := newCluster("test-cluster")
+		acp := newAgentControlPlane("test-acp")
+		acp.Spec.ImageType = ""
+		acp.Spec.DiskEncryption = &controlplanev1.DiskEncryption{Mode: "tpmv2"}
+		setOwnerCluster(acp, cluster)
+
+		// The fake client applies neither webhooks nor CRD schema defaults.
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		infraEnv := &aiv1beta1.InfraEnv{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+		Expect(infraEnv.Spec.ImageType).To(Equal(aiv1beta1.ImageTypeMinimalISO))
+
+		agentClusterInstall := &hiveext.AgentClusterInstall{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), agentClusterInstall)).To(Succeed())
+		Expect(agentClusterInstall.Spec.ProvisionRequirements.ControlPlaneAgents).To(Equal(1))
+		Expect(agentClusterInstall.Spec.PlatformType).To(Equal(hiveext.BareMetalPlatformType))
+		Expect(agentClusterInstall.Spec.DiskEncryption.EnableOn).To(HaveValue(Equal("none")))
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(updated.Spec.Replicas).To(BeNil())
+		Expect(updated.Spec.Platform).To(BeEmpty())
+		Expect(updated.Spec.ImageType).To(BeEmpty())
+		Expect(updated.Spec.BootMethod).To(BeEmpty())
+		Expect(updated.Spec.ManageMachines).To(BeNil())
+		Expect(updated.Spec.DiskEncryption.EnableOn).To(BeEmpty())
+	})
+
+	It("keeps the fields that are set", func() {
+		acp := newAgentControlPlane("test-acp")
+		acp.Spec.Replicas = ptr.To[int32](3)
+		acp.Spec.Platform = controlplanev1.PlatformNone
+		acp.Spec.ImageType = string(aiv1beta1.ImageTypeFullISO)
+		acp.Spec.BootMethod = controlplanev1.BootMethodIPXE
+		acp.Spec.ManageMachines = ptr.To(false)
+		expected := acp.DeepCopy()
+
+		applyDefaults(acp)
+		Expect(acp).To(Equal(expected))
+	})
+
+	It("inherits the AgentControlPlaneConfig defaults the AgentControlPlane does not override", func() {
+		config := &controlplanev1.AgentControlPlaneConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: controlplanev1.AgentControlPlaneConfigName},
+			Spec: controlplanev1.AgentControlPlaneConfigSpec{
+				BaseDomain:    "fleet.example.com",
+				PullSecretRef: &corev1.LocalObjectReference{Name: "fleet-pull-secret"},
+				Proxy:         &controlplanev1.Proxy{HTTPProxy: "http://fleet-proxy:3128"},
+
+				AdditionalNTPSources: []string{"ntp.fleet.example.com"},
+			},
+		}
+		cluster := newCluster("test-cluster")
+		inheriting := newAgentControlPlane("inheriting-acp")
+		inheriting.Spec.PullSecretRef = nil
+		setOwnerCluster(inheriting, cluster)
+		overriding := newAgentControlPlane("overriding-acp")
+		overriding.Spec.BaseDomain = "override.example.com"
+		overriding.Spec.Proxy = &controlplanev1.Proxy{HTTPSProxy: "https://own-proxy:3129"}
+		overriding.Spec.AdditionalNTPSources = []string{"192.168.111.1"}
+		setOwnerCluster(overriding, cluster)
+
+		c := newFakeClient(newTestScheme(), config, inheriting, overriding, cluster)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		for _, acp := range []*controlplanev1.AgentControlPlane{inheriting, overriding} {
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		clusterDeployment := &hivev1.ClusterDeployment{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(inheriting), clusterDeployment)).To(Succeed())
+		Expect(clusterDeployment.Spec.BaseDomain).To(Equal("fleet.example.com"))
+		Expect(clusterDeployment.Spec.PullSecretRef).To(HaveValue(Equal(corev1.LocalObjectReference{Name: "fleet-pull-secret"})))
+		infraEnv := &aiv1beta1.InfraEnv{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(inheriting), infraEnv)).To(Succeed())
+		Expect(infraEnv.Spec.Proxy).To(Equal(&aiv1beta1.Proxy{HTTPProxy: "http://fleet-proxy:3128"}))
+		Expect(infraEnv.Spec.AdditionalNTPSources).To(Equal([]string{"ntp.fleet.example.com"}))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(overriding), clusterDeployment)).To(Succeed())
+		Expect(clusterDeployment.Spec.BaseDomain).To(Equal("override.example.com"))
+		Expect(clusterDeployment.Spec.PullSecretRef).To(HaveValue(Equal(corev1.LocalObjectReference{Name: "pull-secret"})))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(overriding), infraEnv)).To(Succeed())
+		Expect(infraEnv.Spec.Proxy).To(Equal(&aiv1beta1.Proxy{HTTPSProxy: "https://own-proxy:3129"}))
+		Expect(infraEnv.Spec.AdditionalNTPSources).To(Equal([]string{"192.168.111.1"}))
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(inheriting), updated)).To(Succeed())
+		Expect(updated.Spec.BaseDomain).To(BeEmpty())
+		Expect(updated.Spec.PullSecretRef).To(BeNil())
+		Expect(updated.Spec.Proxy).To(BeNil())
+		Expect(updated.Spec.AdditionalNTPSources).To(BeEmpty())
+	})
+
+	It("does not inherit the default proxy over spec.proxyFrom", func() {
+		acp := newAgentControlPlane("test-acp")
+		acp.Spec.ProxyFrom = &controlplanev1.ProxySource{ClusterNetwork: true}
+
+		mergeConfigDefaults(acp, &controlplanev1.AgentControlPlaneConfigSpec{
+			Proxy: &controlplanev1.Proxy{HTTPProxy: "http://fleet-proxy:3128"},
+		})
+		Expect(acp.Spec.Proxy).To(BeNil())
+	})
+})
@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+// reconcileDelete deletes the control plane Machines of acp and removes the
+// finalizer once they are gone, requeueing until then. Unmanaged Machines are
+// left for the operator to delete. The InfraEnv and the
+// install objects are garbage collected through their owner references, so
+// deletion depends on nothing else: it completes even when the owner Cluster,
+// the child objects or the CRDs checked by reconcileDependencies are already
+// gone. An InfraEnv in another namespace has no owner reference, so it, the
+// mirrored pull secret and the NMStateConfig are deleted here, and so is the
+// cluster-scoped ClusterImageSet.
+func (r *AgentControlPlaneReconciler) reconcileDelete(ctx context.Context, acp *controlplanev1.AgentControlPlane) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(acp, controlplanev1.AgentControlPlaneFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	log := log.FromContext(ctx)
+
+	// The Machines are listed by controller rather than through the owner
+	// Cluster, which may be deleted first.
+	machines := &clusterv1.MachineList{}
+	err := r.List(ctx, machines, client.InNamespace(acp.Namespace))
+	if err != nil && !meta.IsNoMatchError(err) {
+		return ctrl.Result{}, err
+	}
+
+	var remaining []string
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		if !metav1.IsControlledBy(machine, acp) {
+			continue
+		}
+		remaining = append(remaining, machine.Name)
+		if !machine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if unmanaged(machine) {
+			log.Info("waiting for the unmanaged control plane Machine to be deleted by hand", "machine", client.ObjectKeyFromObject(machine))
+			continue
+		}
+		log.Info("deleting control plane Machine", "machine", client.ObjectKeyFromObject(machine))
+		if err := r.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	}
+	if len(remaining) > 0 {
+		log.Info("waiting for the control plane Machines to be deleted", "machines", remaining, "requeueAfter", deleteRequeueInterval)
+		return ctrl.Result{RequeueAfter: deleteRequeueInterval}, nil
+	}
+
+	if key := infraEnvKey(acp); key.Namespace != acp.Namespace {
+		for _, obj := range []client.Object{
+			&aiv1beta1.InfraEnv{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}},
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: mirroredPullSecretName(acp)}},
+			&aiv1beta1.NMStateConfig{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}},
+		} {
+			if err := r.deleteAnnotatedFor(ctx, acp, obj); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	imageSet := &hivev1.ClusterImageSet{ObjectMeta: metav1.ObjectMeta{Name: clusterImageSetName(acp)}}
+	if err := r.deleteAnnotatedFor(ctx, acp, imageSet); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("control plane Machines are gone, removing the finalizer")
+	controllerutil.RemoveFinalizer(acp, controlplanev1.AgentControlPlaneFinalizer)
+	readyObserved.Delete(acp.UID)
+	return ctrl.Result{}, nil
+}
+
+// deleteAnnotatedFor deletes obj if it exists and carries the
+// agentControlPlaneAnnotation of acp.
+func (r *AgentControlPlaneReconciler) deleteAnnotatedFor(ctx context.Context, acp *controlplanev1.AgentControlPlane, obj client.Object) error {
+	err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if key, ok := acpKeyFromAnnotation(obj); !ok || key != client.ObjectKeyFromObject(acp) {
+		return nil
+	}
+	log.FromContext(ctx).Info("deleting object without an owner reference", "object", client.ObjectKeyFromObject(obj))
+	return client.IgnoreNotFound(r.Delete(ctx, obj))
+}
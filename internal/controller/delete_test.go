@@ -0,0 +1,163 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+var _ = Describe("AgentControlPlane deletion", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		cluster.Status.InfrastructureReady = true
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.Replicas = ptr.To[int32](1)
+		setOwnerCluster(acp, cluster)
+	})
+
+	reconcileACP := func(c client.Client) ctrl.Result {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	deleteACP := func(c client.Client) {
+		current := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), current)).To(Succeed())
+		Expect(current.Finalizers).To(ContainElement(controlplanev1.AgentControlPlaneFinalizer))
+		Expect(c.Delete(ctx, current)).To(Succeed())
+	}
+
+	isGone := func(c client.Client) bool {
+		err := c.Get(ctx, client.ObjectKeyFromObject(acp), &controlplanev1.AgentControlPlane{})
+		return apierrors.IsNotFound(err)
+	}
+
+	It("deletes the control plane Machines before removing the finalizer", func() {
+		machine := newControlPlaneMachine("test-acp-abcde", cluster, acp)
+		machine.Finalizers = []string{clusterv1.MachineFinalizer}
+		c := newFakeClient(newTestScheme(), acp, cluster, machine)
+		reconcileACP(c)
+		deleteACP(c)
+
+		Expect(reconcileACP(c).RequeueAfter).To(Equal(deleteRequeueInterval))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+		Expect(isGone(c)).To(BeFalse())
+
+		machine.Finalizers = nil
+		Expect(c.Update(ctx, machine)).To(Succeed())
+		reconcileACP(c)
+		Expect(isGone(c)).To(BeTrue())
+	})
+
+	It("deletes the ClusterImageSet created for the AgentControlPlane", func() {
+		acp.Spec.Version = "4.15.0"
+		c := newFakeClient(newTestScheme(), acp, cluster)
+		reconcileACP(c)
+		imageSet := &hivev1.ClusterImageSet{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: clusterImageSetName(acp)}, imageSet)).To(Succeed())
+
+		Expect(c.DeleteAllOf(ctx, &clusterv1.Machine{}, client.InNamespace(testNamespace))).To(Succeed())
+		deleteACP(c)
+		reconcileACP(c)
+		Expect(isGone(c)).To(BeTrue())
+		err := c.Get(ctx, client.ObjectKeyFromObject(imageSet), imageSet)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("leaves unmanaged Machines for the operator to delete", func() {
+		machine := newControlPlaneMachine("test-acp-abcde", cluster, acp)
+		machine.Annotations = map[string]string{controlplanev1.UnmanagedMachineAnnotation: ""}
+		c := newFakeClient(newTestScheme(), acp, cluster, machine)
+		reconcileACP(c)
+		deleteACP(c)
+
+		Expect(reconcileACP(c).RequeueAfter).To(Equal(deleteRequeueInterval))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeTrue())
+
+		Expect(c.Delete(ctx, machine)).To(Succeed())
+		reconcileACP(c)
+		Expect(isGone(c)).To(BeTrue())
+	})
+
+	It("removes the finalizer when the children and the owner Cluster are already gone", func() {
+		acp.Spec.MachineTemplate = &controlplanev1.AgentControlPlaneMachineTemplate{
+			InfrastructureRef: corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+				Kind:       "Metal3MachineTemplate",
+				Name:       "control-plane",
+			},
+		}
+		template := &unstructured.Unstructured{}
+		template.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1beta1")
+		template.SetKind("Metal3MachineTemplate")
+		template.SetNamespace(testNamespace)
+		template.SetName("control-plane")
+		Expect(unstructured.SetNestedMap(template.Object, map[string]interface{}{}, "spec", "template")).To(Succeed())
+		c := newFakeClient(newTestScheme(), acp, cluster, template)
+		reconcileACP(c)
+
+		Expect(c.DeleteAllOf(ctx, &clusterv1.Machine{}, client.InNamespace(testNamespace))).To(Succeed())
+		Expect(c.Delete(ctx, &aiv1beta1.InfraEnv{ObjectMeta: acp.ObjectMeta})).To(Succeed())
+		Expect(c.Delete(ctx, cluster)).To(Succeed())
+		deleteACP(c)
+
+		Expect(reconcileACP(c)).To(Equal(ctrl.Result{}))
+		Expect(isGone(c)).To(BeTrue())
+	})
+
+	It("removes the finalizer when the dependency CRDs are missing", func() {
+		acp.Finalizers = []string{controlplanev1.AgentControlPlaneFinalizer}
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(clusterv1.AddToScheme(s)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(s)).To(Succeed())
+		c := newFakeClient(s, acp)
+		deleteACP(c)
+
+		reconcileACP(c)
+		Expect(isGone(c)).To(BeTrue())
+	})
+})
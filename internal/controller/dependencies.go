@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+var (
+	infraEnvGVK            = aiv1beta1.GroupVersion.WithKind("InfraEnv")
+	agentGVK               = aiv1beta1.GroupVersion.WithKind("Agent")
+	agentClusterInstallGVK = hiveext.GroupVersion.WithKind("AgentClusterInstall")
+	clusterDeploymentGVK   = hivev1.GroupVersion.WithKind("ClusterDeployment")
+	clusterImageSetGVK     = hivev1.GroupVersion.WithKind("ClusterImageSet")
+)
+
+// dependency is an API served by another component that the controller
+// needs before it can reconcile a control plane.
+type dependency struct {
+	gvk schema.GroupVersionKind
+	// reason is set on the DependenciesReadyCondition when the API is missing.
+	reason string
+	// provider names the component that installs the API.
+	provider string
+}
+
+var dependencies = []dependency{
+	{gvk: infraEnvGVK, reason: controlplanev1.InfraEnvCRDMissingReason, provider: "assisted-service (the infrastructure operator)"},
+	{gvk: agentGVK, reason: controlplanev1.AgentCRDMissingReason, provider: "assisted-service (the infrastructure operator)"},
+	{gvk: agentClusterInstallGVK, reason: controlplanev1.AgentClusterInstallCRDMissingReason, provider: "assisted-service (the infrastructure operator)"},
+	{gvk: clusterDeploymentGVK, reason: controlplanev1.HiveCRDMissingReason, provider: "hive"},
+	{gvk: clusterImageSetGVK, reason: controlplanev1.HiveCRDMissingReason, provider: "hive"},
+}
+
+// crdInstalled reports whether mapper serves gvk.
+func crdInstalled(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// servedVersions returns the versions mapper serves gk at.
+func servedVersions(mapper meta.RESTMapper, gk schema.GroupKind) ([]string, error) {
+	mappings, err := mapper.RESTMappings(gk)
+	if meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, mapping := range mappings {
+		versions = append(versions, mapping.GroupVersionKind.Version)
+	}
+	return versions, nil
+}
+
+// reconcileDependencies checks that the CRDs the control plane needs are
+// installed and records the result on the DependenciesReadyCondition. A
+// missing CRD is not an error: the caller requeues after a fixed interval
+// instead of backing off on a failure that only an operator can fix. A CRD
+// served only at versions other than the one the controller was built
+// against is reported on the DependencyVersionCondition too, rather than
+// left to fail decoding later on.
+func (r *AgentControlPlaneReconciler) reconcileDependencies(ctx context.Context, acp *controlplanev1.AgentControlPlane) (bool, error) {
+	log := log.FromContext(ctx)
+
+	for _, dep := range dependencies {
+		installed, err := crdInstalled(r.RESTMapper(), dep.gvk)
+		if err != nil {
+			return false, err
+		}
+		if installed {
+			continue
+		}
+
+		versions, err := servedVersions(r.RESTMapper(), dep.gvk.GroupKind())
+		if err != nil {
+			return false, err
+		}
+		if len(versions) > 0 {
+			log.Info("required CRD is served at unsupported versions", "groupVersionKind", dep.gvk, "servedVersions", versions)
+			message := fmt.Sprintf("The %s API is served at %s, but this release of the provider requires %s; "+
+				"upgrade the provider to a release supporting %s, or install a release of %s serving %s",
+				dep.gvk.GroupKind(), strings.Join(versions, ", "), dep.gvk.Version,
+				versions[0], dep.provider, dep.gvk.Version)
+			conditions.MarkFalse(acp, controlplanev1.DependencyVersionCondition, controlplanev1.DependencyVersionUnsupportedReason,
+				clusterv1.ConditionSeverityError, "%s", message)
+			conditions.MarkFalse(acp, controlplanev1.DependenciesReadyCondition, controlplanev1.DependencyVersionUnsupportedReason,
+				clusterv1.ConditionSeverityError, "%s", message)
+			return false, nil
+		}
+
+		log.Info("required CRD is not installed", "groupVersionKind", dep.gvk)
+		conditions.MarkFalse(acp, controlplanev1.DependenciesReadyCondition, dep.reason, clusterv1.ConditionSeverityWarning,
+			"The %s API is not installed on the management cluster; install %s to continue",
+			dep.gvk.GroupKind(), dep.provider)
+		return false, nil
+	}
+
+	conditions.MarkTrue(acp, controlplanev1.DependencyVersionCondition)
+	conditions.MarkTrue(acp, controlplanev1.DependenciesReadyCondition)
+	return true, nil
+}
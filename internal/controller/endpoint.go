@@ -0,0 +1,58 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// apiServerPort is the port the OpenShift API server listens on.
+const apiServerPort = 6443
+
+// reconcileControlPlaneEndpoint sets the owner Cluster's control plane
+// endpoint to the first API VIP, so that CAPI can reach the control plane. An
+// endpoint that is already set, by this controller or anyone else, is never
+// overwritten.
+func (r *AgentControlPlaneReconciler) reconcileControlPlaneEndpoint(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) error {
+	if cluster.Spec.ControlPlaneEndpoint.IsValid() {
+		conditions.MarkTrue(acp, controlplanev1.ControlPlaneEndpointSetCondition)
+		return nil
+	}
+	if len(acp.Spec.APIVIPs) == 0 {
+		conditions.MarkFalse(acp, controlplanev1.ControlPlaneEndpointSetCondition,
+			controlplanev1.WaitingForAPIVIPReason, clusterv1.ConditionSeverityInfo,
+			"spec.apiVIPs is not set; set the control plane endpoint of Cluster %s directly", cluster.Name)
+		return nil
+	}
+
+	endpoint := clusterv1.APIEndpoint{Host: acp.Spec.APIVIPs[0], Port: apiServerPort}
+	log.FromContext(ctx).Info("setting the Cluster control plane endpoint", "cluster", client.ObjectKeyFromObject(cluster), "endpoint", endpoint.String())
+	patch := client.MergeFrom(cluster.DeepCopy())
+	cluster.Spec.ControlPlaneEndpoint = endpoint
+	if err := r.Patch(ctx, cluster, patch); err != nil {
+		return err
+	}
+	conditions.MarkTrue(acp, controlplanev1.ControlPlaneEndpointSetCondition)
+	return nil
+}
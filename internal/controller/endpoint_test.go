@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Control plane endpoint", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+		patches int
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.MachineNetwork = []string{"192.168.111.0/24"}
+		acp.Spec.APIVIPs = []string{"192.168.111.5"}
+		setOwnerCluster(acp, cluster)
+		patches = 0
+	})
+
+	newClient := func() client.Client {
+		return newFakeClientBuilder(newTestScheme(), acp, cluster).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if _, ok := obj.(*clusterv1.Cluster); ok {
+						patches++
+					}
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).
+			Build()
+	}
+
+	reconcileACP := func(c client.Client) *controlplanev1.AgentControlPlane {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return updated
+	}
+
+	getCluster := func(c client.Client) *clusterv1.Cluster {
+		updated := &clusterv1.Cluster{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cluster), updated)).To(Succeed())
+		return updated
+	}
+
+	It("sets the Cluster endpoint from the API VIP once", func() {
+		c := newClient()
+
+		updated := reconcileACP(c)
+		Expect(conditions.IsTrue(updated, controlplanev1.ControlPlaneEndpointSetCondition)).To(BeTrue())
+		Expect(getCluster(c).Spec.ControlPlaneEndpoint).To(Equal(clusterv1.APIEndpoint{Host: "192.168.111.5", Port: 6443}))
+
+		reconcileACP(c)
+		Expect(patches).To(Equal(1))
+	})
+
+	It("does not overwrite an existing endpoint", func() {
+		cluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "api.example.com", Port: 443}
+		c := newClient()
+
+		updated := reconcileACP(c)
+		Expect(conditions.IsTrue(updated, controlplanev1.ControlPlaneEndpointSetCondition)).To(BeTrue())
+		Expect(getCluster(c).Spec.ControlPlaneEndpoint.Host).To(Equal("api.example.com"))
+		Expect(patches).To(BeZero())
+	})
+
+	It("waits for an API VIP", func() {
+		acp.Spec.APIVIPs = nil
+		c := newClient()
+
+		condition := conditions.Get(reconcileACP(c), controlplanev1.ControlPlaneEndpointSetCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.WaitingForAPIVIPReason))
+		Expect(getCluster(c).Spec.ControlPlaneEndpoint.IsZero()).To(BeTrue())
+	})
+})
@@ -0,0 +1,96 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature names a feature gate guarding a behavior that is still
+// experimental, so that operators can turn it off, or on, without a new
+// release.
+type Feature string
+
+const (
+	// ReconcileCacheFeature skips the reconciles of AgentControlPlanes that
+	// start from the state the last reconcile left nothing to do in.
+	ReconcileCacheFeature Feature = "ReconcileCache"
+
+	// ClusterStatusFeature publishes the readiness of AgentControlPlanes on
+	// the ControlPlaneReady condition of their owner Clusters.
+	ClusterStatusFeature Feature = "ClusterStatus"
+)
+
+// defaultFeatureGates are the features and whether they are enabled unless
+// configured otherwise.
+var defaultFeatureGates = map[Feature]bool{
+	ReconcileCacheFeature: true,
+	ClusterStatusFeature:  true,
+}
+
+// FeatureGates tells which features are enabled. Features it does not list
+// have their default.
+type FeatureGates map[Feature]bool
+
+// ParseFeatureGates parses a comma-separated list of Feature=true|false
+// pairs, as given to the manager's --feature-gates flag.
+func ParseFeatureGates(s string) (FeatureGates, error) {
+	gates := FeatureGates{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("feature gate %q must be given as <name>=true|false", pair)
+		}
+		feature := Feature(strings.TrimSpace(name))
+		if _, known := defaultFeatureGates[feature]; !known {
+			return nil, fmt.Errorf("unknown feature gate %q", feature)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of feature gate %q: %w", feature, err)
+		}
+		gates[feature] = enabled
+	}
+	return gates, nil
+}
+
+// Enabled returns whether feature is enabled.
+func (g FeatureGates) Enabled(feature Feature) bool {
+	if enabled, ok := g[feature]; ok {
+		return enabled
+	}
+	return defaultFeatureGates[feature]
+}
+
+// EnabledFeatures returns the names of the enabled features, sorted.
+func (g FeatureGates) EnabledFeatures() []string {
+	var names []string
+	for feature := range defaultFeatureGates {
+		if g.Enabled(feature) {
+			names = append(names, string(feature))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Feature gates", func() {
+	It("parses the enabled and disabled features", func() {
+		gates, err := ParseFeatureGates("ClusterStatus=false, ReconcileCache=true")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gates.Enabled(ClusterStatusFeature)).To(BeFalse())
+		Expect(gates.Enabled(ReconcileCacheFeature)).To(BeTrue())
+		Expect(gates.EnabledFeatures()).To(Equal([]string{"ReconcileCache"}))
+	})
+
+	It("gives the features not listed their default", func() {
+		var gates FeatureGates
+		Expect(gates.Enabled(ClusterStatusFeature)).To(BeTrue())
+		Expect(gates.EnabledFeatures()).To(Equal([]string{"ClusterStatus", "ReconcileCache"}))
+	})
+
+	DescribeTable("rejecting invalid gates",
+		func(s, message string) {
+			_, err := ParseFeatureGates(s)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("an unknown feature", "Rollout=true", `unknown feature gate "Rollout"`),
+		Entry("a missing value", "ClusterStatus", "must be given as <name>=true|false"),
+		Entry("an invalid value", "ClusterStatus=maybe", `invalid value of feature gate "ClusterStatus"`),
+	)
+})
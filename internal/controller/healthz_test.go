@@ -0,0 +1,59 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("InformersSynced", func() {
+	It("fails until every informer has synced", func() {
+		infraEnvInformer := &controllertest.FakeInformer{Synced: true}
+		clusterInformer := &controllertest.FakeInformer{}
+		informers := &informertest.FakeInformers{Scheme: newTestScheme()}
+		informers.InformersByGVK = map[schema.GroupVersionKind]toolscache.SharedIndexInformer{
+			infraEnvGVK: infraEnvInformer,
+			clusterv1.GroupVersion.WithKind("Cluster"): clusterInformer,
+		}
+		check := InformersSynced(informers, &aiv1beta1.InfraEnv{}, &clusterv1.Cluster{})
+		req := httptest.NewRequest("GET", "/readyz", nil)
+
+		Expect(check(req)).To(MatchError(ContainSubstring("has not synced yet")))
+
+		clusterInformer.Synced = true
+		Expect(check(req)).To(Succeed())
+	})
+
+	It("fails when an informer cannot be created", func() {
+		informers := &informertest.FakeInformers{Scheme: newTestScheme(), Error: errors.New("no matches for kind")}
+		check := InformersSynced(informers, &aiv1beta1.InfraEnv{})
+
+		Expect(check(httptest.NewRequest("GET", "/readyz", nil))).To(MatchError(ContainSubstring("no matches for kind")))
+	})
+})
@@ -0,0 +1,322 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+const testNamespace = "test-namespace"
+
+// newTestScheme returns a scheme with every API the controller works with.
+func newTestScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+	Expect(clusterv1.AddToScheme(s)).To(Succeed())
+	Expect(controlplanev1.AddToScheme(s)).To(Succeed())
+	Expect(aiv1beta1.AddToScheme(s)).To(Succeed())
+	Expect(hiveext.AddToScheme(s)).To(Succeed())
+	Expect(hivev1.AddToScheme(s)).To(Succeed())
+	return s
+}
+
+// newRESTMapper returns a REST mapper serving every kind registered in s, the
+// way an API server would once the matching CRDs are installed.
+func newRESTMapper(s *runtime.Scheme) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(s.PrioritizedVersionsAllGroups())
+	for gvk := range s.AllKnownTypes() {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	return mapper
+}
+
+// fakeClientBuilder builds fake clients supporting server-side apply, which
+// the fake client of controller-runtime rejects.
+type fakeClientBuilder struct {
+	*fake.ClientBuilder
+	funcs interceptor.Funcs
+}
+
+// WithInterceptorFuncs intercepts the calls made to the built client. The
+// interceptors see applies as apply patches, and pass them on to a client
+// emulating them.
+func (b *fakeClientBuilder) WithInterceptorFuncs(funcs interceptor.Funcs) *fakeClientBuilder {
+	b.funcs = funcs
+	return b
+}
+
+// WithStatusSubresource is fake.ClientBuilder.WithStatusSubresource.
+func (b *fakeClientBuilder) WithStatusSubresource(objs ...client.Object) *fakeClientBuilder {
+	b.ClientBuilder.WithStatusSubresource(objs...)
+	return b
+}
+
+// Build builds the fake client.
+func (b *fakeClientBuilder) Build() client.WithWatch {
+	applyClient := interceptor.NewClient(b.ClientBuilder.Build(), interceptor.Funcs{Patch: emulateApply})
+	return interceptor.NewClient(applyClient, b.funcs)
+}
+
+// emulateApply approximates a server-side apply of obj: it creates obj, or
+// merges its fields into the existing object, matching owner references by
+// uid and replacing other lists. Unlike the API server, it does not track
+// field owners, so fields dropped from a later apply are kept. Like the API
+// server, it does not write an object the apply does not change.
+func emulateApply(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	applied := obj.(*unstructured.Unstructured)
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(applied.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKeyFromObject(applied), existing)
+	if apierrors.IsNotFound(err) {
+		return c.Create(ctx, applied)
+	}
+	if err != nil {
+		return err
+	}
+
+	merged := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(existing.Object)}
+	mergeApplied(merged.Object, applied.Object)
+	if !equality.Semantic.DeepEqual(merged.Object, existing.Object) {
+		if err := c.Update(ctx, merged); err != nil {
+			return err
+		}
+	}
+	applied.Object = merged.Object
+	return nil
+}
+
+func mergeApplied(current, applied map[string]interface{}) {
+	for key, value := range applied {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if currentValue, ok := current[key].(map[string]interface{}); ok {
+				mergeApplied(currentValue, value)
+				continue
+			}
+		case []interface{}:
+			if key == "ownerReferences" {
+				current[key] = mergeOwnerReferences(current[key], value)
+				continue
+			}
+		}
+		current[key] = runtime.DeepCopyJSONValue(value)
+	}
+}
+
+func mergeOwnerReferences(current interface{}, applied []interface{}) []interface{} {
+	refs, _ := current.([]interface{})
+	for _, ref := range applied {
+		ref := ref.(map[string]interface{})
+		i := slices.IndexFunc(refs, func(r interface{}) bool { return r.(map[string]interface{})["uid"] == ref["uid"] })
+		if i < 0 {
+			refs = append(refs, ref)
+			continue
+		}
+		refs[i] = ref
+	}
+	return refs
+}
+
+// emptyFields returns the paths of the fields of object holding an empty
+// value. Applying them would take their ownership from the managers setting
+// them.
+func emptyFields(object map[string]interface{}) []string {
+	var paths []string
+	for key, value := range object {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if len(value) == 0 {
+				paths = append(paths, key)
+			}
+			for _, path := range emptyFields(value) {
+				paths = append(paths, key+"."+path)
+			}
+		case []interface{}:
+			if len(value) == 0 {
+				paths = append(paths, key)
+			}
+			for i, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					for _, path := range emptyFields(item) {
+						paths = append(paths, fmt.Sprintf("%s[%d].%s", key, i, path))
+					}
+				}
+			}
+		case string:
+			if value == "" {
+				paths = append(paths, key)
+			}
+		case nil:
+			paths = append(paths, key)
+		}
+	}
+	return paths
+}
+
+// isInfraEnv returns whether obj is an InfraEnv, either typed or the
+// configuration of an apply.
+func isInfraEnv(obj client.Object) bool {
+	if _, ok := obj.(*aiv1beta1.InfraEnv); ok {
+		return true
+	}
+	return obj.GetObjectKind().GroupVersionKind() == aiv1beta1.GroupVersion.WithKind("InfraEnv")
+}
+
+// newFakeClientBuilder returns a fake client builder backed by s and seeded
+// with objs, for tests that need to customize the client further.
+func newFakeClientBuilder(s *runtime.Scheme, objs ...client.Object) *fakeClientBuilder {
+	b := fake.NewClientBuilder().
+		WithScheme(s).
+		WithRESTMapper(newRESTMapper(s)).
+		WithObjects(objs...).
+		WithStatusSubresource(&controlplanev1.AgentControlPlane{})
+	if s.Recognizes(clusterv1.GroupVersion.WithKind("Cluster")) {
+		b = b.WithStatusSubresource(&clusterv1.Cluster{})
+	}
+	// Mirror SetupWithManager, which only registers indexes for installed CRDs.
+	if s.Recognizes(clusterDeploymentGVK) {
+		b = b.WithIndex(&hivev1.ClusterDeployment{}, clusterDeploymentOwnerField, indexClusterDeploymentByOwner)
+	}
+	return &fakeClientBuilder{ClientBuilder: b}
+}
+
+// newFakeClient returns a fake client backed by s and seeded with objs.
+func newFakeClient(s *runtime.Scheme, objs ...client.Object) client.Client {
+	return newFakeClientBuilder(s, objs...).Build()
+}
+
+func newAgentControlPlane(name string) *controlplanev1.AgentControlPlane {
+	return &controlplanev1.AgentControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			UID:       types.UID(name + "-uid"),
+		},
+		Spec: controlplanev1.AgentControlPlaneSpec{
+			PullSecretRef: &corev1.LocalObjectReference{Name: "pull-secret"},
+			ImageType:     string(aiv1beta1.ImageTypeMinimalISO),
+		},
+	}
+}
+
+// newPullSecret returns a valid pull secret named as the one of the
+// AgentControlPlanes returned by newAgentControlPlane.
+func newPullSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: testNamespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"dXNlcjpwYXNzd29yZA=="}}}`),
+		},
+	}
+}
+
+func newCluster(name string) *clusterv1.Cluster {
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			UID:       types.UID(name + "-uid"),
+		},
+	}
+}
+
+// setOwnerCluster makes cluster the owner of acp, as the Cluster controller
+// does once spec.controlPlaneRef is resolved.
+func setOwnerCluster(acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) {
+	acp.OwnerReferences = append(acp.OwnerReferences, metav1.OwnerReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Cluster",
+		Name:       cluster.Name,
+		UID:        cluster.UID,
+	})
+}
+
+// newAvailableAgents returns n unbound Agents discovered through the InfraEnv
+// of acp.
+func newAvailableAgents(acp *controlplanev1.AgentControlPlane, n int) []client.Object {
+	var agents []client.Object
+	for i := 0; i < n; i++ {
+		agents = append(agents, &aiv1beta1.Agent{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-agent-%d", acp.Name, i),
+				Namespace: acp.Namespace,
+				Labels:    agentSelector(acp),
+			},
+		})
+	}
+	return agents
+}
+
+// newControlPlaneMachine returns a Machine labeled as part of cluster's
+// control plane and controlled by acp.
+func newControlPlaneMachine(name string, cluster *clusterv1.Cluster, acp *controlplanev1.AgentControlPlane) *clusterv1.Machine {
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabel:         cluster.Name,
+				clusterv1.MachineControlPlaneLabel: "",
+			},
+		},
+		Spec: clusterv1.MachineSpec{ClusterName: cluster.Name},
+	}
+	if acp != nil {
+		machine.Labels[clusterv1.MachineControlPlaneNameLabel] = acp.Name
+		machine.OwnerReferences = []metav1.OwnerReference{
+			*metav1.NewControllerRef(acp, controlplanev1.GroupVersion.WithKind("AgentControlPlane")),
+		}
+	}
+	return machine
+}
+
+// fakeWorkloadClusters serves the same client for every workload cluster.
+type fakeWorkloadClusters struct {
+	client client.Client
+}
+
+func (f *fakeWorkloadClusters) GetClient(_ context.Context, _ client.ObjectKey) (client.Client, error) {
+	return f.client, nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+// imageRefreshAnnotation is set on InfraEnvs to when the controller last
+// asked for their discovery image to be regenerated because it was getting
+// old. Changing it regenerates the image, as RegenerateISOAnnotation does.
+const imageRefreshAnnotation = "controlplane.openshift.io/image-refresh"
+
+// imageRefresh returns the value of the imageRefreshAnnotation to apply on
+// infraEnv at now: a new one once its discovery image is older than interval,
+// and the current one otherwise; zero disables refreshes. It also returns how
+// long until the image is due to be refreshed, or zero. A refresh is only
+// requested once until the image is regenerated.
+func imageRefresh(infraEnv *aiv1beta1.InfraEnv, interval time.Duration, now time.Time) (string, time.Duration) {
+	current := infraEnv.Annotations[imageRefreshAnnotation]
+	created := infraEnv.Status.CreatedTime
+	if interval == 0 || created == nil {
+		return current, 0
+	}
+	if requested, err := time.Parse(time.RFC3339, current); err == nil && requested.After(created.Time) {
+		return current, 0
+	}
+	if due := created.Add(interval).Sub(now); due > 0 {
+		return current, due
+	}
+	return now.UTC().Format(time.RFC3339), 0
+}
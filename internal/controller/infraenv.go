@@ -0,0 +1,651 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+// infraEnvVersionAnnotation records on an InfraEnv the spec.version of the
+// AgentControlPlane it was last applied for.
+const infraEnvVersionAnnotation = "controlplane.openshift.io/openshift-version"
+
+// infraEnvKey returns the key of the InfraEnv of acp: it shares the
+// AgentControlPlane's name, in spec.infraEnvNamespace if set.
+func infraEnvKey(acp *controlplanev1.AgentControlPlane) client.ObjectKey {
+	key := client.ObjectKeyFromObject(acp)
+	if acp.Spec.InfraEnvNamespace != "" {
+		key.Namespace = acp.Spec.InfraEnvNamespace
+	}
+	return key
+}
+
+// reconcileInfraEnv ensures the InfraEnv generating the discovery image for
+// this control plane exists and matches acp. The InfraEnv is found at
+// infraEnvKey; a pre-existing one is adopted, unless another
+// AgentControlPlane claims it, as reported on the InfraEnvClaimedCondition,
+// in which case its boot artifacts are not reported either. An InfraEnv in
+// another namespace cannot be owned by acp, so it is tracked through its
+// agentControlPlaneAnnotation alone and uses a mirrored pull secret.
+// The referenced mirror registry configuration is checked along the way.
+// The InfraEnv is server-side applied, so a concurrent reconcile creating it
+// first is not an error. An InfraEnv deleted after it was created is
+// recreated, with a Warning event, once recreateCooldown has passed since the
+// deletion was seen. With spec.infraEnvRecreateOnVersionChange, a version
+// change deletes the InfraEnv to create it afresh.
+func (r *AgentControlPlaneReconciler) reconcileInfraEnv(ctx context.Context, acp *controlplanev1.AgentControlPlane) (ctrl.Result, error) {
+	if err := r.reconcileMirrorRegistry(ctx, acp); err != nil {
+		return ctrl.Result{}, fmt.Errorf("configuring the mirror registry: %w", err)
+	}
+	result, err := r.ensureInfraEnv(ctx, acp)
+	if err != nil || conditions.IsFalse(acp, controlplanev1.InfraEnvClaimedCondition) {
+		return result, err
+	}
+	return result, r.reconcileBootArtifacts(ctx, acp)
+}
+
+// reconcileBootArtifacts reports in status.bootArtifacts the URLs the
+// InfraEnv gives for the artifacts of the configured boot method. They are
+// cleared, and the BootArtifactsAvailableCondition is false, until the
+// InfraEnv reports all of them; its status updates trigger a reconcile. Once
+// the InfraEnv is past spec.bootArtifactsGeneration, the URLs reported last
+// are kept.
+func (r *AgentControlPlaneReconciler) reconcileBootArtifacts(ctx context.Context, acp *controlplanev1.AgentControlPlane) error {
+	infraEnv := &aiv1beta1.InfraEnv{}
+	err := r.Get(ctx, infraEnvKey(acp), infraEnv)
+	if client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("getting InfraEnv %s for its boot artifacts: %w", infraEnvKey(acp), err)
+	}
+
+	if pin := acp.Spec.BootArtifactsGeneration; pin != nil && infraEnv.Generation > *pin {
+		if pinned := acp.Status.BootArtifacts; pinned != nil && bootArtifactsComplete(acp, pinned) {
+			conditions.MarkTrue(acp, controlplanev1.BootArtifactsAvailableCondition)
+			return nil
+		}
+		acp.Status.BootArtifacts = nil
+		conditions.MarkFalse(acp, controlplanev1.BootArtifactsAvailableCondition, controlplanev1.WaitingForBootArtifactsReason,
+			clusterv1.ConditionSeverityInfo, "InfraEnv %s is at generation %d, past the pinned generation %d; raise spec.bootArtifactsGeneration to boot its current image",
+			infraEnv.Name, infraEnv.Generation, *pin)
+		return nil
+	}
+
+	var artifacts controlplanev1.BootArtifacts
+	if bootMethod(acp) == controlplanev1.BootMethodIPXE {
+		status := infraEnv.Status.BootArtifacts
+		artifacts = controlplanev1.BootArtifacts{
+			IPXEScriptURL: status.IpxeScriptURL,
+			KernelURL:     status.KernelURL,
+			InitrdURL:     status.InitrdURL,
+			RootfsURL:     status.RootfsURL,
+		}
+	} else {
+		artifacts = controlplanev1.BootArtifacts{ISOURL: infraEnv.Status.ISODownloadURL}
+	}
+	if !bootArtifactsComplete(acp, &artifacts) {
+		acp.Status.BootArtifacts = nil
+		conditions.MarkFalse(acp, controlplanev1.BootArtifactsAvailableCondition, controlplanev1.WaitingForBootArtifactsReason,
+			clusterv1.ConditionSeverityInfo, "Waiting for InfraEnv %s to report the %s boot artifacts", infraEnv.Name, bootMethod(acp))
+		return nil
+	}
+	artifacts.InfraEnvGeneration = infraEnv.Generation
+	acp.Status.BootArtifacts = &artifacts
+	conditions.MarkTrue(acp, controlplanev1.BootArtifactsAvailableCondition)
+	return nil
+}
+
+// bootArtifactsComplete returns whether artifacts hold all the URLs needed to
+// boot with the boot method of acp.
+func bootArtifactsComplete(acp *controlplanev1.AgentControlPlane, artifacts *controlplanev1.BootArtifacts) bool {
+	urls := []string{artifacts.ISOURL}
+	if bootMethod(acp) == controlplanev1.BootMethodIPXE {
+		urls = []string{artifacts.IPXEScriptURL, artifacts.KernelURL, artifacts.InitrdURL, artifacts.RootfsURL}
+	}
+	return !slices.Contains(urls, "")
+}
+
+// bootMethod returns the boot method of acp, defaulting to the ISO.
+func bootMethod(acp *controlplanev1.AgentControlPlane) string {
+	if acp.Spec.BootMethod == "" {
+		return controlplanev1.BootMethodISO
+	}
+	return acp.Spec.BootMethod
+}
+
+func (r *AgentControlPlaneReconciler) ensureInfraEnv(ctx context.Context, acp *controlplanev1.AgentControlPlane) (ctrl.Result, error) {
+	key := infraEnvKey(acp)
+	log := log.FromContext(ctx).WithValues("infraEnv", key)
+
+	trustBundle, err := r.additionalTrustBundle(ctx, acp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	proxy, err := r.infraEnvProxy(ctx, acp)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("resolving the proxy: %w", err)
+	}
+	nmStateConfigSelector, err := r.reconcileStaticRoutes(ctx, acp)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("configuring the static routes: %w", err)
+	}
+	if acp.Spec.NMStateConfigLabelSelector != nil {
+		// The webhook forbids static routes along with a selector of the
+		// user's own, which replaces the selector of the controller.
+		nmStateConfigSelector = acp.Spec.NMStateConfigLabelSelector
+	}
+
+	existing := &aiv1beta1.InfraEnv{}
+	err = r.Get(ctx, key, existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		existing = nil
+	case err != nil:
+		return ctrl.Result{}, fmt.Errorf("getting InfraEnv %s: %w", key, err)
+	default:
+		// An InfraEnv controlled by anything else, including a deleted
+		// AgentControlPlane of the same name, is not adopted. The conflict
+		// is reported rather than retried as an error: it lasts until
+		// either spec is fixed.
+		conflict := checkNotControlledByOther(acp, existing)
+		if conflict == nil {
+			conflict = checkNotAnnotatedForOther(acp, existing)
+		}
+		if conflict != nil {
+			log.Info("not adopting the InfraEnv", "reason", conflict.Error())
+			conditions.MarkFalse(acp, controlplanev1.InfraEnvClaimedCondition, controlplanev1.InfraEnvConflictReason,
+				clusterv1.ConditionSeverityError, "InfraEnv %v", conflict)
+			return ctrl.Result{}, nil
+		}
+	}
+	conditions.MarkTrue(acp, controlplanev1.InfraEnvClaimedCondition)
+	if existing != nil && recreateForVersion(acp, existing) {
+		return ctrl.Result{}, r.recreateInfraEnv(ctx, acp, existing)
+	}
+
+	desired := &aiv1beta1.InfraEnv{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+	}
+	setACPAnnotation(desired, acp)
+	if acp.Spec.Version != "" {
+		desired.Annotations[infraEnvVersionAnnotation] = acp.Spec.Version
+	}
+	// The handled regeneration request is recorded on the InfraEnv, so
+	// changing the value on the AgentControlPlane regenerates the image
+	// exactly once. A new InfraEnv gets a fresh image, which already
+	// satisfies any pending request.
+	requested, regenerationRequested := acp.Annotations[controlplanev1.RegenerateISOAnnotation]
+	if regenerationRequested {
+		desired.Annotations[controlplanev1.RegenerateISOAnnotation] = requested
+	}
+	var refreshResult ctrl.Result
+	if existing != nil {
+		refresh, due := imageRefresh(existing, r.reconcileConfig(ctx).ImageRefreshInterval.Duration, r.clock())
+		if refresh != "" {
+			desired.Annotations[imageRefreshAnnotation] = refresh
+		}
+		if refresh != existing.Annotations[imageRefreshAnnotation] {
+			log.Info("regenerating the discovery image before its download URLs expire",
+				"createdTime", existing.Status.CreatedTime)
+		}
+		refreshResult = ctrl.Result{RequeueAfter: due}
+	}
+	defaults := infraEnvDefaults{trustBundle: trustBundle, proxy: proxy, nmStateConfigSelector: nmStateConfigSelector}
+	if key.Namespace == acp.Namespace {
+		if err := controllerutil.SetControllerReference(acp, desired, r.Scheme); err != nil {
+			return ctrl.Result{}, fmt.Errorf("setting the controller reference of InfraEnv %s: %w", key, err)
+		}
+	} else if acp.Spec.PullSecretRef != nil {
+		pullSecret, err := r.mirrorPullSecret(ctx, acp, key.Namespace)
+		if apierrors.IsNotFound(err) {
+			// reconcilePullSecret reports the missing secret; it may
+			// only be absent while a sync recreates it.
+			log.Info("waiting for the pull secret to mirror", "requeueAfter", pullSecretRequeueInterval)
+			return ctrl.Result{RequeueAfter: pullSecretRequeueInterval}, nil
+		}
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("mirroring the pull secret into namespace %s: %w", key.Namespace, err)
+		}
+		defaults.pullSecretName = pullSecret.Name
+	}
+	desired.Spec, err = desiredInfraEnvSpec(acp, defaults)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("computing the spec of InfraEnv %s: %w", key, err)
+	}
+
+	if existing == nil {
+		if acp.Status.InfraEnvCreated {
+			now := r.infraEnvDeletions.clock()
+			if !r.infraEnvDeletions.recorded(acp.UID) {
+				log.Info("InfraEnv was deleted, waiting before recreating it", "requeueAfter", recreateCooldown)
+				r.infraEnvDeletions.record(acp.UID, recreateCooldown, now)
+				return ctrl.Result{RequeueAfter: recreateCooldown}, nil
+			}
+			if wait := r.infraEnvDeletions.wait(acp.UID, recreateCooldown, now); wait > 0 {
+				return ctrl.Result{RequeueAfter: wait}, nil
+			}
+			r.infraEnvDeletions.forget(acp.UID)
+			log.Info("InfraEnv was deleted, recreating it")
+			r.eventf(acp, corev1.EventTypeWarning, "InfraEnvDeleted",
+				"InfraEnv %s was deleted and is being recreated; discovered hosts must boot the new discovery image", desired.Name)
+		}
+		config, err := r.applyConfiguration(desired)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("building the apply configuration of InfraEnv %s: %w", key, err)
+		}
+		log.Info("creating InfraEnv")
+		if err := r.apply(ctx, config, desired); err != nil {
+			return ctrl.Result{}, fmt.Errorf("creating InfraEnv %s: %w", key, err)
+		}
+		r.infraEnvPatches.record(acp.UID, r.reconcileConfig(ctx).InfraEnvPatchInterval.Duration, r.infraEnvPatches.clock())
+		acp.Status.InfraEnvCreated = true
+		return ctrl.Result{}, nil
+	}
+
+	acp.Status.InfraEnvCreated = true
+	result, err := r.updateInfraEnv(ctx, acp, existing, desired)
+	return util.LowestNonZeroResult(result, refreshResult), err
+}
+
+// recreateForVersion reports whether the existing InfraEnv of acp is to be
+// recreated for a new version, following
+// spec.infraEnvRecreateOnVersionChange. InfraEnvs that recorded no version
+// are kept.
+func recreateForVersion(acp *controlplanev1.AgentControlPlane, existing *aiv1beta1.InfraEnv) bool {
+	if !acp.Spec.InfraEnvRecreateOnVersionChange || acp.Status.Initialized || acp.Spec.Version == "" {
+		return false
+	}
+	recorded, ok := existing.Annotations[infraEnvVersionAnnotation]
+	return ok && recorded != acp.Spec.Version
+}
+
+// recreateInfraEnv deletes the existing InfraEnv of acp for the next
+// reconcile, triggered by the deletion, to create it afresh. The deletion is
+// recorded by clearing status.infraEnvCreated, so it is not taken for one
+// made by hand.
+func (r *AgentControlPlaneReconciler) recreateInfraEnv(ctx context.Context, acp *controlplanev1.AgentControlPlane, existing *aiv1beta1.InfraEnv) error {
+	acp.Status.InfraEnvCreated = false
+	if !existing.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	log.FromContext(ctx).Info("deleting the InfraEnv to recreate it for the new version", "infraEnv", client.ObjectKeyFromObject(existing),
+		"version", acp.Spec.Version, "previousVersion", existing.Annotations[infraEnvVersionAnnotation])
+	if err := r.Delete(ctx, existing); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting InfraEnv %s: %w", client.ObjectKeyFromObject(existing), err)
+	}
+	r.eventf(acp, corev1.EventTypeNormal, "RecreatingInfraEnv",
+		"InfraEnv %s is recreated for version %s; discovered hosts must boot the new discovery image", existing.Name, acp.Spec.Version)
+	return nil
+}
+
+// updateInfraEnv applies desired over the existing InfraEnv of acp, adopting
+// it. assisted-service regenerates the discovery image when the spec changes,
+// so spec changes are deferred until InfraEnvPatchInterval has elapsed since
+// the previous one, and the returned result requeues for when it does. Only
+// the fields set by this controller are applied, so fields set by other
+// managers are preserved, and nothing is sent when they are up to date. A
+// change of the pull secret reference alone is sent as a patch of that field.
+func (r *AgentControlPlaneReconciler) updateInfraEnv(ctx context.Context, acp *controlplanev1.AgentControlPlane, existing, desired *aiv1beta1.InfraEnv) (ctrl.Result, error) {
+	key := client.ObjectKeyFromObject(existing)
+	log := log.FromContext(ctx).WithValues("infraEnv", key)
+
+	var result ctrl.Result
+	now := r.infraEnvPatches.clock()
+	patchInterval := r.reconcileConfig(ctx).InfraEnvPatchInterval.Duration
+	specChanged := !equality.Semantic.DeepEqual(existing.Spec, desired.Spec)
+	if specChanged {
+		if wait := r.infraEnvPatches.wait(acp.UID, patchInterval, now); wait > 0 {
+			log.Info("deferring InfraEnv spec update to limit discovery image regenerations", "requeueAfter", wait)
+			desired.Spec = existing.Spec
+			specChanged = false
+			result = ctrl.Result{RequeueAfter: wait}
+		}
+	}
+
+	if specChanged {
+		drifted, err := r.onlyPullSecretDrifted(existing, desired)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("comparing InfraEnv %s with its apply configuration: %w", key, err)
+		}
+		if drifted {
+			return result, r.patchInfraEnvPullSecret(ctx, acp, existing, desired.Spec.PullSecretRef, now)
+		}
+	}
+
+	config, err := r.applyConfiguration(desired)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("building the apply configuration of InfraEnv %s: %w", key, err)
+	}
+	// Fields this controller stopped setting are only noticed through the
+	// spec comparison, as applying leaves their removal to the API server.
+	applied, err := isApplied(existing, config)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("comparing InfraEnv %s with its apply configuration: %w", key, err)
+	}
+	if applied && !specChanged {
+		return result, nil
+	}
+
+	if requested, ok := desired.Annotations[controlplanev1.RegenerateISOAnnotation]; ok &&
+		requested != existing.Annotations[controlplanev1.RegenerateISOAnnotation] {
+		log.Info("regenerating the discovery image on request", "value", requested)
+	}
+	if _, annotated := acpKeyFromAnnotation(existing); metav1.GetControllerOf(existing) == nil && !annotated {
+		log.Info("adopting InfraEnv")
+	}
+	updated := &aiv1beta1.InfraEnv{}
+	if err := r.apply(ctx, config, updated); err != nil {
+		return ctrl.Result{}, fmt.Errorf("patching InfraEnv %s: %w", key, err)
+	}
+	// Fields left empty here but set by other managers keep their value, so
+	// the apply may not have changed the spec after all.
+	if !equality.Semantic.DeepEqual(existing.Spec, updated.Spec) {
+		log.Info("updated InfraEnv spec, the discovery image will be regenerated")
+		r.infraEnvPatches.record(acp.UID, patchInterval, now)
+	}
+	return result, nil
+}
+
+// onlyPullSecretDrifted returns whether the pull secret reference is all
+// that differs between the existing InfraEnv and the desired one.
+func (r *AgentControlPlaneReconciler) onlyPullSecretDrifted(existing, desired *aiv1beta1.InfraEnv) (bool, error) {
+	if equality.Semantic.DeepEqual(existing.Spec.PullSecretRef, desired.Spec.PullSecretRef) {
+		return false, nil
+	}
+	rest := desired.DeepCopy()
+	rest.Spec.PullSecretRef = existing.Spec.PullSecretRef
+	if !equality.Semantic.DeepEqual(existing.Spec, rest.Spec) {
+		return false, nil
+	}
+	config, err := r.applyConfiguration(rest)
+	if err != nil {
+		return false, err
+	}
+	return isApplied(existing, config)
+}
+
+// patchInfraEnvPullSecret points the existing InfraEnv of acp at ref with a
+// patch of that field alone, sent as the field manager of the applies so the
+// field keeps a single owner. assisted-service embeds the pull secret in the
+// discovery image, so the image is regenerated, as for any other spec
+// change; when only the content of the referenced Secret changes, the
+// InfraEnv is not patched at all.
+func (r *AgentControlPlaneReconciler) patchInfraEnvPullSecret(ctx context.Context, acp *controlplanev1.AgentControlPlane, existing *aiv1beta1.InfraEnv, ref *corev1.LocalObjectReference, now time.Time) error {
+	key := client.ObjectKeyFromObject(existing)
+	patch := client.MergeFrom(existing.DeepCopy())
+	existing.Spec.PullSecretRef = ref
+	if err := r.Patch(ctx, existing, patch, client.FieldOwner(fieldManager)); err != nil {
+		return fmt.Errorf("patching the pull secret of InfraEnv %s: %w", key, err)
+	}
+	log.FromContext(ctx).Info("updated the pull secret of InfraEnv, the discovery image will be regenerated",
+		"infraEnv", key, "pullSecret", ptr.Deref(ref, corev1.LocalObjectReference{}).Name)
+	r.infraEnvPatches.record(acp.UID, r.reconcileConfig(ctx).InfraEnvPatchInterval.Duration, now)
+	return nil
+}
+
+// mirroredPullSecretName returns the name of the copy of the pull secret of
+// acp kept next to an InfraEnv in another namespace.
+func mirroredPullSecretName(acp *controlplanev1.AgentControlPlane) string {
+	return acp.Name + "-pull-secret"
+}
+
+// mirrorPullSecret copies the pull secret of acp into namespace, where the
+// InfraEnv can reference it, and returns the copy. Nothing is sent when the
+// copy is up to date.
+func (r *AgentControlPlaneReconciler) mirrorPullSecret(ctx context.Context, acp *controlplanev1.AgentControlPlane, namespace string) (*corev1.Secret, error) {
+	source := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: acp.Namespace, Name: acp.Spec.PullSecretRef.Name}, source); err != nil {
+		return nil, fmt.Errorf("getting the pull secret to mirror: %w", err)
+	}
+
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      mirroredPullSecretName(acp),
+			Namespace: namespace,
+		},
+		Type: source.Type,
+		Data: source.Data,
+	}
+	setACPAnnotation(desired, acp)
+	mirrored := &corev1.Secret{}
+	return mirrored, r.applyObject(ctx, desired, mirrored, func(existing client.Object) error {
+		return checkNotAnnotatedForOther(acp, existing)
+	})
+}
+
+// patchTracker remembers when objects were last patched, or seen deleted,
+// keyed by UID. The zero value is ready to use.
+type patchTracker struct {
+	mu   sync.Mutex
+	last map[types.UID]time.Time
+	// now returns the current time; time.Now when nil.
+	now func() time.Time
+}
+
+func (t *patchTracker) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// wait returns how long to wait before patching uid again so that patches
+// are at least interval apart.
+func (t *patchTracker) wait(uid types.UID, interval time.Duration, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.last[uid]
+	if !ok {
+		return 0
+	}
+	if wait := last.Add(interval).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// recorded returns whether a patch of uid is still remembered.
+func (t *patchTracker) recorded(uid types.UID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.last[uid]
+	return ok
+}
+
+// forget drops what is remembered about uid.
+func (t *patchTracker) forget(uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.last, uid)
+}
+
+// record notes that uid was patched at now. Entries older than interval no
+// longer defer anything and are dropped, so deleted objects do not
+// accumulate.
+func (t *patchTracker) record(uid types.UID, interval time.Duration, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.last == nil {
+		t.last = map[types.UID]time.Time{}
+	}
+	for other, last := range t.last {
+		if now.Sub(last) >= interval {
+			delete(t.last, other)
+		}
+	}
+	t.last[uid] = now
+}
+
+// infraEnvDefaults are the inputs of the InfraEnv spec resolved from outside
+// the AgentControlPlane spec.
+type infraEnvDefaults struct {
+	// trustBundle is the additional trust bundle, merged from all sources.
+	trustBundle string
+	// pullSecretName, if set, names the pull secret to use in place of
+	// spec.pullSecretRef, e.g. one mirrored to the InfraEnv namespace.
+	pullSecretName string
+	// proxy is the proxy of the discovered hosts, merged from all sources.
+	proxy *aiv1beta1.Proxy
+	// nmStateConfigSelector selects the NMStateConfig holding the static
+	// routes of the discovered hosts, if any.
+	nmStateConfigSelector *metav1.LabelSelector
+}
+
+// desiredInfraEnvSpec returns the InfraEnv spec acp asks for. It is both the
+// spec new InfraEnvs are created with and the one existing InfraEnvs are
+// compared against.
+func desiredInfraEnvSpec(acp *controlplanev1.AgentControlPlane, defaults infraEnvDefaults) (aiv1beta1.InfraEnvSpec, error) {
+	if override := acp.Spec.IgnitionConfigOverride; override != "" && !json.Valid([]byte(override)) {
+		return aiv1beta1.InfraEnvSpec{}, fmt.Errorf("spec.ignitionConfigOverride is not valid JSON")
+	}
+	ignitionOverride, err := discoveryIgnitionOverride(acp)
+	if err != nil {
+		return aiv1beta1.InfraEnvSpec{}, err
+	}
+
+	spec := aiv1beta1.InfraEnvSpec{
+		PullSecretRef:          acp.Spec.PullSecretRef,
+		AdditionalTrustBundle:  defaults.trustBundle,
+		SSHAuthorizedKey:       acp.Spec.SSHAuthorizedKey,
+		ImageType:              aiv1beta1.ImageType(acp.Spec.ImageType),
+		IPXEScriptType:         aiv1beta1.IPXEScriptType(acp.Spec.IPXEScriptType),
+		IgnitionConfigOverride: ignitionOverride,
+		MirrorRegistryRef:      acp.Spec.MirrorRegistryRef,
+		Proxy:                  defaults.proxy,
+		AdditionalNTPSources:   acp.Spec.AdditionalNTPSources,
+
+		NMStateConfigLabelSelector: defaults.nmStateConfigSelector,
+	}
+	if defaults.pullSecretName != "" {
+		spec.PullSecretRef = &corev1.LocalObjectReference{Name: defaults.pullSecretName}
+	}
+	for _, arg := range acp.Spec.KernelArguments {
+		spec.KernelArguments = append(spec.KernelArguments, aiv1beta1.KernelArgument{
+			Operation: arg.Operation,
+			Value:     arg.Value,
+		})
+	}
+	return spec, nil
+}
+
+const (
+	// agentServiceUnit is the systemd unit running the agent on the
+	// discovered hosts.
+	agentServiceUnit = "agent.service"
+
+	// agentLogLevelDropIn is the drop-in for agentServiceUnit setting the
+	// agent's log level.
+	agentLogLevelDropIn = "10-log-level.conf"
+
+	// defaultIgnitionVersion is the ignition spec version of the discovery
+	// ignition override when spec.ignitionConfigOverride does not set one.
+	defaultIgnitionVersion = "3.1.0"
+)
+
+// discoveryIgnitionOverride returns the ignition override of the discovery
+// image: spec.ignitionConfigOverride, with a drop-in for agentServiceUnit
+// merged in when spec.agentLogLevel is set. The override must be valid JSON.
+func discoveryIgnitionOverride(acp *controlplanev1.AgentControlPlane) (string, error) {
+	if acp.Spec.AgentLogLevel == "" {
+		return acp.Spec.IgnitionConfigOverride, nil
+	}
+
+	config := map[string]any{}
+	if override := acp.Spec.IgnitionConfigOverride; override != "" {
+		if err := json.Unmarshal([]byte(override), &config); err != nil {
+			return "", fmt.Errorf("spec.ignitionConfigOverride is not a JSON object: %w", err)
+		}
+	}
+	ignition, _ := config["ignition"].(map[string]any)
+	if ignition == nil {
+		ignition = map[string]any{}
+		config["ignition"] = ignition
+	}
+	if _, ok := ignition["version"]; !ok {
+		ignition["version"] = defaultIgnitionVersion
+	}
+
+	systemd, _ := config["systemd"].(map[string]any)
+	if systemd == nil {
+		systemd = map[string]any{}
+		config["systemd"] = systemd
+	}
+	units, _ := systemd["units"].([]any)
+	dropIn := map[string]any{
+		"name":     agentLogLevelDropIn,
+		"contents": fmt.Sprintf("[Service]\nEnvironment=LOG_LEVEL=%s\n", acp.Spec.AgentLogLevel),
+	}
+	var unit map[string]any
+	for _, u := range units {
+		if u, ok := u.(map[string]any); ok && u["name"] == agentServiceUnit {
+			unit = u
+			break
+		}
+	}
+	if unit == nil {
+		unit = map[string]any{"name": agentServiceUnit}
+		units = append(units, unit)
+	}
+	dropIns, _ := unit["dropins"].([]any)
+	unit["dropins"] = append(dropIns, dropIn)
+	systemd["units"] = units
+
+	// Maps are encoded with sorted keys, so the override is stable across
+	// reconciles and does not needlessly regenerate the discovery image.
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("encoding the discovery ignition override: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// infraEnvToAgentControlPlane maps an InfraEnv to the AgentControlPlane named
+// in its agentControlPlaneAnnotation.
+func (r *AgentControlPlaneReconciler) infraEnvToAgentControlPlane(_ context.Context, obj client.Object) []ctrl.Request {
+	key, ok := acpKeyFromAnnotation(obj)
+	if !ok {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: key}}
+}
@@ -0,0 +1,1073 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("InfraEnv reconciliation", func() {
+	ctx := context.Background()
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+	})
+
+	reconcileACP := func(c client.Client) reconcile.Result {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	getACP := func(c client.Client) *controlplanev1.AgentControlPlane {
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return updated
+	}
+
+	When("the InfraEnv CRD is not installed", func() {
+		var c client.Client
+
+		BeforeEach(func() {
+			s := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+			Expect(controlplanev1.AddToScheme(s)).To(Succeed())
+			c = newFakeClient(s, acp)
+		})
+
+		It("reports the missing dependency and requeues without an error", func() {
+			result := reconcileACP(c)
+			Expect(result.RequeueAfter).To(Equal(dependencyRequeueInterval))
+
+			condition := conditions.Get(getACP(c), controlplanev1.DependenciesReadyCondition)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(BeEquivalentTo("False"))
+			Expect(condition.Reason).To(Equal(controlplanev1.InfraEnvCRDMissingReason))
+			Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityWarning))
+			Expect(condition.Message).To(ContainSubstring("InfraEnv.agent-install.openshift.io"))
+			Expect(condition.Message).To(ContainSubstring("install assisted-service"))
+		})
+
+		It("recovers once the CRD is installed", func() {
+			reconcileACP(c)
+			Expect(conditions.IsFalse(getACP(c), controlplanev1.DependenciesReadyCondition)).To(BeTrue())
+
+			// Simulate installing the CRD by serving the same object from a
+			// client whose REST mapper knows about InfraEnv.
+			cluster := newCluster("test-cluster")
+			installed := getACP(c)
+			setOwnerCluster(installed, cluster)
+			c = newFakeClient(newTestScheme(), installed, cluster, newPullSecret())
+			result := reconcileACP(c)
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(conditions.IsTrue(getACP(c), controlplanev1.DependenciesReadyCondition)).To(BeTrue())
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+		})
+	})
+
+	When("the InfraEnv CRD serves another version", func() {
+		var c client.Client
+
+		BeforeEach(func() {
+			s := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+			Expect(controlplanev1.AddToScheme(s)).To(Succeed())
+			served := schema.GroupVersion{Group: aiv1beta1.GroupVersion.Group, Version: "v1beta2"}
+			s.AddKnownTypeWithName(served.WithKind("InfraEnv"), &aiv1beta1.InfraEnv{})
+			s.AddKnownTypeWithName(served.WithKind("InfraEnvList"), &aiv1beta1.InfraEnvList{})
+			c = newFakeClient(s, acp)
+		})
+
+		It("reports the unsupported version with guidance and requeues without an error", func() {
+			result := reconcileACP(c)
+			Expect(result.RequeueAfter).To(Equal(dependencyRequeueInterval))
+
+			updated := getACP(c)
+			condition := conditions.Get(updated, controlplanev1.DependencyVersionCondition)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(controlplanev1.DependencyVersionUnsupportedReason))
+			Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityError))
+			Expect(condition.Message).To(Equal("The InfraEnv.agent-install.openshift.io API is served at v1beta2, " +
+				"but this release of the provider requires v1beta1; upgrade the provider to a release supporting v1beta2, " +
+				"or install a release of assisted-service (the infrastructure operator) serving v1beta1"))
+			Expect(conditions.GetReason(updated, controlplanev1.DependenciesReadyCondition)).To(Equal(controlplanev1.DependencyVersionUnsupportedReason))
+		})
+	})
+
+	When("the InfraEnv does not exist", func() {
+		It("creates it from the AgentControlPlane spec", func() {
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Spec.PullSecretRef).To(Equal(acp.Spec.PullSecretRef))
+			Expect(infraEnv.Spec.ImageType).To(Equal(aiv1beta1.ImageTypeMinimalISO))
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(agentControlPlaneAnnotation, testNamespace+"/test-acp"))
+			Expect(infraEnv.OwnerReferences).To(HaveLen(1))
+			Expect(infraEnv.OwnerReferences[0].UID).To(Equal(acp.UID))
+		})
+	})
+
+	When("the InfraEnv is deleted out of band", func() {
+		It("recreates it after a cooldown and records a Warning event", func() {
+			c := newFakeClient(newTestScheme(), acp)
+			recorder := record.NewFakeRecorder(10)
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), Recorder: recorder}
+			reconcileOnce := func() reconcile.Result {
+				result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+				Expect(err).NotTo(HaveOccurred())
+				return result
+			}
+
+			// The InfraEnv is created right away.
+			reconcileOnce()
+			Expect(getACP(c).Status.InfraEnvCreated).To(BeTrue())
+			Expect(recorder.Events).To(BeEmpty())
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(c.Delete(ctx, infraEnv)).To(Succeed())
+			// As the InfraEnv watch does.
+			r.reconciles.invalidate(client.ObjectKeyFromObject(acp))
+
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			r.infraEnvDeletions.now = func() time.Time { return now }
+			Expect(reconcileOnce().RequeueAfter).To(Equal(recreateCooldown))
+			Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{}))).To(BeTrue())
+
+			now = now.Add(2 * time.Second)
+			Expect(reconcileOnce().RequeueAfter).To(Equal(recreateCooldown - 2*time.Second))
+			Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{}))).To(BeTrue())
+			Expect(recorder.Events).To(BeEmpty())
+
+			now = now.Add(recreateCooldown)
+			reconcileOnce()
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{})).To(Succeed())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning InfraEnvDeleted ")))
+		})
+	})
+
+	When("the version changes", func() {
+		const marker = "test.example.com/original"
+
+		// changeVersion creates the InfraEnv for 4.15.0, marks it, then moves
+		// acp to 4.16.0 and reconciles until the InfraEnv exists again.
+		changeVersion := func(recreate bool) (*aiv1beta1.InfraEnv, *record.FakeRecorder) {
+			acp.Spec.Version = "4.15.0"
+			acp.Spec.InfraEnvRecreateOnVersionChange = recreate
+			c := newFakeClient(newTestScheme(), acp)
+			recorder := record.NewFakeRecorder(10)
+			reconcileOnce := func() {
+				r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), Recorder: recorder}
+				_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			reconcileOnce()
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(infraEnvVersionAnnotation, "4.15.0"))
+			infraEnv.Annotations[marker] = ""
+			Expect(c.Update(ctx, infraEnv)).To(Succeed())
+
+			updated := getACP(c)
+			updated.Spec.Version = "4.16.0"
+			Expect(c.Update(ctx, updated)).To(Succeed())
+			reconcileOnce()
+			if recreate {
+				Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{}))).To(BeTrue())
+				Expect(getACP(c).Status.InfraEnvCreated).To(BeFalse())
+				reconcileOnce()
+			}
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(infraEnvVersionAnnotation, "4.16.0"))
+			Expect(getACP(c).Status.InfraEnvCreated).To(BeTrue())
+			return infraEnv, recorder
+		}
+
+		It("recreates the InfraEnv when the policy is enabled", func() {
+			infraEnv, recorder := changeVersion(true)
+
+			Expect(infraEnv.Annotations).NotTo(HaveKey(marker))
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal RecreatingInfraEnv ")))
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("updates the InfraEnv in place otherwise", func() {
+			infraEnv, recorder := changeVersion(false)
+
+			Expect(infraEnv.Annotations).To(HaveKey(marker))
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("keeps the InfraEnv of an initialized control plane", func() {
+			acp.Spec.Version = "4.16.0"
+			acp.Spec.InfraEnvRecreateOnVersionChange = true
+			infraEnv := &aiv1beta1.InfraEnv{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{infraEnvVersionAnnotation: "4.15.0"},
+			}}
+			Expect(recreateForVersion(acp, infraEnv)).To(BeTrue())
+			acp.Status.Initialized = true
+			Expect(recreateForVersion(acp, infraEnv)).To(BeFalse())
+		})
+	})
+
+	When("a concurrent reconcile creates the InfraEnv first", func() {
+		var existing *aiv1beta1.InfraEnv
+
+		BeforeEach(func() {
+			existing = &aiv1beta1.InfraEnv{
+				ObjectMeta: metav1.ObjectMeta{Name: acp.Name, Namespace: acp.Namespace},
+				Spec:       aiv1beta1.InfraEnvSpec{PullSecretRef: acp.Spec.PullSecretRef},
+			}
+		})
+
+		It("adopts the InfraEnv instead of failing on AlreadyExists", func() {
+			// Hide the InfraEnv from the first lookup, as if it were created
+			// between this reconcile's Get and Create.
+			var hidden bool
+			c := newFakeClientBuilder(newTestScheme(), acp, existing).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if _, ok := obj.(*aiv1beta1.InfraEnv); ok && !hidden {
+							hidden = true
+							return apierrors.NewNotFound(aiv1beta1.GroupVersion.WithResource("infraenvs").GroupResource(), key.Name)
+						}
+						return c.Get(ctx, key, obj, opts...)
+					},
+				}).
+				Build()
+
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(agentControlPlaneAnnotation, testNamespace+"/test-acp"))
+			Expect(infraEnv.OwnerReferences).To(HaveLen(1))
+			Expect(infraEnv.OwnerReferences[0].UID).To(Equal(acp.UID))
+		})
+
+		It("applies only the fields it sets, as its own field manager", func() {
+			existing.Labels = map[string]string{"team": "edge"}
+			var (
+				applied *unstructured.Unstructured
+				options client.PatchOptions
+			)
+			c := newFakeClientBuilder(newTestScheme(), acp, existing).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if patch.Type() == types.ApplyPatchType && isInfraEnv(obj) {
+							applied = obj.(*unstructured.Unstructured).DeepCopy()
+							options.ApplyOptions(opts)
+						}
+						return c.Patch(ctx, obj, patch, opts...)
+					},
+				}).
+				Build()
+
+			reconcileACP(c)
+
+			Expect(applied).NotTo(BeNil())
+			Expect(options.FieldManager).To(Equal(fieldManager))
+			Expect(options.Force).To(HaveValue(BeTrue()))
+			Expect(applied.GetLabels()).NotTo(HaveKey("team"))
+			Expect(applied.GetOwnerReferences()).To(ConsistOf(HaveField("UID", Equal(acp.UID))))
+			Expect(emptyFields(applied.Object)).To(BeEmpty())
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(metav1.GetControllerOf(infraEnv).UID).To(Equal(acp.UID))
+		})
+	})
+
+	When("the InfraEnv already has other owners", func() {
+		var existing *aiv1beta1.InfraEnv
+
+		BeforeEach(func() {
+			existing = &aiv1beta1.InfraEnv{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      acp.Name,
+					Namespace: acp.Namespace,
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "v1",
+						Kind:       "ConfigMap",
+						Name:       "unrelated",
+						UID:        "unrelated-uid",
+					}},
+				},
+				Spec: aiv1beta1.InfraEnvSpec{PullSecretRef: acp.Spec.PullSecretRef},
+			}
+		})
+
+		It("adds the controller reference next to the existing ones", func() {
+			c := newFakeClient(newTestScheme(), acp, existing)
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(agentControlPlaneAnnotation, testNamespace+"/test-acp"))
+			Expect(infraEnv.OwnerReferences).To(ConsistOf(
+				HaveField("UID", BeEquivalentTo("unrelated-uid")),
+				HaveField("UID", Equal(acp.UID)),
+			))
+			Expect(metav1.GetControllerOf(infraEnv).UID).To(Equal(acp.UID))
+		})
+
+		It("does not patch the InfraEnv again once adopted", func() {
+			var patches int
+			c := newFakeClientBuilder(newTestScheme(), acp, existing).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if isInfraEnv(obj) {
+							patches++
+						}
+						return c.Patch(ctx, obj, patch, opts...)
+					},
+				}).
+				Build()
+
+			reconcileACP(c)
+			reconcileACP(c)
+
+			Expect(patches).To(Equal(1))
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.OwnerReferences).To(HaveLen(2))
+		})
+	})
+
+	When("the discovery configuration changes", func() {
+		It("propagates kernel arguments and the ignition override to the InfraEnv", func() {
+			acp.Spec.KernelArguments = []controlplanev1.KernelArgument{{Operation: "append", Value: "rd.driver.pre=ice"}}
+			acp.Spec.IgnitionConfigOverride = `{"ignition":{"version":"3.1.0"}}`
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Spec.KernelArguments).To(Equal([]aiv1beta1.KernelArgument{{Operation: "append", Value: "rd.driver.pre=ice"}}))
+			Expect(infraEnv.Spec.IgnitionConfigOverride).To(Equal(`{"ignition":{"version":"3.1.0"}}`))
+		})
+
+		DescribeTable("propagates the iPXE script type to the InfraEnv",
+			func(scriptType string) {
+				acp.Spec.BootMethod = controlplanev1.BootMethodIPXE
+				acp.Spec.IPXEScriptType = scriptType
+				c := newFakeClient(newTestScheme(), acp)
+				reconcileACP(c)
+
+				infraEnv := &aiv1beta1.InfraEnv{}
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+				Expect(infraEnv.Spec.IPXEScriptType).To(Equal(aiv1beta1.IPXEScriptType(scriptType)))
+			},
+			Entry("left to assisted-service", ""),
+			Entry("always booting the discovery image", controlplanev1.IPXEScriptTypeDiscoveryImageAlways),
+			Entry("controlling the boot order", controlplanev1.IPXEScriptTypeBootOrderControl),
+		)
+
+		It("re-patches the InfraEnv when the iPXE script type changes", func() {
+			acp.Spec.BootMethod = controlplanev1.BootMethodIPXE
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			updated := getACP(c)
+			updated.Spec.IPXEScriptType = controlplanev1.IPXEScriptTypeBootOrderControl
+			Expect(c.Update(ctx, updated)).To(Succeed())
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Spec.IPXEScriptType).To(Equal(aiv1beta1.BootOrderControl))
+		})
+
+		It("merges the agent log level into the ignition override", func() {
+			acp.Spec.AgentLogLevel = "debug"
+			acp.Spec.IgnitionConfigOverride = `{"ignition":{"version":"3.2.0"},"systemd":{"units":[{"name":"agent.service","enabled":true}]}}`
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Spec.IgnitionConfigOverride).To(MatchJSON(`{
+				"ignition": {"version": "3.2.0"},
+				"systemd": {"units": [{
+					"name": "agent.service",
+					"enabled": true,
+					"dropins": [{"name": "10-log-level.conf", "contents": "[Service]\nEnvironment=LOG_LEVEL=debug\n"}]
+				}]}
+			}`))
+		})
+
+		It("re-patches the InfraEnv when the agent log level changes", func() {
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), acp)).To(Succeed())
+			acp.Spec.AgentLogLevel = "warning"
+			Expect(c.Update(ctx, acp)).To(Succeed())
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Spec.IgnitionConfigOverride).To(MatchJSON(`{
+				"ignition": {"version": "3.1.0"},
+				"systemd": {"units": [{
+					"name": "agent.service",
+					"dropins": [{"name": "10-log-level.conf", "contents": "[Service]\nEnvironment=LOG_LEVEL=warning\n"}]
+				}]}
+			}`))
+		})
+
+		It("re-patches the InfraEnv so the discovery image is regenerated", func() {
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			resourceVersion := infraEnv.ResourceVersion
+
+			updated := getACP(c)
+			updated.Spec.KernelArguments = []controlplanev1.KernelArgument{{Operation: "append", Value: "modprobe.blacklist=megaraid_sas"}}
+			Expect(c.Update(ctx, updated)).To(Succeed())
+			reconcileACP(c)
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.ResourceVersion).NotTo(Equal(resourceVersion))
+			Expect(infraEnv.Spec.KernelArguments).To(Equal([]aiv1beta1.KernelArgument{{Operation: "append", Value: "modprobe.blacklist=megaraid_sas"}}))
+		})
+
+		It("patches only the pull secret reference when that is all that changed", func() {
+			var (
+				patches       []string
+				fieldManagers []string
+			)
+			c := newFakeClientBuilder(newTestScheme(), acp).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if _, ok := obj.(*aiv1beta1.InfraEnv); ok && patch.Type() != types.ApplyPatchType {
+							data, err := patch.Data(obj)
+							Expect(err).NotTo(HaveOccurred())
+							patches = append(patches, string(data))
+							options := &client.PatchOptions{}
+							fieldManagers = append(fieldManagers, options.ApplyOptions(opts).FieldManager)
+						}
+						return c.Patch(ctx, obj, patch, opts...)
+					},
+				}).
+				Build()
+			reconcileACP(c)
+			Expect(patches).To(BeEmpty())
+
+			updated := getACP(c)
+			updated.Spec.PullSecretRef = &corev1.LocalObjectReference{Name: "rotated-pull-secret"}
+			Expect(c.Update(ctx, updated)).To(Succeed())
+			reconcileACP(c)
+
+			Expect(patches).To(HaveLen(1))
+			Expect(patches[0]).To(MatchJSON(`{"spec":{"pullSecretRef":{"name":"rotated-pull-secret"}}}`))
+			Expect(fieldManagers).To(Equal([]string{fieldManager}))
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Spec.PullSecretRef).To(Equal(&corev1.LocalObjectReference{Name: "rotated-pull-secret"}))
+
+			// Up to date again, nothing more is sent.
+			reconcileACP(c)
+			Expect(patches).To(HaveLen(1))
+		})
+
+		It("leaves an up to date InfraEnv untouched", func() {
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			resourceVersion := infraEnv.ResourceVersion
+
+			reconcileACP(c)
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.ResourceVersion).To(Equal(resourceVersion))
+		})
+	})
+
+	When("the discovery image gets old", func() {
+		var (
+			c        client.Client
+			infraEnv *aiv1beta1.InfraEnv
+			created  time.Time
+		)
+
+		reconcileAt := func(at time.Time) reconcile.Result {
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), ImageRefreshInterval: 3 * time.Hour,
+				now: func() time.Time { return at }}
+			result, err := r.reconcileInfraEnv(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			return result
+		}
+
+		// generateImage emulates assisted-service generating the discovery
+		// image of the InfraEnv at the given time.
+		generateImage := func(at time.Time, url string) {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			infraEnv.Status.CreatedTime = ptr.To(metav1.NewTime(at))
+			infraEnv.Status.ISODownloadURL = url
+			Expect(c.Update(ctx, infraEnv)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			c = newFakeClient(newTestScheme(), acp)
+			infraEnv = &aiv1beta1.InfraEnv{}
+			created = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			reconcileAt(created)
+			generateImage(created, "https://assisted.example.com/images/old.iso")
+		})
+
+		It("regenerates it before its download URL expires, then reports the new URL", func() {
+			result := reconcileAt(created.Add(time.Hour))
+			Expect(result.RequeueAfter).To(Equal(2 * time.Hour))
+			Expect(infraEnv.Annotations).NotTo(HaveKey(imageRefreshAnnotation))
+			Expect(acp.Status.BootArtifacts.ISOURL).To(Equal("https://assisted.example.com/images/old.iso"))
+
+			result = reconcileAt(created.Add(3 * time.Hour))
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(imageRefreshAnnotation, "2024-05-01T15:00:00Z"))
+
+			// The refresh is requested once until the image is regenerated.
+			reconcileAt(created.Add(3*time.Hour + time.Minute))
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(imageRefreshAnnotation, "2024-05-01T15:00:00Z"))
+
+			generateImage(created.Add(3*time.Hour+2*time.Minute), "https://assisted.example.com/images/new.iso")
+			result = reconcileAt(created.Add(3*time.Hour + 2*time.Minute))
+			Expect(result.RequeueAfter).To(Equal(3 * time.Hour))
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(imageRefreshAnnotation, "2024-05-01T15:00:00Z"))
+			Expect(acp.Status.BootArtifacts.ISOURL).To(Equal("https://assisted.example.com/images/new.iso"))
+		})
+
+		It("does not refresh it when disabled", func() {
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(),
+				now: func() time.Time { return created.Add(24 * time.Hour) }}
+			result, err := r.reconcileInfraEnv(ctx, acp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.Annotations).NotTo(HaveKey(imageRefreshAnnotation))
+		})
+	})
+
+	When("a discovery image regeneration is requested", func() {
+		var c client.Client
+
+		BeforeEach(func() {
+			c = newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+		})
+
+		infraEnvResourceVersion := func() string {
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			return infraEnv.ResourceVersion
+		}
+
+		requestRegeneration := func(value string) {
+			updated := getACP(c)
+			if updated.Annotations == nil {
+				updated.Annotations = map[string]string{}
+			}
+			updated.Annotations[controlplanev1.RegenerateISOAnnotation] = value
+			Expect(c.Update(ctx, updated)).To(Succeed())
+		}
+
+		It("patches the InfraEnv when the annotation value changes", func() {
+			before := infraEnvResourceVersion()
+
+			requestRegeneration("2024-05-01T10:00:00Z")
+			reconcileACP(c)
+
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(infraEnv.ResourceVersion).NotTo(Equal(before))
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(controlplanev1.RegenerateISOAnnotation, "2024-05-01T10:00:00Z"))
+
+			// A new value requests another regeneration.
+			before = infraEnv.ResourceVersion
+			requestRegeneration("2024-05-02T10:00:00Z")
+			reconcileACP(c)
+			Expect(infraEnvResourceVersion()).NotTo(Equal(before))
+		})
+
+		It("does not patch the InfraEnv again for an unchanged value", func() {
+			requestRegeneration("2024-05-01T10:00:00Z")
+			reconcileACP(c)
+			handled := infraEnvResourceVersion()
+
+			reconcileACP(c)
+			Expect(infraEnvResourceVersion()).To(Equal(handled))
+		})
+	})
+
+	When("InfraEnv spec updates are rate limited", func() {
+		It("defers and coalesces spec changes made within the interval", func() {
+			cluster := newCluster("test-cluster")
+			setOwnerCluster(acp, cluster)
+			c := newFakeClient(newTestScheme(), acp, cluster, newPullSecret())
+			now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), InfraEnvPatchInterval: time.Minute}
+			r.infraEnvPatches.now = func() time.Time { return now }
+			reconcileWith := func() reconcile.Result {
+				result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+				Expect(err).NotTo(HaveOccurred())
+				return result
+			}
+			setSSHKey := func(key string) {
+				updated := getACP(c)
+				updated.Spec.SSHAuthorizedKey = key
+				// The fake client does not bump it on spec changes.
+				updated.Generation++
+				Expect(c.Update(ctx, updated)).To(Succeed())
+			}
+			infraEnvSSHKey := func() string {
+				infraEnv := &aiv1beta1.InfraEnv{}
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+				return infraEnv.Spec.SSHAuthorizedKey
+			}
+
+			// Creating the InfraEnv counts as the first patch.
+			reconcileWith()
+
+			now = now.Add(10 * time.Second)
+			setSSHKey("ssh-ed25519 first")
+			Expect(reconcileWith().RequeueAfter).To(Equal(50 * time.Second))
+			Expect(infraEnvSSHKey()).To(BeEmpty())
+
+			now = now.Add(10 * time.Second)
+			setSSHKey("ssh-ed25519 second")
+			Expect(reconcileWith().RequeueAfter).To(Equal(40 * time.Second))
+			Expect(infraEnvSSHKey()).To(BeEmpty())
+
+			now = now.Add(40 * time.Second)
+			Expect(reconcileWith().RequeueAfter).To(BeZero())
+			Expect(infraEnvSSHKey()).To(Equal("ssh-ed25519 second"))
+		})
+	})
+
+	When("the InfraEnv reports its boot artifacts", func() {
+		setBootArtifacts := func(c client.Client) {
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			infraEnv.Status.ISODownloadURL = "https://images.example.com/discovery.iso"
+			infraEnv.Status.BootArtifacts = aiv1beta1.BootArtifacts{
+				IpxeScriptURL: "https://images.example.com/ipxe-script",
+				KernelURL:     "https://images.example.com/kernel",
+				InitrdURL:     "https://images.example.com/initrd",
+				RootfsURL:     "https://images.example.com/rootfs",
+			}
+			Expect(c.Update(ctx, infraEnv)).To(Succeed())
+		}
+
+		It("waits for them to be populated, then reports the ISO by default", func() {
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+
+			updated := getACP(c)
+			Expect(updated.Status.BootArtifacts).To(BeNil())
+			condition := conditions.Get(updated, controlplanev1.BootArtifactsAvailableCondition)
+			Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(controlplanev1.WaitingForBootArtifactsReason))
+
+			setBootArtifacts(c)
+			reconcileACP(c)
+
+			updated = getACP(c)
+			Expect(updated.Status.BootArtifacts).To(Equal(&controlplanev1.BootArtifacts{
+				ISOURL: "https://images.example.com/discovery.iso",
+			}))
+			Expect(conditions.IsTrue(updated, controlplanev1.BootArtifactsAvailableCondition)).To(BeTrue())
+		})
+
+		It("keeps the artifacts pinned to an InfraEnv generation while the InfraEnv changes", func() {
+			acp.Spec.BootArtifactsGeneration = ptr.To[int64](2)
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+			setInfraEnvImage := func(generation int64, url string) {
+				infraEnv := &aiv1beta1.InfraEnv{}
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+				infraEnv.Generation = generation
+				infraEnv.Status.ISODownloadURL = url
+				Expect(c.Update(ctx, infraEnv)).To(Succeed())
+			}
+
+			setInfraEnvImage(2, "https://images.example.com/discovery-2.iso")
+			reconcileACP(c)
+			Expect(getACP(c).Status.BootArtifacts).To(Equal(&controlplanev1.BootArtifacts{
+				ISOURL:             "https://images.example.com/discovery-2.iso",
+				InfraEnvGeneration: 2,
+			}))
+
+			setInfraEnvImage(3, "https://images.example.com/discovery-3.iso")
+			reconcileACP(c)
+			updated := getACP(c)
+			Expect(updated.Status.BootArtifacts).To(Equal(&controlplanev1.BootArtifacts{
+				ISOURL:             "https://images.example.com/discovery-2.iso",
+				InfraEnvGeneration: 2,
+			}))
+			Expect(conditions.IsTrue(updated, controlplanev1.BootArtifactsAvailableCondition)).To(BeTrue())
+
+			updated.Spec.BootArtifactsGeneration = ptr.To[int64](3)
+			Expect(c.Update(ctx, updated)).To(Succeed())
+			reconcileACP(c)
+			Expect(getACP(c).Status.BootArtifacts).To(Equal(&controlplanev1.BootArtifacts{
+				ISOURL:             "https://images.example.com/discovery-3.iso",
+				InfraEnvGeneration: 3,
+			}))
+		})
+
+		It("waits when the InfraEnv is past the pinned generation before any artifacts were reported", func() {
+			acp.Spec.BootArtifactsGeneration = ptr.To[int64](1)
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			infraEnv.Generation = 2
+			infraEnv.Status.ISODownloadURL = "https://images.example.com/discovery.iso"
+			Expect(c.Update(ctx, infraEnv)).To(Succeed())
+			reconcileACP(c)
+
+			updated := getACP(c)
+			Expect(updated.Status.BootArtifacts).To(BeNil())
+			condition := conditions.Get(updated, controlplanev1.BootArtifactsAvailableCondition)
+			Expect(condition.Reason).To(Equal(controlplanev1.WaitingForBootArtifactsReason))
+			Expect(condition.Message).To(ContainSubstring("past the pinned generation 1"))
+		})
+
+		It("reports the iPXE artifacts when booting with iPXE", func() {
+			acp.Spec.BootMethod = controlplanev1.BootMethodIPXE
+			c := newFakeClient(newTestScheme(), acp)
+			reconcileACP(c)
+			setBootArtifacts(c)
+			reconcileACP(c)
+
+			updated := getACP(c)
+			Expect(updated.Status.BootArtifacts).To(Equal(&controlplanev1.BootArtifacts{
+				IPXEScriptURL: "https://images.example.com/ipxe-script",
+				KernelURL:     "https://images.example.com/kernel",
+				InitrdURL:     "https://images.example.com/initrd",
+				RootfsURL:     "https://images.example.com/rootfs",
+			}))
+			Expect(conditions.IsTrue(updated, controlplanev1.BootArtifactsAvailableCondition)).To(BeTrue())
+		})
+	})
+
+	When("the InfraEnv is kept in another namespace", func() {
+		const infraEnvNamespace = "discovery"
+
+		var pullSecret *corev1.Secret
+
+		BeforeEach(func() {
+			acp.Spec.InfraEnvNamespace = infraEnvNamespace
+			pullSecret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: testNamespace},
+				Type:       corev1.SecretTypeDockerConfigJson,
+				Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+			}
+		})
+
+		It("creates the InfraEnv there with a mirrored pull secret", func() {
+			c := newFakeClient(newTestScheme(), acp, pullSecret)
+			reconcileACP(c)
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{})).NotTo(Succeed())
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: infraEnvNamespace, Name: acp.Name}, infraEnv)).To(Succeed())
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(agentControlPlaneAnnotation, testNamespace+"/test-acp"))
+			Expect(infraEnv.OwnerReferences).To(BeEmpty())
+			Expect(infraEnv.Spec.PullSecretRef).To(HaveValue(Equal(corev1.LocalObjectReference{Name: "test-acp-pull-secret"})))
+
+			mirrored := &corev1.Secret{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: infraEnvNamespace, Name: "test-acp-pull-secret"}, mirrored)).To(Succeed())
+			Expect(mirrored.Type).To(Equal(pullSecret.Type))
+			Expect(mirrored.Data).To(Equal(pullSecret.Data))
+
+			r := &AgentControlPlaneReconciler{Client: c}
+			agent := &aiv1beta1.Agent{ObjectMeta: metav1.ObjectMeta{
+				Namespace: infraEnvNamespace,
+				Name:      "agent",
+				Labels:    map[string]string{aiv1beta1.InfraEnvNameLabel: acp.Name},
+			}}
+			Expect(r.agentToAgentControlPlane(ctx, agent)).To(ConsistOf(
+				reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)},
+			))
+		})
+
+		It("deletes the InfraEnv and the mirrored pull secret with the AgentControlPlane", func() {
+			c := newFakeClient(newTestScheme(), acp, pullSecret)
+			reconcileACP(c)
+
+			Expect(c.Delete(ctx, getACP(c))).To(Succeed())
+			reconcileACP(c)
+
+			Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(acp), &controlplanev1.AgentControlPlane{}))).To(BeTrue())
+			Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKey{Namespace: infraEnvNamespace, Name: acp.Name}, &aiv1beta1.InfraEnv{}))).To(BeTrue())
+			Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKey{Namespace: infraEnvNamespace, Name: "test-acp-pull-secret"}, &corev1.Secret{}))).To(BeTrue())
+		})
+	})
+
+	It("reports a conflict when two AgentControlPlanes claim one InfraEnv", func() {
+		const infraEnvNamespace = "discovery"
+		acp.Spec.InfraEnvNamespace = infraEnvNamespace
+		other := newAgentControlPlane(acp.Name)
+		other.Namespace = "other-namespace"
+		other.UID = "other-uid"
+		other.Spec.InfraEnvNamespace = infraEnvNamespace
+		pullSecret := newPullSecret()
+		otherPullSecret := newPullSecret()
+		otherPullSecret.Namespace = other.Namespace
+		c := newFakeClient(newTestScheme(), acp, other, pullSecret, otherPullSecret)
+		reconcileACP(c)
+
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(other)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(infraEnvConflictRequeueInterval))
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(other), updated)).To(Succeed())
+		condition := conditions.Get(updated, controlplanev1.InfraEnvClaimedCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.InfraEnvConflictReason))
+		Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityError))
+		Expect(condition.Message).To(Equal("InfraEnv discovery/test-acp belongs to AgentControlPlane " + testNamespace + "/test-acp"))
+		Expect(updated.Status.BootArtifacts).To(BeNil())
+
+		infraEnv := &aiv1beta1.InfraEnv{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: infraEnvNamespace, Name: acp.Name}, infraEnv)).To(Succeed())
+		Expect(infraEnv.Annotations).To(HaveKeyWithValue(agentControlPlaneAnnotation, testNamespace+"/test-acp"))
+		Expect(conditions.IsTrue(getACP(c), controlplanev1.InfraEnvClaimedCondition)).To(BeTrue())
+	})
+
+	Context("proxy", func() {
+		var (
+			cluster   *clusterv1.Cluster
+			configMap *corev1.ConfigMap
+		)
+
+		BeforeEach(func() {
+			cluster = newCluster("test-cluster")
+			cluster.Spec.ClusterNetwork = &clusterv1.ClusterNetwork{
+				Pods:          &clusterv1.NetworkRanges{CIDRBlocks: []string{"10.128.0.0/14"}},
+				Services:      &clusterv1.NetworkRanges{CIDRBlocks: []string{"172.30.0.0/16"}},
+				ServiceDomain: "cluster.local",
+			}
+			setOwnerCluster(acp, cluster)
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: testNamespace},
+				Data: map[string]string{
+					"httpProxy":  "http://proxy.example.com:3128",
+					"httpsProxy": "http://proxy.example.com:3129",
+					"noProxy":    "example.com, 192.168.0.0/16",
+				},
+			}
+			acp.Spec.ProxyFrom = &controlplanev1.ProxySource{
+				ClusterNetwork: true,
+				ConfigMapRef:   &corev1.LocalObjectReference{Name: "proxy"},
+			}
+		})
+
+		getInfraEnv := func(c client.Client) *aiv1beta1.InfraEnv {
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			return infraEnv
+		}
+
+		It("is derived from the ConfigMap and the owner Cluster when no explicit proxy is set", func() {
+			c := newFakeClient(newTestScheme(), acp, cluster, configMap)
+			reconcileACP(c)
+
+			Expect(getInfraEnv(c).Spec.Proxy).To(Equal(&aiv1beta1.Proxy{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "http://proxy.example.com:3129",
+				NoProxy:    "example.com,192.168.0.0/16,10.128.0.0/14,172.30.0.0/16,.cluster.local",
+			}))
+		})
+
+		It("takes the fields of the explicit proxy over the derived ones", func() {
+			acp.Spec.Proxy = &controlplanev1.Proxy{HTTPSProxy: "http://other.example.com:3129"}
+			c := newFakeClient(newTestScheme(), acp, cluster, configMap)
+			reconcileACP(c)
+
+			Expect(getInfraEnv(c).Spec.Proxy).To(Equal(&aiv1beta1.Proxy{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "http://other.example.com:3129",
+				NoProxy:    "example.com,192.168.0.0/16,10.128.0.0/14,172.30.0.0/16,.cluster.local",
+			}))
+		})
+
+		It("is left unset when nothing is derived or set", func() {
+			acp.Spec.ProxyFrom = &controlplanev1.ProxySource{ClusterNetwork: true}
+			cluster.Spec.ClusterNetwork = nil
+			c := newFakeClient(newTestScheme(), acp, cluster)
+			reconcileACP(c)
+
+			Expect(getInfraEnv(c).Spec.Proxy).To(BeNil())
+		})
+
+		It("fails while the ConfigMap does not exist", func() {
+			c := newFakeClient(newTestScheme(), acp, cluster)
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+
+			_, err := r.reconcileInfraEnv(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("resolving the proxy: reading proxy ConfigMap proxy: ")))
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("errors", func() {
+		It("tell which step failed", func() {
+			c := newFakeClientBuilder(newTestScheme(), acp).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if _, ok := obj.(*aiv1beta1.InfraEnv); ok {
+							return apierrors.NewForbidden(aiv1beta1.GroupVersion.WithResource("infraenvs").GroupResource(), key.Name, nil)
+						}
+						return c.Get(ctx, key, obj, opts...)
+					},
+				}).
+				Build()
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+
+			_, err := r.reconcileInfraEnv(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("getting InfraEnv test-namespace/test-acp: ")))
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		})
+
+		It("keep the API status of the cause", func() {
+			acp.Spec.InfraEnvNamespace = "infraenvs"
+			c := newFakeClientBuilder(newTestScheme(), acp).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if _, ok := obj.(*corev1.Secret); ok {
+							return apierrors.NewForbidden(corev1.Resource("secrets"), key.Name, nil)
+						}
+						return c.Get(ctx, key, obj, opts...)
+					},
+				}).
+				Build()
+			r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+
+			_, err := r.reconcileInfraEnv(ctx, acp)
+			Expect(err).To(MatchError(ContainSubstring("mirroring the pull secret into namespace infraenvs: getting the pull secret to mirror: ")))
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		})
+	})
+
+	It("maps an annotated InfraEnv back to its AgentControlPlane", func() {
+		infraEnv := &aiv1beta1.InfraEnv{}
+		infraEnv.SetAnnotations(map[string]string{agentControlPlaneAnnotation: testNamespace + "/test-acp"})
+
+		r := &AgentControlPlaneReconciler{}
+		Expect(r.infraEnvToAgentControlPlane(ctx, infraEnv)).To(ConsistOf(
+			reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)},
+		))
+		Expect(r.infraEnvToAgentControlPlane(ctx, &aiv1beta1.InfraEnv{})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Desired InfraEnv spec", func() {
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+	})
+
+	It("propagates the discovery settings of the AgentControlPlane", func() {
+		acp.Spec.SSHAuthorizedKey = "ssh-ed25519 key"
+		acp.Spec.ImageType = string(aiv1beta1.ImageTypeFullISO)
+		acp.Spec.IgnitionConfigOverride = `{"ignition":{"version":"3.1.0"}}`
+		acp.Spec.MirrorRegistryRef = &corev1.LocalObjectReference{Name: "mirror"}
+		acp.Spec.KernelArguments = []controlplanev1.KernelArgument{
+			{Operation: "append", Value: "rd.net.timeout.carrier=60"},
+			{Operation: "delete", Value: "quiet"},
+		}
+
+		spec, err := desiredInfraEnvSpec(acp, infraEnvDefaults{trustBundle: "bundle"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec).To(Equal(aiv1beta1.InfraEnvSpec{
+			PullSecretRef:          &corev1.LocalObjectReference{Name: "pull-secret"},
+			AdditionalTrustBundle:  "bundle",
+			SSHAuthorizedKey:       "ssh-ed25519 key",
+			ImageType:              aiv1beta1.ImageTypeFullISO,
+			IgnitionConfigOverride: `{"ignition":{"version":"3.1.0"}}`,
+			MirrorRegistryRef:      &corev1.LocalObjectReference{Name: "mirror"},
+			KernelArguments: []aiv1beta1.KernelArgument{
+				{Operation: "append", Value: "rd.net.timeout.carrier=60"},
+				{Operation: "delete", Value: "quiet"},
+			},
+		}))
+	})
+
+	It("leaves unset fields empty", func() {
+		acp.Spec = controlplanev1.AgentControlPlaneSpec{}
+
+		spec, err := desiredInfraEnvSpec(acp, infraEnvDefaults{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec).To(Equal(aiv1beta1.InfraEnvSpec{}))
+	})
+
+	It("uses the pull secret named by the defaults", func() {
+		spec, err := desiredInfraEnvSpec(acp, infraEnvDefaults{pullSecretName: "test-acp-pull-secret"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec.PullSecretRef).To(Equal(&corev1.LocalObjectReference{Name: "test-acp-pull-secret"}))
+	})
+
+	It("rejects an ignition config override that is not JSON", func() {
+		acp.Spec.IgnitionConfigOverride = "ignition: {}"
+
+		_, err := desiredInfraEnvSpec(acp, infraEnvDefaults{})
+		Expect(err).To(MatchError("spec.ignitionConfigOverride is not valid JSON"))
+	})
+})
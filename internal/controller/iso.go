@@ -0,0 +1,77 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+const (
+	// metal3MachineTemplateKind is the kind of the infrastructure templates
+	// the discovery ISO is propagated to.
+	metal3MachineTemplateKind = "Metal3MachineTemplate"
+
+	// liveISODiskFormat is the Metal3 disk format booting an ISO in memory
+	// rather than writing it to disk.
+	liveISODiskFormat = "live-iso"
+)
+
+// propagateISO points the image of template, the infrastructure template of
+// acp, at the discovery ISO, so that the Machines cloned from it boot the
+// hosts into discovery. Only Metal3MachineTemplates booting the ISO are
+// patched; the ISOPropagated condition is removed for the others.
+func (r *AgentControlPlaneReconciler) propagateISO(ctx context.Context, acp *controlplanev1.AgentControlPlane, template *unstructured.Unstructured) error {
+	if template.GetKind() != metal3MachineTemplateKind || bootMethod(acp) != controlplanev1.BootMethodISO {
+		conditions.Delete(acp, controlplanev1.ISOPropagatedCondition)
+		return nil
+	}
+	if acp.Status.BootArtifacts == nil || acp.Status.BootArtifacts.ISOURL == "" {
+		conditions.MarkFalse(acp, controlplanev1.ISOPropagatedCondition, controlplanev1.WaitingForBootArtifactsReason,
+			clusterv1.ConditionSeverityInfo, "Waiting for the discovery ISO URL")
+		return nil
+	}
+	isoURL := acp.Status.BootArtifacts.ISOURL
+
+	url, _, _ := unstructured.NestedString(template.Object, "spec", "template", "spec", "image", "url")
+	format, _, _ := unstructured.NestedString(template.Object, "spec", "template", "spec", "image", "diskFormat")
+	if url == isoURL && format == liveISODiskFormat {
+		conditions.MarkTrue(acp, controlplanev1.ISOPropagatedCondition)
+		return nil
+	}
+
+	patch := client.MergeFrom(template.DeepCopy())
+	for field, value := range map[string]string{"url": isoURL, "diskFormat": liveISODiskFormat} {
+		if err := unstructured.SetNestedField(template.Object, value, "spec", "template", "spec", "image", field); err != nil {
+			return fmt.Errorf("setting the image %s of %s %s: %w", field, template.GetKind(), template.GetName(), err)
+		}
+	}
+	if err := r.Patch(ctx, template, patch); err != nil {
+		conditions.MarkFalse(acp, controlplanev1.ISOPropagatedCondition, controlplanev1.ISOPropagationFailedReason,
+			clusterv1.ConditionSeverityWarning, "Patching %s %s/%s: %v", template.GetKind(), template.GetNamespace(), template.GetName(), err)
+		return fmt.Errorf("propagating the discovery ISO to %s %s: %w", template.GetKind(), template.GetName(), err)
+	}
+	conditions.MarkTrue(acp, controlplanev1.ISOPropagatedCondition)
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Discovery ISO propagation", func() {
+	ctx := context.Background()
+
+	const isoURL = "https://assisted.example.com/images/test-acp.iso"
+
+	var (
+		acp      *controlplanev1.AgentControlPlane
+		template *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+		acp.Status.BootArtifacts = &controlplanev1.BootArtifacts{ISOURL: isoURL}
+		template = &unstructured.Unstructured{}
+		template.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1beta1")
+		template.SetKind(metal3MachineTemplateKind)
+		template.SetNamespace(testNamespace)
+		template.SetName("control-plane")
+		Expect(unstructured.SetNestedField(template.Object, "md5", "spec", "template", "spec", "image", "checksumType")).To(Succeed())
+	})
+
+	It("points the infrastructure template at the discovery ISO", func() {
+		c := newFakeClient(newTestScheme(), template.DeepCopy())
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+
+		Expect(r.propagateISO(ctx, acp, template)).To(Succeed())
+
+		condition := conditions.Get(acp, controlplanev1.ISOPropagatedCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+
+		patched := &unstructured.Unstructured{}
+		patched.SetGroupVersionKind(template.GroupVersionKind())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(template), patched)).To(Succeed())
+		image, _, _ := unstructured.NestedStringMap(patched.Object, "spec", "template", "spec", "image")
+		Expect(image).To(Equal(map[string]string{"url": isoURL, "diskFormat": liveISODiskFormat, "checksumType": "md5"}))
+	})
+
+	It("reports a failed patch", func() {
+		c := newFakeClientBuilder(newTestScheme(), template.DeepCopy()).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					return errors.New("admission webhook denied the request")
+				},
+			}).
+			Build()
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+
+		Expect(r.propagateISO(ctx, acp, template)).To(MatchError(ContainSubstring("admission webhook denied the request")))
+
+		condition := conditions.Get(acp, controlplanev1.ISOPropagatedCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.ISOPropagationFailedReason))
+		Expect(condition.Message).To(ContainSubstring("admission webhook denied the request"))
+	})
+
+	It("waits for the discovery ISO URL", func() {
+		acp.Status.BootArtifacts = nil
+		r := &AgentControlPlaneReconciler{}
+
+		Expect(r.propagateISO(ctx, acp, template)).To(Succeed())
+
+		Expect(conditions.GetReason(acp, controlplanev1.ISOPropagatedCondition)).To(Equal(controlplanev1.WaitingForBootArtifactsReason))
+	})
+
+	It("leaves the other infrastructure templates alone", func() {
+		template.SetKind("AWSMachineTemplate")
+		conditions.MarkTrue(acp, controlplanev1.ISOPropagatedCondition)
+		r := &AgentControlPlaneReconciler{}
+
+		Expect(r.propagateISO(ctx, acp, template)).To(Succeed())
+
+		Expect(conditions.Has(acp, controlplanev1.ISOPropagatedCondition)).To(BeFalse())
+	})
+})
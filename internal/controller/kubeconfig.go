@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/cluster-api/util/secret"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// adminKubeconfigKey is the key of the admin kubeconfig in the Secret
+// referenced by the ClusterDeployment's cluster metadata.
+const adminKubeconfigKey = "kubeconfig"
+
+// reconcileKubeconfig copies the admin kubeconfig of the installed control
+// plane, as published by hive in the ClusterDeployment's cluster metadata,
+// into the <cluster>-kubeconfig Secret read by Cluster API. The Secret is
+// controlled by acp. Until the install produced the kubeconfig, which happens
+// no earlier than the control plane is initialized, the
+// KubeconfigAvailableCondition is false. Once written, the Secret is only
+// rewritten when the RotateKubeconfigAnnotation changes; the admin
+// kubeconfig is then read from the API server rather than the cache, so the
+// rotation gets the current credentials.
+func (r *AgentControlPlaneReconciler) reconcileKubeconfig(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) error {
+	log := log.FromContext(ctx)
+
+	waiting := func(messageFormat string, args ...interface{}) error {
+		conditions.MarkFalse(acp, controlplanev1.KubeconfigAvailableCondition, controlplanev1.WaitingForKubeconfigReason,
+			clusterv1.ConditionSeverityInfo, messageFormat, args...)
+		return nil
+	}
+	if !acp.Status.Initialized {
+		return waiting("Waiting for the install to complete")
+	}
+
+	requested, rotationRequested := acp.Annotations[controlplanev1.RotateKubeconfigAnnotation]
+	rotate := rotationRequested && requested != acp.Status.KubeconfigRotation
+
+	existing := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: secret.Name(cluster.Name, secret.Kubeconfig)}, existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		existing = nil
+	case err != nil:
+		return err
+	default:
+		if err := checkNotControlledByOther(acp, existing); err != nil {
+			return err
+		}
+		if !rotate {
+			conditions.MarkTrue(acp, controlplanev1.KubeconfigAvailableCondition)
+			return nil
+		}
+	}
+
+	// A pending rotation does not make the current kubeconfig unusable.
+	unavailable := func(messageFormat string, args ...interface{}) error {
+		if existing != nil {
+			log.Info("cannot rotate the kubeconfig yet", "reason", fmt.Sprintf(messageFormat, args...))
+			return nil
+		}
+		return waiting(messageFormat, args...)
+	}
+
+	clusterDeployment, err := r.ownedClusterDeployment(ctx, acp)
+	if err != nil {
+		return err
+	}
+	if clusterDeployment == nil || clusterDeployment.Spec.ClusterMetadata == nil ||
+		clusterDeployment.Spec.ClusterMetadata.AdminKubeconfigSecretRef.Name == "" {
+		return unavailable("Waiting for the install to publish the admin kubeconfig")
+	}
+
+	sourceKey := client.ObjectKey{Namespace: acp.Namespace, Name: clusterDeployment.Spec.ClusterMetadata.AdminKubeconfigSecretRef.Name}
+	source := &corev1.Secret{}
+	err = r.apiReader().Get(ctx, sourceKey, source)
+	switch {
+	case apierrors.IsNotFound(err):
+		return unavailable("Secret %s not found", sourceKey.Name)
+	case err != nil:
+		return err
+	}
+	data, ok := source.Data[adminKubeconfigKey]
+	if !ok {
+		return unavailable("Secret %s has no %s key", sourceKey.Name, adminKubeconfigKey)
+	}
+
+	desired := kubeconfig.GenerateSecretWithOwner(util.ObjectKey(cluster), data, metav1.OwnerReference{
+		APIVersion:         controlplanev1.GroupVersion.String(),
+		Kind:               "AgentControlPlane",
+		Name:               acp.Name,
+		UID:                acp.UID,
+		Controller:         ptr.To(true),
+		BlockOwnerDeletion: ptr.To(true),
+	})
+	if err := r.applyObject(ctx, desired, &corev1.Secret{}, nil); err != nil {
+		return err
+	}
+	// Writing the Secret afresh satisfies any pending rotation.
+	if rotationRequested {
+		if rotate && existing != nil {
+			log.Info("rotated the kubeconfig on request", "value", requested)
+		}
+		acp.Status.KubeconfigRotation = requested
+	}
+	conditions.MarkTrue(acp, controlplanev1.KubeconfigAvailableCondition)
+	return nil
+}
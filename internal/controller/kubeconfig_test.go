@@ -0,0 +1,154 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+var _ = Describe("Kubeconfig reconciliation", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, cluster)
+	})
+
+	reconcileACP := func(c client.Client) *controlplanev1.AgentControlPlane {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return updated
+	}
+
+	It("writes the kubeconfig Secret once the install published the admin kubeconfig", func() {
+		agentClusterInstall := &hiveext.AgentClusterInstall{
+			ObjectMeta: acp.ObjectMeta,
+			Spec: hiveext.AgentClusterInstallSpec{
+				ClusterDeploymentRef: corev1.LocalObjectReference{Name: acp.Name},
+			},
+			Status: hiveext.AgentClusterInstallStatus{Conditions: []hivev1.ClusterInstallCondition{
+				{Type: hiveext.ClusterCompletedCondition, Status: corev1.ConditionTrue},
+			}},
+		}
+		c := newFakeClient(newTestScheme(), acp, cluster, agentClusterInstall)
+
+		updated := reconcileACP(c)
+		Expect(updated.Status.Initialized).To(BeTrue())
+		condition := conditions.Get(updated, controlplanev1.KubeconfigAvailableCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.WaitingForKubeconfigReason))
+
+		clusterDeployment := &hivev1.ClusterDeployment{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), clusterDeployment)).To(Succeed())
+		clusterDeployment.Spec.ClusterMetadata = &hivev1.ClusterMetadata{
+			AdminKubeconfigSecretRef: corev1.LocalObjectReference{Name: "test-acp-admin-kubeconfig"},
+		}
+		Expect(c.Update(ctx, clusterDeployment)).To(Succeed())
+		Expect(conditions.Get(reconcileACP(c), controlplanev1.KubeconfigAvailableCondition).Message).
+			To(Equal("Secret test-acp-admin-kubeconfig not found"))
+
+		Expect(c.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-acp-admin-kubeconfig", Namespace: testNamespace},
+			Data:       map[string][]byte{"kubeconfig": []byte("apiVersion: v1\nkind: Config\n")},
+		})).To(Succeed())
+		Expect(conditions.IsTrue(reconcileACP(c), controlplanev1.KubeconfigAvailableCondition)).To(BeTrue())
+
+		kubeconfig := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "test-cluster-kubeconfig"}, kubeconfig)).To(Succeed())
+		Expect(kubeconfig.Type).To(Equal(clusterv1.ClusterSecretType))
+		Expect(kubeconfig.Labels).To(HaveKeyWithValue(clusterv1.ClusterNameLabel, cluster.Name))
+		Expect(kubeconfig.Data).To(HaveKeyWithValue("value", []byte("apiVersion: v1\nkind: Config\n")))
+		Expect(metav1.GetControllerOf(kubeconfig).UID).To(Equal(acp.UID))
+	})
+
+	It("waits for the install before looking for the kubeconfig", func() {
+		condition := conditions.Get(reconcileACP(newFakeClient(newTestScheme(), acp, cluster)), controlplanev1.KubeconfigAvailableCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Message).To(Equal("Waiting for the install to complete"))
+	})
+	It("rewrites the kubeconfig Secret when a rotation is requested", func() {
+		clusterDeployment := &hivev1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: acp.Name, Namespace: testNamespace},
+			Spec: hivev1.ClusterDeploymentSpec{
+				Installed: true,
+				ClusterMetadata: &hivev1.ClusterMetadata{
+					AdminKubeconfigSecretRef: corev1.LocalObjectReference{Name: "test-acp-admin-kubeconfig"},
+				},
+			},
+		}
+		Expect(controllerutil.SetControllerReference(acp, clusterDeployment, newTestScheme())).To(Succeed())
+		adminKubeconfig := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-acp-admin-kubeconfig", Namespace: testNamespace},
+			Data:       map[string][]byte{"kubeconfig": []byte("first")},
+		}
+		c := newFakeClient(newTestScheme(), acp, cluster, clusterDeployment, adminKubeconfig)
+		kubeconfigData := func() string {
+			kubeconfig := &corev1.Secret{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "test-cluster-kubeconfig"}, kubeconfig)).To(Succeed())
+			return string(kubeconfig.Data["value"])
+		}
+
+		reconcileACP(c)
+		Expect(kubeconfigData()).To(Equal("first"))
+
+		// New credentials are only picked up on request.
+		adminKubeconfig.Data["kubeconfig"] = []byte("second")
+		Expect(c.Update(ctx, adminKubeconfig)).To(Succeed())
+		reconcileACP(c)
+		Expect(kubeconfigData()).To(Equal("first"))
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		updated.Annotations = map[string]string{controlplanev1.RotateKubeconfigAnnotation: "1"}
+		Expect(c.Update(ctx, updated)).To(Succeed())
+		updated = reconcileACP(c)
+		Expect(kubeconfigData()).To(Equal("second"))
+		Expect(updated.Status.KubeconfigRotation).To(Equal("1"))
+		Expect(conditions.IsTrue(updated, controlplanev1.KubeconfigAvailableCondition)).To(BeTrue())
+
+		// The handled rotation is not repeated.
+		adminKubeconfig.Data["kubeconfig"] = []byte("third")
+		Expect(c.Update(ctx, adminKubeconfig)).To(Succeed())
+		reconcileACP(c)
+		Expect(kubeconfigData()).To(Equal("second"))
+	})
+})
@@ -0,0 +1,501 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+	acputil "github.com/openshift-assisted/agent-controlplane-provider/pkg/acp"
+)
+
+// desiredReplicas returns the number of control plane machines requested by
+// acp, defaulting to one.
+func desiredReplicas(acp *controlplanev1.AgentControlPlane) int32 {
+	return acputil.EffectiveReplicas(acp)
+}
+
+// controlledBy returns a filter matching Machines whose controller reference
+// points at owner. Unlike collections.OwnedMachines it compares UIDs, so it
+// does not depend on the owner's TypeMeta being populated.
+func controlledBy(owner metav1.Object) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		return metav1.IsControlledBy(machine, owner)
+	}
+}
+
+// namedFor returns a filter matching Machines whose control plane name label
+// is unset or names acp, so Machines created for another control plane are
+// left alone.
+func namedFor(acp *controlplanev1.AgentControlPlane) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		name, ok := machine.Labels[clusterv1.MachineControlPlaneNameLabel]
+		return !ok || name == acp.Name
+	}
+}
+
+// createdFor returns a filter matching the Machines labeled with the name of
+// acp, as the Machines it creates are. Unlike namedFor, it does not match
+// Machines without the label.
+func createdFor(acp *controlplanev1.AgentControlPlane) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		return machine.Labels[clusterv1.MachineControlPlaneNameLabel] == acp.Name
+	}
+}
+
+// adoptableBy returns a filter matching the Machines created for acp by an
+// earlier AgentControlPlane of the same name: those labeled with its name
+// that have no controller, or whose controller is an AgentControlPlane of
+// that name but another UID.
+func adoptableBy(acp *controlplanev1.AgentControlPlane) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		if machine.Labels[clusterv1.MachineControlPlaneNameLabel] != acp.Name {
+			return false
+		}
+		ref := metav1.GetControllerOf(machine)
+		if ref == nil {
+			return true
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		return err == nil && gv.Group == controlplanev1.GroupVersion.Group &&
+			ref.Kind == "AgentControlPlane" && ref.Name == acp.Name && ref.UID != acp.UID
+	}
+}
+
+// unmanaged is a filter matching Machines carrying the
+// UnmanagedMachineAnnotation, which the controller must not delete.
+func unmanaged(machine *clusterv1.Machine) bool {
+	_, ok := machine.Annotations[controlplanev1.UnmanagedMachineAnnotation]
+	return ok
+}
+
+// managesMachines returns whether the controller manages the control plane
+// Machines of acp, following spec.manageMachines.
+func managesMachines(acp *controlplanev1.AgentControlPlane) bool {
+	return ptr.Deref(acp.Spec.ManageMachines, true)
+}
+
+// reconcileMachines creates control plane Machines until the desired replica
+// count is reached. With spec.manageMachines false, it only reports the
+// control plane Machines created by other tooling. Each Machine gets its own
+// infrastructure object cloned from spec.machineTemplate.infrastructureRef;
+// no Machine is created while the template is missing or being deleted, as
+// reported on the InfrastructureReady condition. Machines are not created
+// before the owner Cluster's infrastructure is ready either, nor beyond the
+// number of Agents available to back them; the returned result requeues to
+// check again.
+func (r *AgentControlPlaneReconciler) reconcileMachines(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) (ctrl.Result, error) {
+	if !managesMachines(acp) {
+		conditions.Delete(acp, controlplanev1.MachinesCreatedCondition)
+		machines, err := collections.GetFilteredMachinesForCluster(ctx, r.Client, cluster,
+			collections.ActiveMachines, collections.ControlPlaneMachines(cluster.Name), namedFor(acp))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		acp.Status.Replicas = int32(machines.Len())
+		updateVersionSkew(acp, machines)
+		return ctrl.Result{}, nil
+	}
+	if acp.Spec.MachineTemplate == nil {
+		return ctrl.Result{}, nil
+	}
+	log := log.FromContext(ctx)
+
+	if !cluster.Status.InfrastructureReady {
+		log.Info("waiting for the Cluster infrastructure to be ready", "requeueAfter", infrastructureRequeueInterval)
+		conditions.MarkFalse(acp, controlplanev1.MachinesCreatedCondition,
+			controlplanev1.WaitingForClusterInfrastructureReason, clusterv1.ConditionSeverityInfo,
+			"Waiting for the infrastructure of Cluster %s to be ready", cluster.Name)
+		return ctrl.Result{RequeueAfter: infrastructureRequeueInterval}, nil
+	}
+
+	templateRef := &acp.Spec.MachineTemplate.InfrastructureRef
+	template, err := external.Get(ctx, r.Client, templateRef, acp.Namespace)
+	switch {
+	case apierrors.IsNotFound(err):
+		conditions.MarkFalse(acp, controlplanev1.InfrastructureReadyCondition,
+			controlplanev1.InfrastructureTemplateNotFoundReason, clusterv1.ConditionSeverityError,
+			"%s %s/%s not found", templateRef.Kind, acp.Namespace, templateRef.Name)
+		return ctrl.Result{}, nil
+	case err != nil:
+		return ctrl.Result{}, err
+	case !template.GetDeletionTimestamp().IsZero():
+		conditions.MarkFalse(acp, controlplanev1.InfrastructureReadyCondition,
+			controlplanev1.InfrastructureTemplateDeletingReason, clusterv1.ConditionSeverityError,
+			"%s %s/%s is being deleted", templateRef.Kind, acp.Namespace, templateRef.Name)
+		return ctrl.Result{}, nil
+	}
+	conditions.MarkTrue(acp, controlplanev1.InfrastructureReadyCondition)
+
+	if err := r.propagateISO(ctx, acp, template); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.adoptMachines(ctx, acp, cluster); err != nil {
+		return ctrl.Result{}, fmt.Errorf("adopting control plane Machines: %w", err)
+	}
+	machines, err := collections.GetFilteredMachinesForCluster(ctx, r.Client, cluster,
+		collections.ActiveMachines, collections.ControlPlaneMachines(cluster.Name), controlledBy(acp))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	updateVersionSkew(acp, machines)
+
+	agents, err := r.availableAgents(ctx, acp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	available := len(agents)
+	desired := int(desiredReplicas(acp))
+	// A single Machine is created per reconcile, so that the next one is
+	// placed knowing where the previous one landed. Creating it enqueues the
+	// next reconcile through the Machine watch.
+	if machines.Len() < min(desired, available) {
+		domain := nextFailureDomain(failureDomains(acp, cluster), machines, agents)
+		machine, err := r.createMachine(ctx, acp, cluster, template, domain)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		machines.Insert(machine)
+		log.Info("created control plane Machine", "machine", client.ObjectKeyFromObject(machine),
+			"failureDomain", domain)
+	}
+	acp.Status.Replicas = int32(machines.Len())
+	if machines.Len() < min(desired, available) {
+		conditions.MarkFalse(acp, controlplanev1.MachinesCreatedCondition,
+			controlplanev1.CreatingMachinesReason, clusterv1.ConditionSeverityInfo,
+			"%d of %d control plane Machines created", machines.Len(), desired)
+		return ctrl.Result{}, nil
+	}
+	if available < desired && machines.Len() < desired {
+		log.Info("waiting for Agents to back the remaining Machines",
+			"available", available, "desired", desired, "requeueAfter", agentRequeueInterval)
+		conditions.MarkFalse(acp, controlplanev1.MachinesCreatedCondition,
+			controlplanev1.WaitingForAgentsReason, clusterv1.ConditionSeverityInfo,
+			"%d of %d Agents available", available, desired)
+		return ctrl.Result{RequeueAfter: agentRequeueInterval}, nil
+	}
+	conditions.MarkTrue(acp, controlplanev1.MachinesCreatedCondition)
+	return ctrl.Result{}, nil
+}
+
+// adoptMachines makes acp the controller of the control plane Machines of
+// cluster it can adopt, e.g. those left behind when a previous
+// AgentControlPlane of the same name was deleted with orphaning, so that
+// they count towards its replicas rather than being created again.
+func (r *AgentControlPlaneReconciler) adoptMachines(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) error {
+	machines, err := collections.GetFilteredMachinesForCluster(ctx, r.Client, cluster,
+		collections.ActiveMachines, collections.ControlPlaneMachines(cluster.Name), adoptableBy(acp))
+	if err != nil {
+		return err
+	}
+
+	for _, machine := range machines.SortedByCreationTimestamp() {
+		patch := client.MergeFrom(machine.DeepCopy())
+		machine.OwnerReferences = slices.DeleteFunc(machine.OwnerReferences, func(ref metav1.OwnerReference) bool {
+			return ptr.Deref(ref.Controller, false)
+		})
+		machine.OwnerReferences = append(machine.OwnerReferences,
+			*metav1.NewControllerRef(acp, controlplanev1.GroupVersion.WithKind("AgentControlPlane")))
+		log.FromContext(ctx).Info("adopting control plane Machine", "machine", client.ObjectKeyFromObject(machine))
+		if err := r.Patch(ctx, machine, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateVersionSkew reports the range of versions machines run in
+// status.versionSkew and on the MachineVersionsConvergedCondition. Machines
+// with no version, or one that cannot be parsed, are left out; the condition
+// is removed when no Machine is left.
+func updateVersionSkew(acp *controlplanev1.AgentControlPlane, machines collections.Machines) {
+	var oldest, newest string
+	var oldestVersion, newestVersion *version.Version
+	for _, machine := range machines {
+		if machine.Spec.Version == nil {
+			continue
+		}
+		v, err := version.ParseGeneric(*machine.Spec.Version)
+		if err != nil {
+			continue
+		}
+		if oldestVersion == nil || v.LessThan(oldestVersion) {
+			oldest, oldestVersion = *machine.Spec.Version, v
+		}
+		if newestVersion == nil || newestVersion.LessThan(v) {
+			newest, newestVersion = *machine.Spec.Version, v
+		}
+	}
+
+	switch {
+	case oldestVersion == nil:
+		acp.Status.VersionSkew = nil
+		conditions.Delete(acp, controlplanev1.MachineVersionsConvergedCondition)
+	case oldestVersion.LessThan(newestVersion):
+		acp.Status.VersionSkew = &controlplanev1.VersionSkew{MinVersion: oldest, MaxVersion: newest}
+		conditions.MarkFalse(acp, controlplanev1.MachineVersionsConvergedCondition, controlplanev1.VersionSkewReason,
+			clusterv1.ConditionSeverityInfo, "Control plane Machines run versions %s to %s", oldest, newest)
+	default:
+		acp.Status.VersionSkew = nil
+		conditions.MarkTrue(acp, controlplanev1.MachineVersionsConvergedCondition)
+	}
+}
+
+// machineFromTemplate returns the control plane Machine named name that acp
+// asks for in cluster, as described by spec.machineTemplate. Its
+// infrastructure object is the one cloned from the template under the same
+// name. It has no side effects, so creations and rollouts get the same
+// Machine for the same spec.
+func machineFromTemplate(acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster, name string) *clusterv1.Machine {
+	template := acp.Spec.MachineTemplate
+	labels := map[string]string{}
+	for key, value := range template.HealthCheckLabels {
+		labels[key] = value
+	}
+	labels[clusterv1.ClusterNameLabel] = cluster.Name
+	labels[clusterv1.MachineControlPlaneLabel] = ""
+	labels[clusterv1.MachineControlPlaneNameLabel] = acp.Name
+
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: acp.Namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(acp, controlplanev1.GroupVersion.WithKind("AgentControlPlane")),
+			},
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: cluster.Name,
+			InfrastructureRef: corev1.ObjectReference{
+				APIVersion: template.InfrastructureRef.APIVersion,
+				Kind:       strings.TrimSuffix(template.InfrastructureRef.Kind, clusterv1.TemplateSuffix),
+				Namespace:  acp.Namespace,
+				Name:       name,
+			},
+			// Hosts boot the discovery image rather than bootstrap data.
+			Bootstrap:               clusterv1.Bootstrap{DataSecretName: ptr.To("")},
+			NodeDrainTimeout:        template.NodeDrainTimeout,
+			NodeVolumeDetachTimeout: template.NodeVolumeDetachTimeout,
+			NodeDeletionTimeout:     template.NodeDeletionTimeout,
+		},
+	}
+	if acp.Spec.Version != "" {
+		machine.Spec.Version = ptr.To(acp.Spec.Version)
+	}
+	return machine
+}
+
+// failureDomains returns the failure domains to spread the control plane
+// Machines of acp across: those of its Machine template, or else the
+// Cluster's failure domains suitable for the control plane, sorted by name.
+func failureDomains(acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) []string {
+	if domains := acp.Spec.MachineTemplate.FailureDomains; len(domains) > 0 {
+		return domains
+	}
+	var domains []string
+	for name, domain := range cluster.Status.FailureDomains {
+		if domain.ControlPlane {
+			domains = append(domains, name)
+		}
+	}
+	slices.Sort(domains)
+	return domains
+}
+
+// nextFailureDomain returns the one of domains to place the next control
+// plane Machine in, or "" when there are no domains. Domains with more of
+// agents labeled with their zone than machines are preferred, so that the
+// Machine can be backed by a host there; among them, or among all domains
+// when none has such spare Agents, it is the one holding the fewest of
+// machines, the first one on ties.
+func nextFailureDomain(domains []string, machines collections.Machines, agents []aiv1beta1.Agent) string {
+	counts := make(map[string]int, len(domains))
+	for _, machine := range machines {
+		if machine.Spec.FailureDomain != nil {
+			counts[*machine.Spec.FailureDomain]++
+		}
+	}
+	spare := make(map[string]int, len(domains))
+	for i := range agents {
+		if zone, ok := agents[i].Labels[corev1.LabelTopologyZone]; ok {
+			spare[zone]++
+		}
+	}
+	candidates := slices.DeleteFunc(slices.Clone(domains), func(domain string) bool {
+		return spare[domain] <= counts[domain]
+	})
+	if len(candidates) == 0 {
+		candidates = domains
+	}
+
+	var next string
+	for _, domain := range candidates {
+		if next == "" || counts[domain] < counts[next] {
+			next = domain
+		}
+	}
+	return next
+}
+
+// createMachine creates a control plane Machine in failureDomain, if any, and
+// the infrastructure object backing it, cloned from template. The Machine is
+// server-side applied, so the fields later set by the Machine controller have
+// their own owner. The infrastructure object is deleted again if the Machine
+// cannot be created.
+func (r *AgentControlPlaneReconciler) createMachine(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster, template *unstructured.Unstructured, failureDomain string) (*clusterv1.Machine, error) {
+	machine := machineFromTemplate(acp, cluster, acp.Name+"-"+utilrand.String(5))
+	if failureDomain != "" {
+		machine.Spec.FailureDomain = ptr.To(failureDomain)
+	}
+
+	// The Machine controller takes over as controller of the infrastructure
+	// object once the Machine references it.
+	infraMachine, err := external.GenerateTemplate(&external.GenerateTemplateInput{
+		Template:    template,
+		TemplateRef: &acp.Spec.MachineTemplate.InfrastructureRef,
+		Namespace:   acp.Namespace,
+		Name:        machine.Name,
+		ClusterName: cluster.Name,
+		OwnerRef: &metav1.OwnerReference{
+			APIVersion: controlplanev1.GroupVersion.String(),
+			Kind:       "AgentControlPlane",
+			Name:       acp.Name,
+			UID:        acp.UID,
+		},
+		Labels: machine.Labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Create(ctx, infraMachine); err != nil {
+		return nil, err
+	}
+
+	config, err := r.applyConfiguration(machine)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.apply(ctx, config, machine); err != nil {
+		if deleteErr := r.Delete(ctx, infraMachine); deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+			return nil, kerrors.NewAggregate([]error{err, deleteErr})
+		}
+		return nil, err
+	}
+	return machine, nil
+}
+
+// reconcileUnexpectedMachines reports on the MachinesExpectedCondition the
+// control plane Machines of the Cluster that acp does not control, e.g. ones
+// created by hand with the control plane label, when they bring the control
+// plane Machines beyond the desired replicas. They are not deleted here;
+// stale Machine garbage collection, when enabled, only removes those labeled
+// with the name of acp, as the Machines it created are.
+func (r *AgentControlPlaneReconciler) reconcileUnexpectedMachines(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) error {
+	// Other tooling creating the Machines is expected then.
+	if !managesMachines(acp) {
+		conditions.Delete(acp, controlplanev1.MachinesExpectedCondition)
+		return nil
+	}
+	machines, err := collections.GetFilteredMachinesForCluster(ctx, r.Client, cluster,
+		collections.ActiveMachines, collections.ControlPlaneMachines(cluster.Name), namedFor(acp))
+	if err != nil {
+		return err
+	}
+
+	unexpected := machines.Filter(collections.Not(controlledBy(acp)))
+	if desired := int(desiredReplicas(acp)); unexpected.Len() > 0 && machines.Len() > desired {
+		names := unexpected.Names()
+		slices.Sort(names)
+		log.FromContext(ctx).Info("found control plane Machines not created by the controller", "machines", names)
+		conditions.MarkFalse(acp, controlplanev1.MachinesExpectedCondition,
+			controlplanev1.UnexpectedMachinesReason, clusterv1.ConditionSeverityWarning,
+			"%d control plane Machines exist for %d replicas; %s were not created by this controller and are left alone",
+			machines.Len(), desired, strings.Join(names, ", "))
+		return nil
+	}
+	conditions.MarkTrue(acp, controlplanev1.MachinesExpectedCondition)
+	return nil
+}
+
+// reconcileStaleMachines deletes Machines left behind by earlier rollouts:
+// Machines owned by acp that lost the control plane label, and control plane
+// Machines that no longer have a controller but are labeled with the name of
+// acp, as are the Machines it creates, when reconcileMachines did not adopt
+// them. Control plane Machines without that label may have been created by
+// other tooling: they are only reported by reconcileUnexpectedMachines. Stale
+// Machines may still host etcd members, so they are only removed once the
+// current Machines alone satisfy the desired replica count. Unmanaged
+// Machines are never removed.
+func (r *AgentControlPlaneReconciler) reconcileStaleMachines(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) error {
+	log := log.FromContext(ctx)
+
+	machines, err := collections.GetFilteredMachinesForCluster(ctx, r.Client, cluster, collections.ActiveMachines)
+	if err != nil {
+		return err
+	}
+
+	isControlPlane := collections.ControlPlaneMachines(cluster.Name)
+	current := machines.Filter(isControlPlane, controlledBy(acp))
+	stale := machines.AnyFilter(
+		collections.And(controlledBy(acp), collections.Not(isControlPlane)),
+		collections.And(isControlPlane, collections.Not(collections.HasControllerRef), createdFor(acp)),
+	)
+	if kept := stale.Filter(unmanaged); kept.Len() > 0 {
+		log.Info("keeping unmanaged stale Machines", "machines", kept.Names())
+		stale = stale.Filter(collections.Not(unmanaged))
+	}
+	if stale.Len() == 0 {
+		return nil
+	}
+
+	if desired := int(desiredReplicas(acp)); current.Len() < desired {
+		log.Info("keeping stale Machines until the desired replicas are available",
+			"stale", stale.Names(), "current", current.Len(), "desired", desired)
+		return nil
+	}
+
+	for _, machine := range stale.SortedByCreationTimestamp() {
+		log.Info("deleting stale Machine", "machine", client.ObjectKeyFromObject(machine))
+		if err := r.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
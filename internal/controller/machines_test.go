@@ -0,0 +1,576 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Stale Machine garbage collection", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.Replicas = ptr.To[int32](1)
+		setOwnerCluster(acp, cluster)
+	})
+
+	reconcileACP := func(c client.Client, gc bool) {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), GarbageCollectStaleMachines: gc}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// newOrphanedMachine returns a control plane Machine created for acp that
+	// no longer has a controller.
+	newOrphanedMachine := func(name string) *clusterv1.Machine {
+		machine := newControlPlaneMachine(name, cluster, acp)
+		machine.OwnerReferences = nil
+		return machine
+	}
+
+	exists := func(c client.Client, machine *clusterv1.Machine) bool {
+		err := c.Get(ctx, client.ObjectKeyFromObject(machine), &clusterv1.Machine{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	It("deletes an orphaned control plane Machine once the desired replicas exist", func() {
+		current := newControlPlaneMachine("current", cluster, acp)
+		orphaned := newOrphanedMachine("orphaned")
+		c := newFakeClient(newTestScheme(), acp, cluster, current, orphaned)
+
+		reconcileACP(c, true)
+
+		Expect(exists(c, current)).To(BeTrue())
+		Expect(exists(c, orphaned)).To(BeFalse())
+	})
+
+	It("deletes an owned Machine that lost the control plane label", func() {
+		current := newControlPlaneMachine("current", cluster, acp)
+		unlabeled := newControlPlaneMachine("unlabeled", cluster, acp)
+		delete(unlabeled.Labels, clusterv1.MachineControlPlaneLabel)
+		c := newFakeClient(newTestScheme(), acp, cluster, current, unlabeled)
+
+		reconcileACP(c, true)
+
+		Expect(exists(c, current)).To(BeTrue())
+		Expect(exists(c, unlabeled)).To(BeFalse())
+	})
+
+	It("keeps stale Machines while the current Machines are below the desired replicas", func() {
+		acp.Spec.Replicas = ptr.To[int32](3)
+		current := newControlPlaneMachine("current", cluster, acp)
+		orphaned := newOrphanedMachine("orphaned")
+		c := newFakeClient(newTestScheme(), acp, cluster, current, orphaned)
+
+		reconcileACP(c, true)
+
+		Expect(exists(c, orphaned)).To(BeTrue())
+	})
+
+	It("leaves stale Machines alone when garbage collection is disabled", func() {
+		current := newControlPlaneMachine("current", cluster, acp)
+		orphaned := newOrphanedMachine("orphaned")
+		c := newFakeClient(newTestScheme(), acp, cluster, current, orphaned)
+
+		reconcileACP(c, false)
+
+		Expect(exists(c, orphaned)).To(BeTrue())
+	})
+
+	DescribeTable("warns about labeled Machines it did not create beyond the desired replicas without deleting them", func(gc bool) {
+		current := newControlPlaneMachine("current", cluster, acp)
+		extra := newControlPlaneMachine("extra", cluster, nil)
+		c := newFakeClient(newTestScheme(), acp, cluster, current, extra)
+
+		reconcileACP(c, gc)
+
+		Expect(exists(c, current)).To(BeTrue())
+		Expect(exists(c, extra)).To(BeTrue())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), acp)).To(Succeed())
+		condition := conditions.Get(acp, controlplanev1.MachinesExpectedCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.UnexpectedMachinesReason))
+		Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityWarning))
+		Expect(condition.Message).To(ContainSubstring("2 control plane Machines exist for 1 replicas; extra were not created"))
+	},
+		Entry("without garbage collection", false),
+		Entry("with garbage collection", true),
+	)
+
+	It("does not warn while the control plane Machines do not exceed the desired replicas", func() {
+		acp.Spec.Replicas = ptr.To[int32](3)
+		current := newControlPlaneMachine("current", cluster, acp)
+		extra := newControlPlaneMachine("extra", cluster, nil)
+		c := newFakeClient(newTestScheme(), acp, cluster, current, extra)
+
+		reconcileACP(c, false)
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), acp)).To(Succeed())
+		Expect(conditions.IsTrue(acp, controlplanev1.MachinesExpectedCondition)).To(BeTrue())
+	})
+
+	It("never deletes an unmanaged Machine", func() {
+		current := newControlPlaneMachine("current", cluster, acp)
+		orphaned := newOrphanedMachine("orphaned")
+		orphaned.Annotations = map[string]string{controlplanev1.UnmanagedMachineAnnotation: ""}
+		unlabeled := newControlPlaneMachine("unlabeled", cluster, acp)
+		unlabeled.Annotations = map[string]string{controlplanev1.UnmanagedMachineAnnotation: ""}
+		delete(unlabeled.Labels, clusterv1.MachineControlPlaneLabel)
+		c := newFakeClient(newTestScheme(), acp, cluster, current, orphaned, unlabeled)
+
+		reconcileACP(c, true)
+
+		Expect(exists(c, current)).To(BeTrue())
+		Expect(exists(c, orphaned)).To(BeTrue())
+		Expect(exists(c, unlabeled)).To(BeTrue())
+	})
+
+	It("keeps orphaned Machines created for another control plane", func() {
+		current := newControlPlaneMachine("current", cluster, acp)
+		foreign := newControlPlaneMachine("foreign", cluster, nil)
+		foreign.Labels[clusterv1.MachineControlPlaneNameLabel] = "other-acp"
+		c := newFakeClient(newTestScheme(), acp, cluster, current, foreign)
+
+		reconcileACP(c, true)
+
+		Expect(exists(c, foreign)).To(BeTrue())
+	})
+
+	It("does not touch Machines belonging to another cluster", func() {
+		other := newCluster("other-cluster")
+		foreign := newControlPlaneMachine("foreign", other, nil)
+		current := newControlPlaneMachine("current", cluster, acp)
+		c := newFakeClient(newTestScheme(), acp, cluster, current, foreign)
+
+		reconcileACP(c, true)
+
+		Expect(exists(c, foreign)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Control plane Machine creation", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		cluster.Status.InfrastructureReady = true
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.Replicas = ptr.To[int32](3)
+		acp.Spec.MachineTemplate = &controlplanev1.AgentControlPlaneMachineTemplate{
+			InfrastructureRef: corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+				Kind:       "Metal3MachineTemplate",
+				Name:       "control-plane",
+			},
+		}
+		setOwnerCluster(acp, cluster)
+	})
+
+	reconcileACP := func(c client.Client) *controlplanev1.AgentControlPlane {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return updated
+	}
+
+	listMachines := func(c client.Client) []clusterv1.Machine {
+		machines := &clusterv1.MachineList{}
+		Expect(c.List(ctx, machines, client.InNamespace(testNamespace))).To(Succeed())
+		return machines.Items
+	}
+
+	// scaleUp reconciles until no more Machines are created, as they are
+	// created one per reconcile.
+	scaleUp := func(c client.Client) *controlplanev1.AgentControlPlane {
+		for {
+			created := len(listMachines(c))
+			updated := reconcileACP(c)
+			if len(listMachines(c)) == created {
+				return updated
+			}
+		}
+	}
+
+	newTemplate := func() *unstructured.Unstructured {
+		template := &unstructured.Unstructured{}
+		template.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1beta1")
+		template.SetKind("Metal3MachineTemplate")
+		template.SetNamespace(testNamespace)
+		template.SetName("control-plane")
+		Expect(unstructured.SetNestedMap(template.Object, map[string]interface{}{}, "spec", "template")).To(Succeed())
+		return template
+	}
+
+	It("waits for the Cluster infrastructure to be ready before creating Machines", func() {
+		cluster.Status.InfrastructureReady = false
+		objs := append(newAvailableAgents(acp, 3), acp, cluster, newTemplate())
+		c := newFakeClientBuilder(newTestScheme(), objs...).
+			WithStatusSubresource(&clusterv1.Cluster{}).
+			Build()
+
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(infrastructureRequeueInterval))
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		condition := conditions.Get(updated, controlplanev1.MachinesCreatedCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.WaitingForClusterInfrastructureReason))
+		Expect(listMachines(c)).To(BeEmpty())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster)).To(Succeed())
+		cluster.Status.InfrastructureReady = true
+		Expect(c.Status().Update(ctx, cluster)).To(Succeed())
+
+		Expect(conditions.IsTrue(scaleUp(c), controlplanev1.MachinesCreatedCondition)).To(BeTrue())
+		Expect(listMachines(c)).To(HaveLen(3))
+	})
+
+	It("waits for the infrastructure template, then creates the Machines", func() {
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster)...)
+
+		updated := reconcileACP(c)
+		condition := conditions.Get(updated, controlplanev1.InfrastructureReadyCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.InfrastructureTemplateNotFoundReason))
+		Expect(listMachines(c)).To(BeEmpty())
+
+		template := newTemplate()
+		Expect(unstructured.SetNestedMap(template.Object, map[string]interface{}{
+			"spec": map[string]interface{}{"automatedCleaningMode": "disabled"},
+		}, "spec", "template")).To(Succeed())
+		Expect(c.Create(ctx, template)).To(Succeed())
+
+		Expect(conditions.IsTrue(scaleUp(c), controlplanev1.InfrastructureReadyCondition)).To(BeTrue())
+		machines := listMachines(c)
+		Expect(machines).To(HaveLen(3))
+		for _, machine := range machines {
+			Expect(metav1.IsControlledBy(&machine, acp)).To(BeTrue())
+			Expect(machine.Labels).To(HaveKey(clusterv1.MachineControlPlaneLabel))
+			Expect(machine.Labels).To(HaveKeyWithValue(clusterv1.MachineControlPlaneNameLabel, acp.Name))
+			Expect(machine.Spec.InfrastructureRef.Kind).To(Equal("Metal3Machine"))
+
+			infraMachine := &unstructured.Unstructured{}
+			infraMachine.SetGroupVersionKind(machine.Spec.InfrastructureRef.GroupVersionKind())
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: machine.Spec.InfrastructureRef.Name}, infraMachine)).To(Succeed())
+			Expect(infraMachine.Object).To(HaveKeyWithValue("spec", HaveKeyWithValue("automatedCleaningMode", "disabled")))
+		}
+
+		// The desired replicas exist, so reconciling again creates nothing.
+		reconcileACP(c)
+		Expect(listMachines(c)).To(HaveLen(3))
+	})
+
+	It("creates no Machines, but reports those of other tooling, when not managing them", func() {
+		acp.Spec.ManageMachines = ptr.To(false)
+		external := newControlPlaneMachine("external", cluster, nil)
+		external.Spec.Version = ptr.To("v1.29.5")
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate(), external)...)
+
+		updated := reconcileACP(c)
+
+		Expect(listMachines(c)).To(HaveLen(1))
+		Expect(updated.Status.Replicas).To(BeEquivalentTo(1))
+		Expect(conditions.Has(updated, controlplanev1.MachinesCreatedCondition)).To(BeFalse())
+		Expect(conditions.Has(updated, controlplanev1.MachinesExpectedCondition)).To(BeFalse())
+		Expect(conditions.IsTrue(updated, controlplanev1.MachineVersionsConvergedCondition)).To(BeTrue())
+	})
+
+	It("reports the Machines it creates", func() {
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate())...)
+
+		Expect(scaleUp(c).Status.Replicas).To(BeEquivalentTo(3))
+	})
+
+	failureDomainsOf := func(machines []clusterv1.Machine) []string {
+		var domains []string
+		for _, machine := range machines {
+			domains = append(domains, ptr.Deref(machine.Spec.FailureDomain, ""))
+		}
+		return domains
+	}
+
+	It("spreads the Machines across the failure domains of the template", func() {
+		acp.Spec.MachineTemplate.FailureDomains = []string{"rack-a", "rack-b", "rack-c"}
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate())...)
+		scaleUp(c)
+
+		Expect(failureDomainsOf(listMachines(c))).To(ConsistOf("rack-a", "rack-b", "rack-c"))
+	})
+
+	It("spreads the Machines across the control plane failure domains of the Cluster", func() {
+		cluster.Status.FailureDomains = clusterv1.FailureDomains{
+			"zone-a": {ControlPlane: true},
+			"zone-b": {ControlPlane: true},
+			"zone-c": {ControlPlane: false},
+		}
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate())...)
+		scaleUp(c)
+
+		Expect(failureDomainsOf(listMachines(c))).To(ConsistOf("zone-a", "zone-a", "zone-b"))
+	})
+
+	It("places new Machines in the failure domains holding the fewest Machines", func() {
+		acp.Spec.MachineTemplate.FailureDomains = []string{"rack-a", "rack-b", "rack-c"}
+		existing := newControlPlaneMachine("existing", cluster, acp)
+		existing.Spec.FailureDomain = ptr.To("rack-a")
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate(), existing)...)
+		scaleUp(c)
+
+		Expect(failureDomainsOf(listMachines(c))).To(ConsistOf("rack-a", "rack-b", "rack-c"))
+	})
+
+	It("creates no duplicates of the Machines found after a restart", func() {
+		var objs []client.Object
+		for _, name := range []string{"test-acp-a", "test-acp-b", "test-acp-c"} {
+			objs = append(objs, newControlPlaneMachine(name, cluster, acp))
+		}
+		c := newFakeClient(newTestScheme(), append(append(objs, newAvailableAgents(acp, 3)...), acp, cluster, newTemplate())...)
+
+		// Each reconcile uses a new reconciler, like a restarted controller.
+		Expect(scaleUp(c).Status.Replicas).To(BeEquivalentTo(3))
+		Expect(listMachines(c)).To(HaveLen(3))
+	})
+
+	It("adopts the Machines left behind by a former AgentControlPlane of the same name", func() {
+		former := acp.DeepCopy()
+		former.UID = "former-uid"
+		orphaned := newControlPlaneMachine("orphaned", cluster, acp)
+		orphaned.OwnerReferences = nil
+		formerlyOwned := newControlPlaneMachine("formerly-owned", cluster, former)
+		handMade := newControlPlaneMachine("hand-made", cluster, nil)
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate(), orphaned, formerlyOwned, handMade)...)
+
+		scaleUp(c)
+
+		machines := listMachines(c)
+		Expect(machines).To(HaveLen(4))
+		var adopted []string
+		for _, machine := range machines {
+			if metav1.IsControlledBy(&machine, acp) {
+				adopted = append(adopted, machine.Name)
+			}
+		}
+		Expect(adopted).To(ContainElements("orphaned", "formerly-owned"))
+		Expect(adopted).NotTo(ContainElement("hand-made"))
+		Expect(adopted).To(HaveLen(3))
+	})
+
+	It("creates one Machine per reconcile", func() {
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate())...)
+
+		condition := conditions.Get(reconcileACP(c), controlplanev1.MachinesCreatedCondition)
+		Expect(listMachines(c)).To(HaveLen(1))
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.CreatingMachinesReason))
+		Expect(condition.Message).To(Equal("1 of 3 control plane Machines created"))
+	})
+
+	It("prefers the failure domains with spare Agents", func() {
+		acp.Spec.MachineTemplate.FailureDomains = []string{"rack-a", "rack-b", "rack-c"}
+		agents := newAvailableAgents(acp, 3)
+		for i, zone := range []string{"rack-b", "rack-c", "rack-c"} {
+			agents[i].GetLabels()[corev1.LabelTopologyZone] = zone
+		}
+		c := newFakeClient(newTestScheme(), append(agents, acp, cluster, newTemplate())...)
+
+		reconcileACP(c)
+		Expect(failureDomainsOf(listMachines(c))).To(ConsistOf("rack-b"))
+		scaleUp(c)
+		Expect(failureDomainsOf(listMachines(c))).To(ConsistOf("rack-b", "rack-c", "rack-c"))
+	})
+
+	It("sets the health check labels on the Machines it creates", func() {
+		acp.Spec.MachineTemplate.HealthCheckLabels = map[string]string{
+			"mhc.example.com/target":           "control-plane",
+			clusterv1.MachineControlPlaneLabel: "overridden",
+		}
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newTemplate())...)
+		scaleUp(c)
+
+		machines := listMachines(c)
+		Expect(machines).To(HaveLen(3))
+		for _, machine := range machines {
+			Expect(machine.Labels).To(HaveKeyWithValue("mhc.example.com/target", "control-plane"))
+			Expect(machine.Labels).To(HaveKeyWithValue(clusterv1.MachineControlPlaneLabel, ""))
+			Expect(machine.Labels).To(HaveKeyWithValue(clusterv1.MachineControlPlaneNameLabel, acp.Name))
+		}
+	})
+
+	It("reports the version skew between Machines until they converge", func() {
+		acp.Spec.Replicas = ptr.To[int32](2)
+		older := newControlPlaneMachine("older", cluster, acp)
+		older.Spec.Version = ptr.To("4.14.10")
+		newer := newControlPlaneMachine("newer", cluster, acp)
+		newer.Spec.Version = ptr.To("4.15.2")
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 2), acp, cluster, newTemplate(), older, newer)...)
+
+		updated := reconcileACP(c)
+		Expect(updated.Status.VersionSkew).To(Equal(&controlplanev1.VersionSkew{MinVersion: "4.14.10", MaxVersion: "4.15.2"}))
+		condition := conditions.Get(updated, controlplanev1.MachineVersionsConvergedCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.VersionSkewReason))
+		Expect(condition.Message).To(Equal("Control plane Machines run versions 4.14.10 to 4.15.2"))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(older), older)).To(Succeed())
+		older.Spec.Version = ptr.To("4.15.2")
+		Expect(c.Update(ctx, older)).To(Succeed())
+
+		updated = reconcileACP(c)
+		Expect(updated.Status.VersionSkew).To(BeNil())
+		Expect(conditions.IsTrue(updated, controlplanev1.MachineVersionsConvergedCondition)).To(BeTrue())
+	})
+
+	It("does not create Machines from a template being deleted", func() {
+		template := newTemplate()
+		template.SetFinalizers([]string{"test"})
+		template.SetDeletionTimestamp(ptr.To(metav1.Now()))
+		c := newFakeClient(newTestScheme(), acp, cluster, template)
+
+		condition := conditions.Get(reconcileACP(c), controlplanev1.InfrastructureReadyCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.InfrastructureTemplateDeletingReason))
+		Expect(listMachines(c)).To(BeEmpty())
+	})
+	It("creates no more Machines than there are available Agents", func() {
+		c := newFakeClient(newTestScheme(), append(newAvailableAgents(acp, 1), acp, cluster, newTemplate())...)
+
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		for i := 0; i < 2; i++ {
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(agentRequeueInterval))
+			Expect(listMachines(c)).To(HaveLen(1))
+		}
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		condition := conditions.Get(updated, controlplanev1.MachinesCreatedCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.WaitingForAgentsReason))
+		Expect(condition.Message).To(Equal("1 of 3 Agents available"))
+
+		Expect(c.Create(ctx, newAvailableAgents(acp, 3)[1])).To(Succeed())
+		Expect(c.Create(ctx, newAvailableAgents(acp, 3)[2])).To(Succeed())
+		Expect(conditions.IsTrue(scaleUp(c), controlplanev1.MachinesCreatedCondition)).To(BeTrue())
+		Expect(listMachines(c)).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("Machine from template", func() {
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.Version = "4.15.2"
+		acp.Spec.MachineTemplate = &controlplanev1.AgentControlPlaneMachineTemplate{
+			InfrastructureRef: corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+				Kind:       "Metal3MachineTemplate",
+				Name:       "control-plane",
+			},
+			HealthCheckLabels:   map[string]string{"mhc.example.com/target": "control-plane"},
+			NodeDrainTimeout:    &metav1.Duration{Duration: 10 * time.Minute},
+			NodeDeletionTimeout: &metav1.Duration{},
+		}
+	})
+
+	It("describes the Machine asked for by the AgentControlPlane", func() {
+		machine := machineFromTemplate(acp, cluster, "test-acp-abcde")
+
+		Expect(machine.Name).To(Equal("test-acp-abcde"))
+		Expect(machine.Namespace).To(Equal(testNamespace))
+		Expect(machine.Labels).To(Equal(map[string]string{
+			"mhc.example.com/target":               "control-plane",
+			clusterv1.ClusterNameLabel:             "test-cluster",
+			clusterv1.MachineControlPlaneLabel:     "",
+			clusterv1.MachineControlPlaneNameLabel: "test-acp",
+		}))
+		Expect(metav1.IsControlledBy(machine, acp)).To(BeTrue())
+		Expect(machine.Spec.ClusterName).To(Equal("test-cluster"))
+		Expect(machine.Spec.InfrastructureRef).To(Equal(corev1.ObjectReference{
+			APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+			Kind:       "Metal3Machine",
+			Namespace:  testNamespace,
+			Name:       "test-acp-abcde",
+		}))
+		Expect(machine.Spec.Version).To(HaveValue(Equal("4.15.2")))
+		Expect(machine.Spec.Bootstrap.DataSecretName).To(HaveValue(BeEmpty()))
+		Expect(machine.Spec.NodeDrainTimeout).To(Equal(&metav1.Duration{Duration: 10 * time.Minute}))
+		Expect(machine.Spec.NodeVolumeDetachTimeout).To(BeNil())
+		Expect(machine.Spec.NodeDeletionTimeout).To(Equal(&metav1.Duration{}))
+	})
+
+	It("leaves the version unset when the AgentControlPlane has none", func() {
+		acp.Spec.Version = ""
+
+		Expect(machineFromTemplate(acp, cluster, "test-acp-abcde").Spec.Version).To(BeNil())
+	})
+
+	It("does not let the health check labels replace the control plane labels", func() {
+		acp.Spec.MachineTemplate.HealthCheckLabels[clusterv1.MachineControlPlaneNameLabel] = "other"
+
+		machine := machineFromTemplate(acp, cluster, "test-acp-abcde")
+		Expect(machine.Labels).To(HaveKeyWithValue(clusterv1.MachineControlPlaneNameLabel, "test-acp"))
+		Expect(acp.Spec.MachineTemplate.HealthCheckLabels).To(HaveKeyWithValue(clusterv1.MachineControlPlaneNameLabel, "other"))
+	})
+
+	It("produces the same Machine for the same spec", func() {
+		Expect(machineFromTemplate(acp, cluster, "test-acp-abcde")).To(Equal(machineFromTemplate(acp, cluster, "test-acp-abcde")))
+	})
+})
@@ -0,0 +1,84 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// Values of the result label of reconcileDuration.
+const (
+	reconcileResultSuccess = "success"
+	reconcileResultError   = "error"
+	reconcileResultRequeue = "requeue"
+)
+
+// reconcileDuration measures whole AgentControlPlane reconciles. The work
+// queue depth is already exported by controller-runtime as workqueue_depth.
+var reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "agentcontrolplane_reconcile_duration_seconds",
+	Help:    "Duration of AgentControlPlane reconciles, by result.",
+	Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+}, []string{"result"})
+
+// timeToReady measures how long AgentControlPlanes take from their creation
+// to first becoming ready, for provisioning SLAs.
+var timeToReady = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "agentcontrolplane_time_to_ready_seconds",
+	Help:    "Time from the creation of AgentControlPlanes to them first becoming ready.",
+	Buckets: prometheus.ExponentialBuckets(60, 2, 10),
+})
+
+// readyObserved holds the UIDs of the AgentControlPlanes whose time to ready
+// was observed, so that a reconcile reading a stale AgentControlPlane, still
+// not ready, does not observe it again.
+var readyObserved sync.Map
+
+func init() {
+	metrics.Registry.MustRegister(reconcileDuration, timeToReady)
+}
+
+// observeTimeToReady records that acp became ready at now, once per
+// AgentControlPlane. Callers only call it when status.ready turns true, so
+// AgentControlPlanes already ready when the controller starts are not
+// observed again.
+func observeTimeToReady(acp *controlplanev1.AgentControlPlane, now time.Time) {
+	if _, observed := readyObserved.LoadOrStore(acp.UID, struct{}{}); observed {
+		return
+	}
+	timeToReady.Observe(now.Sub(acp.CreationTimestamp.Time).Seconds())
+}
+
+// observeReconcile records a reconcile that started at start and returned
+// result and err.
+func observeReconcile(start time.Time, result ctrl.Result, err error) {
+	label := reconcileResultSuccess
+	switch {
+	case err != nil:
+		label = reconcileResultError
+	case result.Requeue || result.RequeueAfter > 0:
+		label = reconcileResultRequeue
+	}
+	reconcileDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+}
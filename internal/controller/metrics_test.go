@@ -0,0 +1,74 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Reconcile metrics", func() {
+	ctx := context.Background()
+
+	// observations returns how many reconciles with result were observed,
+	// as exported by the controller-runtime metrics registry.
+	observations := func(result string) uint64 {
+		families, err := metrics.Registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		for _, family := range families {
+			if family.GetName() != "agentcontrolplane_reconcile_duration_seconds" {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				if hasLabel(metric, "result", result) {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return 0
+	}
+
+	It("observes the duration of each reconcile by result", func() {
+		acp := newAgentControlPlane("test-acp")
+		cluster := newCluster("test-cluster")
+		setOwnerCluster(acp, cluster)
+		c := newFakeClient(newTestScheme(), acp, cluster, newPullSecret())
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+
+		before := observations(reconcileResultSuccess)
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(observations(reconcileResultSuccess)).To(Equal(before + 1))
+	})
+})
+
+func hasLabel(metric *dto.Metric, name, value string) bool {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name && label.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+const (
+	// mirrorRegistryConfigKey holds the registries.conf of the discovered
+	// hosts in a mirror registry ConfigMap.
+	mirrorRegistryConfigKey = "registries.conf"
+	// mirrorRegistryCABundleKey holds the CA certificates of the mirrors in a
+	// mirror registry ConfigMap.
+	mirrorRegistryCABundleKey = "ca-bundle.crt"
+)
+
+// registryTable matches the [[registry]] tables of a registries.conf.
+var registryTable = regexp.MustCompile(`(?m)^\s*\[\[registry\]\]\s*$`)
+
+// reconcileMirrorRegistry records on the MirrorRegistryConfiguredCondition
+// whether the ConfigMap referenced by spec.mirrorRegistryRef exists and holds
+// a mirror configuration assisted-service accepts.
+func (r *AgentControlPlaneReconciler) reconcileMirrorRegistry(ctx context.Context, acp *controlplanev1.AgentControlPlane) error {
+	ref := acp.Spec.MirrorRegistryRef
+	if ref == nil {
+		conditions.Delete(acp, controlplanev1.MirrorRegistryConfiguredCondition)
+		return nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: acp.Namespace, Name: ref.Name}, configMap)
+	if apierrors.IsNotFound(err) {
+		conditions.MarkFalse(acp, controlplanev1.MirrorRegistryConfiguredCondition, controlplanev1.MirrorRegistryConfigMapMissingReason,
+			clusterv1.ConditionSeverityWarning, "Mirror registry ConfigMap %s not found", ref.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if problems := validateMirrorRegistryConfigMap(configMap); len(problems) > 0 {
+		conditions.MarkFalse(acp, controlplanev1.MirrorRegistryConfiguredCondition, controlplanev1.MirrorRegistryConfigMapInvalidReason,
+			clusterv1.ConditionSeverityError, "Mirror registry ConfigMap %s is invalid: %s", ref.Name, strings.Join(problems, "; "))
+		return nil
+	}
+	conditions.MarkTrue(acp, controlplanev1.MirrorRegistryConfiguredCondition)
+	return nil
+}
+
+// validateMirrorRegistryConfigMap returns what is wrong with the mirror
+// registry configuration held by configMap.
+func validateMirrorRegistryConfigMap(configMap *corev1.ConfigMap) []string {
+	var problems []string
+	config, ok := configMap.Data[mirrorRegistryConfigKey]
+	switch {
+	case !ok:
+		problems = append(problems, fmt.Sprintf("missing key %q", mirrorRegistryConfigKey))
+	case !registryTable.MatchString(config):
+		problems = append(problems, fmt.Sprintf("%s defines no [[registry]]", mirrorRegistryConfigKey))
+	}
+
+	if bundle, ok := configMap.Data[mirrorRegistryCABundleKey]; ok && mergeTrustBundles(bundle) == "" {
+		problems = append(problems, fmt.Sprintf("%s holds no PEM-encoded certificate", mirrorRegistryCABundleKey))
+	}
+
+	var unknown []string
+	for key := range configMap.Data {
+		if key != mirrorRegistryConfigKey && key != mirrorRegistryCABundleKey {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	if len(unknown) > 0 {
+		problems = append(problems, fmt.Sprintf("unknown keys %s", strings.Join(unknown, ", ")))
+	}
+	return problems
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("Mirror registry", func() {
+	ctx := context.Background()
+
+	const registriesConf = `
+[[registry]]
+  location = "quay.io/openshift-release-dev/ocp-release"
+
+  [[registry.mirror]]
+    location = "mirror.example.com:5000/ocp/release"
+`
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.MirrorRegistryRef = &corev1.LocalObjectReference{Name: "mirror-registry"}
+	})
+
+	newConfigMap := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "mirror-registry", Namespace: testNamespace},
+			Data:       data,
+		}
+	}
+
+	reconcileACP := func(objs ...client.Object) (client.Client, *controlplanev1.AgentControlPlane) {
+		c := newFakeClient(newTestScheme(), append(objs, acp)...)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return c, updated
+	}
+
+	It("sets the mirror registry reference on the InfraEnv", func() {
+		c, updated := reconcileACP(newConfigMap(map[string]string{"registries.conf": registriesConf}))
+
+		infraEnv := &aiv1beta1.InfraEnv{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+		Expect(infraEnv.Spec.MirrorRegistryRef).To(Equal(&corev1.LocalObjectReference{Name: "mirror-registry"}))
+		Expect(conditions.IsTrue(updated, controlplanev1.MirrorRegistryConfiguredCondition)).To(BeTrue())
+	})
+
+	It("reports a malformed ConfigMap", func() {
+		_, updated := reconcileACP(newConfigMap(map[string]string{
+			"registries.conf": "unqualified-search-registries = []",
+			"ca-bundle.crt":   "not a certificate",
+			"mirrors":         "mirror.example.com",
+		}))
+
+		condition := conditions.Get(updated, controlplanev1.MirrorRegistryConfiguredCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.MirrorRegistryConfigMapInvalidReason))
+		Expect(condition.Message).To(Equal("Mirror registry ConfigMap mirror-registry is invalid: " +
+			"registries.conf defines no [[registry]]; ca-bundle.crt holds no PEM-encoded certificate; unknown keys mirrors"))
+	})
+
+	It("reports a missing ConfigMap", func() {
+		_, updated := reconcileACP()
+
+		condition := conditions.Get(updated, controlplanev1.MirrorRegistryConfiguredCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.MirrorRegistryConfigMapMissingReason))
+	})
+})
@@ -0,0 +1,171 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+const (
+	// nodeLabelsAnnotation records the label keys last applied to a Node
+	// from spec.nodeLabels, so keys dropped from the spec can be removed.
+	nodeLabelsAnnotation = "controlplane.openshift.io/managed-node-labels"
+
+	// nodeTaintsAnnotation records the "key:effect" pairs last applied to a
+	// Node from spec.nodeTaints, so taints dropped from the spec can be removed.
+	nodeTaintsAnnotation = "controlplane.openshift.io/managed-node-taints"
+)
+
+// WorkloadClusterClientGetter returns clients for workload clusters. It is
+// satisfied by the cluster-api remote.ClusterCacheTracker.
+type WorkloadClusterClientGetter interface {
+	GetClient(ctx context.Context, cluster client.ObjectKey) (client.Client, error)
+}
+
+// reconcileNodes applies spec.nodeLabels and spec.nodeTaints to the Nodes
+// backing the control plane Machines that have joined the workload cluster.
+func (r *AgentControlPlaneReconciler) reconcileNodes(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) error {
+	if r.WorkloadClusters == nil {
+		return nil
+	}
+
+	machines, err := collections.GetFilteredMachinesForCluster(ctx, r.Client, cluster,
+		collections.ControlPlaneMachines(cluster.Name), controlledBy(acp), collections.ActiveMachines, collections.HasNode())
+	if err != nil {
+		return err
+	}
+	if machines.Len() == 0 {
+		return nil
+	}
+
+	workloadClient, err := r.WorkloadClusters.GetClient(ctx, client.ObjectKeyFromObject(cluster))
+	if err != nil {
+		return fmt.Errorf("failed to get workload cluster client: %w", err)
+	}
+
+	for _, machine := range machines.SortedByCreationTimestamp() {
+		node := &corev1.Node{}
+		if err := workloadClient.Get(ctx, client.ObjectKey{Name: machine.Status.NodeRef.Name}, node); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		original := node.DeepCopy()
+		syncNodeLabels(node, acp.Spec.NodeLabels)
+		syncNodeTaints(node, acp.Spec.NodeTaints)
+		if equality.Semantic.DeepEqual(original, node) {
+			continue
+		}
+
+		log.FromContext(ctx).Info("updating control plane Node labels and taints", "node", node.Name, "machine", machine.Name)
+		if err := workloadClient.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncNodeLabels sets labels on node and removes the keys that were applied
+// previously but are no longer desired.
+func syncNodeLabels(node *corev1.Node, labels map[string]string) {
+	previous := sets.New(splitAnnotation(node.Annotations[nodeLabelsAnnotation])...)
+	desired := sets.KeySet(labels)
+
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for key := range previous.Difference(desired) {
+		delete(node.Labels, key)
+	}
+	for key, value := range labels {
+		node.Labels[key] = value
+	}
+	setAnnotationList(node, nodeLabelsAnnotation, desired)
+}
+
+// syncNodeTaints sets taints on node, replacing the value of taints with the
+// same key and effect, and removes taints that were applied previously but
+// are no longer desired.
+func syncNodeTaints(node *corev1.Node, taints []corev1.Taint) {
+	previous := sets.New(splitAnnotation(node.Annotations[nodeTaintsAnnotation])...)
+	desired := sets.New[string]()
+	for _, taint := range taints {
+		desired.Insert(taintKey(taint))
+	}
+
+	result := make([]corev1.Taint, 0, len(node.Spec.Taints)+len(taints))
+	for _, taint := range node.Spec.Taints {
+		key := taintKey(taint)
+		if desired.Has(key) || previous.Has(key) {
+			continue
+		}
+		result = append(result, taint)
+	}
+	for _, taint := range taints {
+		result = append(result, corev1.Taint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+	}
+	if len(result) == 0 {
+		result = nil
+	}
+	node.Spec.Taints = result
+	setAnnotationList(node, nodeTaintsAnnotation, desired)
+}
+
+func taintKey(taint corev1.Taint) string {
+	return taint.Key + ":" + string(taint.Effect)
+}
+
+func splitAnnotation(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// setAnnotationList stores values on obj as a sorted, comma separated
+// annotation, removing the annotation when values is empty.
+func setAnnotationList(obj client.Object, key string, values sets.Set[string]) {
+	annotations := obj.GetAnnotations()
+	if values.Len() == 0 {
+		delete(annotations, key)
+		obj.SetAnnotations(annotations)
+		return
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	list := values.UnsortedList()
+	sort.Strings(list)
+	annotations[key] = strings.Join(list, ",")
+	obj.SetAnnotations(annotations)
+}
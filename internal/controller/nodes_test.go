@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Control plane Node labels and taints", func() {
+	ctx := context.Background()
+
+	var (
+		acp            *controlplanev1.AgentControlPlane
+		cluster        *clusterv1.Cluster
+		machine        *clusterv1.Machine
+		node           *corev1.Node
+		workloadClient client.Client
+		c              client.Client
+	)
+
+	infraTaint := corev1.Taint{Key: "node.cloudprovider.kubernetes.io/uninitialized", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, cluster)
+		acp.Spec.NodeLabels = map[string]string{"node-role.example.com/infra": "true"}
+		acp.Spec.NodeTaints = []corev1.Taint{{Key: "dedicated", Value: "control-plane", Effect: corev1.TaintEffectNoExecute}}
+
+		machine = newControlPlaneMachine("machine-0", cluster, acp)
+		machine.Status.NodeRef = &corev1.ObjectReference{Kind: "Node", Name: "node-0"}
+
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-0",
+				Labels: map[string]string{"kubernetes.io/hostname": "node-0"},
+			},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{infraTaint}},
+		}
+		workloadClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(node).Build()
+		c = newFakeClient(newTestScheme(), acp, cluster, machine)
+	})
+
+	reconcileACP := func() {
+		r := &AgentControlPlaneReconciler{
+			Client:           c,
+			Scheme:           c.Scheme(),
+			WorkloadClusters: &fakeWorkloadClusters{client: workloadClient},
+		}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	getNode := func() *corev1.Node {
+		updated := &corev1.Node{}
+		Expect(workloadClient.Get(ctx, client.ObjectKeyFromObject(node), updated)).To(Succeed())
+		return updated
+	}
+
+	updateACP := func(mutate func(*controlplanev1.AgentControlPlane)) {
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		mutate(updated)
+		Expect(c.Update(ctx, updated)).To(Succeed())
+	}
+
+	It("applies the labels and taints to the Node backing a control plane Machine", func() {
+		reconcileACP()
+
+		updated := getNode()
+		Expect(updated.Labels).To(HaveKeyWithValue("node-role.example.com/infra", "true"))
+		Expect(updated.Labels).To(HaveKeyWithValue("kubernetes.io/hostname", "node-0"))
+		Expect(updated.Spec.Taints).To(ConsistOf(infraTaint, acp.Spec.NodeTaints[0]))
+	})
+
+	It("reverts drift on the Node", func() {
+		reconcileACP()
+
+		drifted := getNode()
+		drifted.Labels["node-role.example.com/infra"] = "false"
+		drifted.Spec.Taints = []corev1.Taint{infraTaint}
+		Expect(workloadClient.Update(ctx, drifted)).To(Succeed())
+
+		reconcileACP()
+
+		updated := getNode()
+		Expect(updated.Labels).To(HaveKeyWithValue("node-role.example.com/infra", "true"))
+		Expect(updated.Spec.Taints).To(ConsistOf(infraTaint, acp.Spec.NodeTaints[0]))
+	})
+
+	It("removes labels and taints dropped from the spec without touching others", func() {
+		reconcileACP()
+
+		updateACP(func(acp *controlplanev1.AgentControlPlane) {
+			acp.Spec.NodeLabels = nil
+			acp.Spec.NodeTaints = nil
+		})
+		reconcileACP()
+
+		updated := getNode()
+		Expect(updated.Labels).NotTo(HaveKey("node-role.example.com/infra"))
+		Expect(updated.Labels).To(HaveKeyWithValue("kubernetes.io/hostname", "node-0"))
+		Expect(updated.Spec.Taints).To(ConsistOf(infraTaint))
+		Expect(updated.Annotations).NotTo(HaveKey(nodeLabelsAnnotation))
+		Expect(updated.Annotations).NotTo(HaveKey(nodeTaintsAnnotation))
+	})
+})
@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// ownerCluster returns the Cluster owning acp, reporting it on the
+// OwnerClusterAvailableCondition. It returns nil while acp has no owner
+// Cluster, which the Cluster controller sets once the Cluster references
+// acp, while the referenced Cluster does not exist, or once the Cluster's
+// controlPlaneRef is changed to reference another control plane, which
+// leaves the owner reference in place.
+func (r *AgentControlPlaneReconciler) ownerCluster(ctx context.Context, acp *controlplanev1.AgentControlPlane) (*clusterv1.Cluster, error) {
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, acp.ObjectMeta)
+	switch {
+	case apierrors.IsNotFound(err):
+		conditions.MarkFalse(acp, controlplanev1.OwnerClusterAvailableCondition, controlplanev1.WaitingForClusterReason,
+			clusterv1.ConditionSeverityInfo, "The owner Cluster does not exist")
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case cluster == nil:
+		conditions.MarkFalse(acp, controlplanev1.OwnerClusterAvailableCondition, controlplanev1.WaitingForClusterReason,
+			clusterv1.ConditionSeverityInfo, "Waiting for a Cluster to reference the control plane")
+		return nil, nil
+	case !referencesControlPlane(cluster, acp):
+		ref := cluster.Spec.ControlPlaneRef
+		conditions.MarkFalse(acp, controlplanev1.OwnerClusterAvailableCondition, controlplanev1.OrphanedReason,
+			clusterv1.ConditionSeverityWarning, "Cluster %s references %s %s as its control plane", cluster.Name, ref.Kind, ref.Name)
+		return nil, nil
+	}
+	conditions.MarkTrue(acp, controlplanev1.OwnerClusterAvailableCondition)
+	return cluster, nil
+}
+
+// referencesControlPlane returns whether the controlPlaneRef of cluster
+// references acp. A Cluster without a controlPlaneRef is taken as
+// referencing it, as the owner reference is the Cluster's own claim.
+func referencesControlPlane(cluster *clusterv1.Cluster, acp *controlplanev1.AgentControlPlane) bool {
+	ref := cluster.Spec.ControlPlaneRef
+	if ref == nil {
+		return true
+	}
+	key, ok := controlPlaneRefKey(cluster)
+	return ok && key == client.ObjectKeyFromObject(acp)
+}
+
+// controlPlaneRefKey returns the key of the AgentControlPlane the
+// controlPlaneRef of cluster references, and false when it references none.
+func controlPlaneRefKey(cluster *clusterv1.Cluster) (client.ObjectKey, bool) {
+	ref := cluster.Spec.ControlPlaneRef
+	if ref == nil || ref.Kind != "AgentControlPlane" || ref.GroupVersionKind().Group != controlplanev1.GroupVersion.Group {
+		return client.ObjectKey{}, false
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = cluster.Namespace
+	}
+	return client.ObjectKey{Namespace: namespace, Name: ref.Name}, true
+}
+
+// clusterToAgentControlPlane maps a Cluster to the AgentControlPlane its
+// controlPlaneRef references. On a change of the reference, the formerly
+// referenced AgentControlPlane is enqueued for the old version of the
+// Cluster, so that it sees it is orphaned, and no longer after that.
+func (r *AgentControlPlaneReconciler) clusterToAgentControlPlane(_ context.Context, obj client.Object) []ctrl.Request {
+	cluster, ok := obj.(*clusterv1.Cluster)
+	if !ok {
+		return nil
+	}
+	key, ok := controlPlaneRefKey(cluster)
+	if !ok {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: key}}
+}
+
+// ownerClusterRequeueInterval returns how long to wait before checking again
+// for the owner Cluster of acp: as long as it has been waited for already,
+// within ownerClusterMinRequeueInterval and ownerClusterMaxRequeueInterval.
+func ownerClusterRequeueInterval(acp *controlplanev1.AgentControlPlane, now time.Time) time.Duration {
+	var waited time.Duration
+	if since := conditions.GetLastTransitionTime(acp, controlplanev1.OwnerClusterAvailableCondition); since != nil {
+		waited = now.Sub(since.Time)
+	}
+	return min(max(waited, ownerClusterMinRequeueInterval), ownerClusterMaxRequeueInterval)
+}
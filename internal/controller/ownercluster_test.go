@@ -0,0 +1,156 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Owner Cluster", func() {
+	ctx := context.Background()
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+	})
+
+	reconcileACP := func(objs ...client.Object) (reconcile.Result, *controlplanev1.AgentControlPlane) {
+		c := newFakeClient(newTestScheme(), append(objs, acp, newPullSecret())...)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return result, updated
+	}
+
+	expectWaitingForCluster := func(updated *controlplanev1.AgentControlPlane, message string) {
+		condition := conditions.Get(updated, controlplanev1.OwnerClusterAvailableCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.WaitingForClusterReason))
+		Expect(condition.Message).To(Equal(message))
+	}
+
+	It("requeues while the AgentControlPlane has no owner Cluster", func() {
+		result, updated := reconcileACP()
+
+		Expect(result.RequeueAfter).To(Equal(ownerClusterMinRequeueInterval))
+		expectWaitingForCluster(updated, "Waiting for a Cluster to reference the control plane")
+	})
+
+	It("requeues while the owner Cluster does not exist", func() {
+		setOwnerCluster(acp, newCluster("test-cluster"))
+		result, updated := reconcileACP()
+
+		Expect(result.RequeueAfter).To(Equal(ownerClusterMinRequeueInterval))
+		expectWaitingForCluster(updated, "The owner Cluster does not exist")
+	})
+
+	It("marks the owner Cluster available once it exists", func() {
+		cluster := newCluster("test-cluster")
+		setOwnerCluster(acp, cluster)
+		_, updated := reconcileACP(cluster)
+
+		Expect(conditions.IsTrue(updated, controlplanev1.OwnerClusterAvailableCondition)).To(BeTrue())
+	})
+
+	It("reports the AgentControlPlane orphaned once the Cluster references another control plane", func() {
+		cluster := newCluster("test-cluster")
+		cluster.Spec.ControlPlaneRef = &corev1.ObjectReference{
+			APIVersion: controlplanev1.GroupVersion.String(),
+			Kind:       "AgentControlPlane",
+			Name:       acp.Name,
+		}
+		setOwnerCluster(acp, cluster)
+		c := newFakeClient(newTestScheme(), acp, cluster, newPullSecret())
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		reconcileAndGet := func() (reconcile.Result, *controlplanev1.AgentControlPlane) {
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+			Expect(err).NotTo(HaveOccurred())
+			updated := &controlplanev1.AgentControlPlane{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+			return result, updated
+		}
+
+		Expect(r.clusterToAgentControlPlane(ctx, cluster)).To(ConsistOf(
+			reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)},
+		))
+		_, updated := reconcileAndGet()
+		Expect(conditions.IsTrue(updated, controlplanev1.OwnerClusterAvailableCondition)).To(BeTrue())
+
+		flipped := &clusterv1.Cluster{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cluster), flipped)).To(Succeed())
+		flipped.Spec.ControlPlaneRef.Name = "other-acp"
+		Expect(c.Update(ctx, flipped)).To(Succeed())
+
+		Expect(r.clusterToAgentControlPlane(ctx, flipped)).To(ConsistOf(
+			reconcile.Request{NamespacedName: client.ObjectKey{Namespace: testNamespace, Name: "other-acp"}},
+		))
+		result, updated := reconcileAndGet()
+		Expect(result.RequeueAfter).NotTo(BeZero())
+		condition := conditions.Get(updated, controlplanev1.OwnerClusterAvailableCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.OrphanedReason))
+		Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityWarning))
+		Expect(condition.Message).To(Equal("Cluster test-cluster references AgentControlPlane other-acp as its control plane"))
+	})
+
+	It("maps Clusters referencing other kinds of control planes to nothing", func() {
+		cluster := newCluster("test-cluster")
+		r := &AgentControlPlaneReconciler{}
+		Expect(r.clusterToAgentControlPlane(ctx, cluster)).To(BeEmpty())
+
+		cluster.Spec.ControlPlaneRef = &corev1.ObjectReference{
+			APIVersion: "controlplane.cluster.x-k8s.io/v1beta1",
+			Kind:       "KubeadmControlPlane",
+			Name:       acp.Name,
+		}
+		Expect(r.clusterToAgentControlPlane(ctx, cluster)).To(BeEmpty())
+	})
+
+	DescribeTable("backing off",
+		func(waited, requeueAfter time.Duration) {
+			now := time.Now()
+			conditions.Set(acp, &clusterv1.Condition{
+				Type:               controlplanev1.OwnerClusterAvailableCondition,
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: metav1.NewTime(now.Add(-waited)),
+			})
+			Expect(ownerClusterRequeueInterval(acp, now)).To(Equal(requeueAfter))
+		},
+		Entry("just started waiting", time.Duration(0), ownerClusterMinRequeueInterval),
+		Entry("waited for a while", 40*time.Second, 40*time.Second),
+		Entry("waited for long", time.Hour, ownerClusterMaxRequeueInterval),
+	)
+})
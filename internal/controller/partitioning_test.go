@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("Control planes sharing a namespace", func() {
+	ctx := context.Background()
+
+	newControlPlane := func(name string) (*controlplanev1.AgentControlPlane, *clusterv1.Cluster) {
+		cluster := newCluster(name + "-cluster")
+		cluster.Status.InfrastructureReady = true
+		acp := newAgentControlPlane(name)
+		acp.Spec.Replicas = ptr.To[int32](1)
+		acp.Spec.MachineTemplate = &controlplanev1.AgentControlPlaneMachineTemplate{
+			InfrastructureRef: corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+				Kind:       "Metal3MachineTemplate",
+				Name:       "control-plane",
+			},
+		}
+		setOwnerCluster(acp, cluster)
+		return acp, cluster
+	}
+
+	newTemplate := func() *unstructured.Unstructured {
+		template := &unstructured.Unstructured{}
+		template.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1beta1")
+		template.SetKind("Metal3MachineTemplate")
+		template.SetNamespace(testNamespace)
+		template.SetName("control-plane")
+		Expect(unstructured.SetNestedMap(template.Object, map[string]interface{}{}, "spec", "template")).To(Succeed())
+		return template
+	}
+
+	reconcileACP := func(c client.Client, acp *controlplanev1.AgentControlPlane) error {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(), GarbageCollectStaleMachines: true}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		return err
+	}
+
+	It("keeps the InfraEnvs and Machines of each control plane apart", func() {
+		acpA, clusterA := newControlPlane("acp-a")
+		acpB, clusterB := newControlPlane("acp-b")
+		objs := append(newAvailableAgents(acpA, 1), newAvailableAgents(acpB, 1)...)
+		c := newFakeClient(newTestScheme(), append(objs, acpA, clusterA, acpB, clusterB, newTemplate())...)
+
+		for i := 0; i < 2; i++ {
+			Expect(reconcileACP(c, acpA)).To(Succeed())
+			Expect(reconcileACP(c, acpB)).To(Succeed())
+		}
+
+		for _, acp := range []*controlplanev1.AgentControlPlane{acpA, acpB} {
+			infraEnv := &aiv1beta1.InfraEnv{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+			Expect(metav1.GetControllerOf(infraEnv).UID).To(Equal(acp.UID))
+			Expect(infraEnv.Annotations).To(HaveKeyWithValue(agentControlPlaneAnnotation, client.ObjectKeyFromObject(acp).String()))
+		}
+
+		machines := &clusterv1.MachineList{}
+		Expect(c.List(ctx, machines, client.InNamespace(testNamespace))).To(Succeed())
+		Expect(machines.Items).To(HaveLen(2))
+		owners := map[types.UID]string{}
+		for _, machine := range machines.Items {
+			owner := metav1.GetControllerOf(&machine)
+			Expect(owner).NotTo(BeNil())
+			Expect(machine.Labels).To(HaveKeyWithValue(clusterv1.MachineControlPlaneNameLabel, owner.Name))
+			owners[owner.UID] = machine.Labels[clusterv1.ClusterNameLabel]
+		}
+		Expect(owners).To(Equal(map[types.UID]string{
+			acpA.UID: clusterA.Name,
+			acpB.UID: clusterB.Name,
+		}))
+	})
+
+	It("does not adopt an InfraEnv controlled by a previous control plane of the same name", func() {
+		acp, cluster := newControlPlane("acp-a")
+		previous := acp.DeepCopy()
+		previous.UID = "previous-uid"
+		infraEnv := &aiv1beta1.InfraEnv{
+			ObjectMeta: metav1.ObjectMeta{Name: acp.Name, Namespace: testNamespace},
+		}
+		Expect(controllerutil.SetControllerReference(previous, infraEnv, newTestScheme())).To(Succeed())
+		c := newFakeClient(newTestScheme(), acp, cluster, infraEnv)
+
+		Expect(reconcileACP(c, acp)).To(Succeed())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(conditions.GetReason(updated, controlplanev1.InfraEnvClaimedCondition)).To(Equal(controlplanev1.InfraEnvConflictReason))
+		Expect(conditions.GetMessage(updated, controlplanev1.InfraEnvClaimedCondition)).To(ContainSubstring("previous-uid"))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(infraEnv), infraEnv)).To(Succeed())
+		Expect(metav1.GetControllerOf(infraEnv).UID).To(Equal(previous.UID))
+		Expect(infraEnv.Annotations).NotTo(HaveKey(agentControlPlaneAnnotation))
+	})
+})
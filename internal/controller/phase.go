@@ -0,0 +1,45 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// updatePhase sets status.phase from the rest of the status, following the
+// order documented on the field: the phases of a deleted or failed control
+// plane win over the progress of the install.
+func updatePhase(acp *controlplanev1.AgentControlPlane) {
+	switch {
+	case !acp.DeletionTimestamp.IsZero():
+		// Without the finalizer the AgentControlPlane may be gone before its
+		// status is patched.
+		if controllerutil.ContainsFinalizer(acp, controlplanev1.AgentControlPlaneFinalizer) {
+			acp.Status.Phase = controlplanev1.AgentControlPlanePhaseDeleting
+		}
+	case acp.Status.FailureReason != "":
+		acp.Status.Phase = controlplanev1.AgentControlPlanePhaseFailed
+	case acp.Status.Ready:
+		acp.Status.Phase = controlplanev1.AgentControlPlanePhaseReady
+	case acp.Status.Initialized, int32(len(acp.Status.Agents)) >= desiredReplicas(acp):
+		acp.Status.Phase = controlplanev1.AgentControlPlanePhaseInstalling
+	default:
+		acp.Status.Phase = controlplanev1.AgentControlPlanePhaseProvisioning
+	}
+}
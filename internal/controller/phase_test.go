@@ -0,0 +1,80 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Phase", func() {
+	deleting := func(acp *controlplanev1.AgentControlPlane) {
+		acp.DeletionTimestamp = ptr.To(metav1.Now())
+		acp.Finalizers = []string{controlplanev1.AgentControlPlaneFinalizer}
+	}
+	failed := func(acp *controlplanev1.AgentControlPlane) {
+		acp.Status.FailureReason = controlplanev1.InstallFailedReason
+	}
+	ready := func(acp *controlplanev1.AgentControlPlane) {
+		acp.Status.Initialized = true
+		acp.Status.Ready = true
+	}
+	initialized := func(acp *controlplanev1.AgentControlPlane) {
+		acp.Status.Initialized = true
+	}
+	boundHosts := func(n int) func(*controlplanev1.AgentControlPlane) {
+		return func(acp *controlplanev1.AgentControlPlane) {
+			acp.Status.Agents = make([]controlplanev1.AgentSummary, n)
+		}
+	}
+
+	DescribeTable("is computed from the status",
+		func(phase controlplanev1.AgentControlPlanePhase, states ...func(*controlplanev1.AgentControlPlane)) {
+			acp := newAgentControlPlane("test-acp")
+			acp.Spec.Replicas = ptr.To[int32](3)
+			for _, state := range states {
+				state(acp)
+			}
+
+			updatePhase(acp)
+
+			Expect(acp.Status.Phase).To(Equal(phase))
+		},
+		Entry("without hosts", controlplanev1.AgentControlPlanePhaseProvisioning),
+		Entry("with fewer hosts bound than replicas", controlplanev1.AgentControlPlanePhaseProvisioning, boundHosts(2)),
+		Entry("with all the hosts bound", controlplanev1.AgentControlPlanePhaseInstalling, boundHosts(3)),
+		Entry("once installed but not yet reachable", controlplanev1.AgentControlPlanePhaseInstalling, initialized),
+		Entry("once ready", controlplanev1.AgentControlPlanePhaseReady, boundHosts(3), ready),
+		Entry("after a terminal failure", controlplanev1.AgentControlPlanePhaseFailed, boundHosts(3), failed),
+		Entry("while being deleted", controlplanev1.AgentControlPlanePhaseDeleting, ready, failed, deleting),
+	)
+
+	It("is left alone once the finalizer is removed", func() {
+		acp := newAgentControlPlane("test-acp")
+		acp.Status.Phase = controlplanev1.AgentControlPlanePhaseReady
+		acp.DeletionTimestamp = ptr.To(metav1.Now())
+
+		updatePhase(acp)
+
+		Expect(acp.Status.Phase).To(Equal(controlplanev1.AgentControlPlanePhaseReady))
+	})
+})
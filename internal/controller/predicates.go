@@ -0,0 +1,60 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// specOrMetadataChanged filters out the update events of an AgentControlPlane
+// that only change its status, such as the status patches of its own
+// reconciles, which would otherwise enqueue it again. Spec changes bump the
+// generation; the annotations requesting actions, the owner references
+// pointing at the Cluster, the finalizers and the deletion timestamp do
+// not, so their changes are let through as well.
+func specOrMetadataChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return true
+			}
+			old, updated := e.ObjectOld, e.ObjectNew
+			return old.GetGeneration() != updated.GetGeneration() ||
+				!reflect.DeepEqual(old.GetLabels(), updated.GetLabels()) ||
+				!reflect.DeepEqual(old.GetAnnotations(), updated.GetAnnotations()) ||
+				!reflect.DeepEqual(old.GetOwnerReferences(), updated.GetOwnerReferences()) ||
+				!reflect.DeepEqual(old.GetFinalizers(), updated.GetFinalizers()) ||
+				!old.GetDeletionTimestamp().Equal(updated.GetDeletionTimestamp())
+		},
+	}
+}
+
+// selected filters out the events of the AgentControlPlanes that r's
+// LabelSelector does not match.
+func (r *AgentControlPlaneReconciler) selected() predicate.Predicate {
+	return predicate.NewPredicateFuncs(r.selects)
+}
+
+// selects tells whether r reconciles obj.
+func (r *AgentControlPlaneReconciler) selects(obj client.Object) bool {
+	return r.LabelSelector == nil || r.LabelSelector.Matches(labels.Set(obj.GetLabels()))
+}
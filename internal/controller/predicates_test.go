@@ -0,0 +1,124 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("AgentControlPlane update filtering", func() {
+	var old *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		old = newAgentControlPlane("test-acp")
+		old.Generation = 1
+		old.ResourceVersion = "1"
+	})
+
+	passes := func(updated *controlplanev1.AgentControlPlane) bool {
+		return specOrMetadataChanged().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated})
+	}
+
+	It("drops status-only updates", func() {
+		updated := old.DeepCopy()
+		updated.ResourceVersion = "2"
+		updated.Status.Replicas = 3
+		conditions.MarkTrue(updated, clusterv1.ReadyCondition)
+
+		Expect(passes(updated)).To(BeFalse())
+	})
+
+	It("lets spec changes through", func() {
+		updated := old.DeepCopy()
+		updated.Spec.Replicas = ptr.To[int32](3)
+		updated.Generation = 2
+
+		Expect(passes(updated)).To(BeTrue())
+	})
+
+	It("lets the metadata changes the reconcile acts on through", func() {
+		annotated := old.DeepCopy()
+		annotated.Annotations = map[string]string{controlplanev1.RegenerateISOAnnotation: "1"}
+		Expect(passes(annotated)).To(BeTrue())
+
+		owned := old.DeepCopy()
+		setOwnerCluster(owned, newCluster("test-cluster"))
+		Expect(passes(owned)).To(BeTrue())
+
+		deleted := old.DeepCopy()
+		deleted.DeletionTimestamp = ptr.To(metav1.Now())
+		Expect(passes(deleted)).To(BeTrue())
+	})
+})
+
+var _ = Describe("AgentControlPlane label selection", func() {
+	ctx := context.Background()
+
+	var (
+		acp *controlplanev1.AgentControlPlane
+		r   *AgentControlPlaneReconciler
+	)
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, newCluster("test-cluster"))
+		r = &AgentControlPlaneReconciler{LabelSelector: labels.SelectorFromSet(labels.Set{"controller": "tenant-a"})}
+	})
+
+	It("filters out the events of the AgentControlPlanes it does not match", func() {
+		Expect(r.selected().Create(event.CreateEvent{Object: acp})).To(BeFalse())
+		Expect(r.selected().Update(event.UpdateEvent{ObjectOld: acp, ObjectNew: acp})).To(BeFalse())
+
+		acp.Labels = map[string]string{"controller": "tenant-a"}
+		Expect(r.selected().Create(event.CreateEvent{Object: acp})).To(BeTrue())
+	})
+
+	It("lets every event through without a selector", func() {
+		r.LabelSelector = nil
+		Expect(r.selected().Create(event.CreateEvent{Object: acp})).To(BeTrue())
+	})
+
+	It("never reconciles an AgentControlPlane without the label", func() {
+		c := newFakeClient(newTestScheme(), acp, newCluster("test-cluster"), newPullSecret())
+		r.Client, r.Scheme = c, c.Scheme()
+
+		res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(updated.Finalizers).To(BeEmpty())
+		Expect(updated.Status).To(Equal(acp.Status))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,138 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+const (
+	// proxyHTTPKey, proxyHTTPSKey and proxyNoProxyKey hold the proxy
+	// settings in the ConfigMap referenced by spec.proxyFrom.configMapRef.
+	proxyHTTPKey    = "httpProxy"
+	proxyHTTPSKey   = "httpsProxy"
+	proxyNoProxyKey = "noProxy"
+)
+
+// infraEnvProxy returns the proxy of the discovered hosts of acp: the one
+// derived through spec.proxyFrom, with the fields set in spec.proxy taking
+// precedence. It returns nil when acp asks for no proxy.
+func (r *AgentControlPlaneReconciler) infraEnvProxy(ctx context.Context, acp *controlplanev1.AgentControlPlane) (*aiv1beta1.Proxy, error) {
+	derived, err := r.derivedProxy(ctx, acp)
+	if err != nil {
+		return nil, err
+	}
+	return mergeProxy(derived, acp.Spec.Proxy), nil
+}
+
+// derivedProxy returns the proxy selected by spec.proxyFrom of acp. The owner
+// Cluster contributes nothing until it exists.
+func (r *AgentControlPlaneReconciler) derivedProxy(ctx context.Context, acp *controlplanev1.AgentControlPlane) (controlplanev1.Proxy, error) {
+	var proxy controlplanev1.Proxy
+	source := acp.Spec.ProxyFrom
+	if source == nil {
+		return proxy, nil
+	}
+
+	var noProxy []string
+	if ref := source.ConfigMapRef; ref != nil {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: acp.Namespace, Name: ref.Name}, configMap); err != nil {
+			return proxy, fmt.Errorf("reading proxy ConfigMap %s: %w", ref.Name, err)
+		}
+		proxy.HTTPProxy = configMap.Data[proxyHTTPKey]
+		proxy.HTTPSProxy = configMap.Data[proxyHTTPSKey]
+		noProxy = appendNoProxy(noProxy, configMap.Data[proxyNoProxyKey])
+	}
+	if source.ClusterNetwork {
+		cluster, err := util.GetOwnerCluster(ctx, r.Client, acp.ObjectMeta)
+		if client.IgnoreNotFound(err) != nil {
+			return proxy, fmt.Errorf("getting the owner Cluster: %w", err)
+		}
+		if cluster != nil {
+			noProxy = append(noProxy, clusterNoProxy(cluster)...)
+		}
+	}
+	proxy.NoProxy = strings.Join(noProxy, ",")
+	return proxy, nil
+}
+
+// clusterNoProxy returns the destinations of cluster's network that are
+// never reached through a proxy: its pod and service networks and its
+// service domain.
+func clusterNoProxy(cluster *clusterv1.Cluster) []string {
+	network := cluster.Spec.ClusterNetwork
+	if network == nil {
+		return nil
+	}
+	var noProxy []string
+	if network.Pods != nil {
+		noProxy = append(noProxy, network.Pods.CIDRBlocks...)
+	}
+	if network.Services != nil {
+		noProxy = append(noProxy, network.Services.CIDRBlocks...)
+	}
+	if network.ServiceDomain != "" {
+		noProxy = append(noProxy, "."+network.ServiceDomain)
+	}
+	return noProxy
+}
+
+// appendNoProxy appends the non-empty entries of the comma-separated list
+// noProxy to entries.
+func appendNoProxy(entries []string, noProxy string) []string {
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// mergeProxy returns derived with the fields set in explicit replacing its
+// own, or nil when neither sets anything.
+func mergeProxy(derived controlplanev1.Proxy, explicit *controlplanev1.Proxy) *aiv1beta1.Proxy {
+	if explicit != nil {
+		if explicit.HTTPProxy != "" {
+			derived.HTTPProxy = explicit.HTTPProxy
+		}
+		if explicit.HTTPSProxy != "" {
+			derived.HTTPSProxy = explicit.HTTPSProxy
+		}
+		if explicit.NoProxy != "" {
+			derived.NoProxy = explicit.NoProxy
+		}
+	}
+	if derived == (controlplanev1.Proxy{}) {
+		return nil
+	}
+	return &aiv1beta1.Proxy{
+		HTTPProxy:  derived.HTTPProxy,
+		HTTPSProxy: derived.HTTPSProxy,
+		NoProxy:    derived.NoProxy,
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// pullSecretRequeueInterval is how long to wait before checking a missing or
+// invalid pull secret again. Secrets are not watched.
+const pullSecretRequeueInterval = 30 * time.Second
+
+// reconcilePullSecret records on the PullSecretValidCondition whether the
+// pull secret of acp holds a docker config with credentials, which the
+// discovery image and the install fail without, and requeues until it does.
+func (r *AgentControlPlaneReconciler) reconcilePullSecret(ctx context.Context, acp *controlplanev1.AgentControlPlane) (ctrl.Result, error) {
+	requeue := ctrl.Result{RequeueAfter: pullSecretRequeueInterval}
+	ref := acp.Spec.PullSecretRef
+	if ref == nil {
+		conditions.MarkFalse(acp, controlplanev1.PullSecretValidCondition, controlplanev1.PullSecretNotFoundReason,
+			clusterv1.ConditionSeverityWarning, "spec.pullSecretRef is not set")
+		return ctrl.Result{}, nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: acp.Namespace, Name: ref.Name}, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		conditions.MarkFalse(acp, controlplanev1.PullSecretValidCondition, controlplanev1.PullSecretNotFoundReason,
+			clusterv1.ConditionSeverityWarning, "Pull secret %s not found", ref.Name)
+		return requeue, nil
+	case err != nil:
+		return ctrl.Result{}, err
+	}
+
+	if err := validateDockerConfig(secret); err != nil {
+		conditions.MarkFalse(acp, controlplanev1.PullSecretValidCondition, controlplanev1.PullSecretInvalidReason,
+			clusterv1.ConditionSeverityError, "Pull secret %s %v", ref.Name, err)
+		return requeue, nil
+	}
+	conditions.MarkTrue(acp, controlplanev1.PullSecretValidCondition)
+	return ctrl.Result{}, nil
+}
+
+// dockerConfig is the content of a .dockerconfigjson key.
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+}
+
+// validateDockerConfig checks that secret holds a docker config with at
+// least one registry, each with base64-encoded "user:password" credentials.
+// The returned error completes a sentence about the secret.
+func validateDockerConfig(secret *corev1.Secret) error {
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return fmt.Errorf("has no %s key", corev1.DockerConfigJsonKey)
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("is not valid JSON: %v", err)
+	}
+	if len(config.Auths) == 0 {
+		return fmt.Errorf("has no registry credentials in auths")
+	}
+
+	var invalid []string
+	for registry, entry := range config.Auths {
+		credentials, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil || !strings.Contains(string(credentials), ":") {
+			invalid = append(invalid, registry)
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("has no valid auth for %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Pull secret validation", func() {
+	ctx := context.Background()
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, newCluster("test-cluster"))
+	})
+
+	reconcileACP := func(objs ...client.Object) (reconcile.Result, *controlplanev1.AgentControlPlane) {
+		c := newFakeClient(newTestScheme(), append(objs, acp, newCluster("test-cluster"))...)
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return result, updated
+	}
+
+	withDockerConfig := func(data map[string][]byte) *corev1.Secret {
+		secret := newPullSecret()
+		secret.Data = data
+		return secret
+	}
+
+	It("accepts a valid pull secret", func() {
+		result, updated := reconcileACP(newPullSecret())
+
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(conditions.IsTrue(updated, controlplanev1.PullSecretValidCondition)).To(BeTrue())
+	})
+
+	It("requeues while the pull secret is missing", func() {
+		result, updated := reconcileACP()
+
+		Expect(result.RequeueAfter).To(Equal(pullSecretRequeueInterval))
+		condition := conditions.Get(updated, controlplanev1.PullSecretValidCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.PullSecretNotFoundReason))
+		Expect(condition.Message).To(Equal("Pull secret pull-secret not found"))
+	})
+
+	It("recovers once a missing pull secret appears", func() {
+		acp.Spec.InfraEnvNamespace = "discovery"
+		c := newFakeClient(newTestScheme(), acp, newCluster("test-cluster"))
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)}
+
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(pullSecretRequeueInterval))
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(conditions.GetReason(updated, controlplanev1.PullSecretValidCondition)).To(Equal(controlplanev1.PullSecretNotFoundReason))
+		Expect(updated.Status.InfraEnvCreated).To(BeFalse())
+
+		Expect(c.Create(ctx, newPullSecret())).To(Succeed())
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		Expect(conditions.IsTrue(updated, controlplanev1.PullSecretValidCondition)).To(BeTrue())
+		Expect(updated.Status.InfraEnvCreated).To(BeTrue())
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "discovery", Name: mirroredPullSecretName(acp)}, &corev1.Secret{})).To(Succeed())
+	})
+
+	DescribeTable("reports invalid pull secrets",
+		func(data map[string][]byte, message string) {
+			result, updated := reconcileACP(withDockerConfig(data))
+
+			Expect(result.RequeueAfter).To(Equal(pullSecretRequeueInterval))
+			condition := conditions.Get(updated, controlplanev1.PullSecretValidCondition)
+			Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(controlplanev1.PullSecretInvalidReason))
+			Expect(condition.Message).To(HavePrefix(message))
+		},
+		Entry("missing the .dockerconfigjson key",
+			map[string][]byte{".dockercfg": []byte(`{}`)},
+			"Pull secret pull-secret has no .dockerconfigjson key"),
+		Entry("holding malformed JSON",
+			map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":`)},
+			"Pull secret pull-secret is not valid JSON: "),
+		Entry("without credentials",
+			map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+			"Pull secret pull-secret has no registry credentials in auths"),
+		Entry("with an undecodable auth",
+			map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"dXNlcjpwYXNzd29yZA=="},"registry.example.com":{"auth":"not base64"}}}`)},
+			"Pull secret pull-secret has no valid auth for registry.example.com"),
+	)
+})
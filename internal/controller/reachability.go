@@ -0,0 +1,77 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// controlPlaneDialTimeout bounds how long probing the control plane endpoint
+// may take.
+const controlPlaneDialTimeout = 5 * time.Second
+
+// reconcileReachability probes the Cluster's control plane endpoint once the
+// install completed, and marks the control plane ready once it is reachable.
+// Ready is never reset: an endpoint that becomes unreachable later is only
+// reported on the ControlPlaneReachable condition, and the returned result
+// requeues to probe it again.
+func (r *AgentControlPlaneReconciler) reconcileReachability(ctx context.Context, acp *controlplanev1.AgentControlPlane, cluster *clusterv1.Cluster) ctrl.Result {
+	if !acp.Status.Initialized {
+		return ctrl.Result{}
+	}
+
+	endpoint := cluster.Spec.ControlPlaneEndpoint
+	if err := dialControlPlane(ctx, endpoint); err != nil {
+		log.FromContext(ctx).Info("control plane endpoint is not reachable", "endpoint", endpoint.String(),
+			"error", err.Error(), "requeueAfter", reachabilityRequeueInterval)
+		conditions.MarkFalse(acp, controlplanev1.ControlPlaneReachableCondition,
+			controlplanev1.ControlPlaneUnreachableReason, clusterv1.ConditionSeverityWarning, "%s", err.Error())
+		return ctrl.Result{RequeueAfter: reachabilityRequeueInterval}
+	}
+
+	conditions.MarkTrue(acp, controlplanev1.ControlPlaneReachableCondition)
+	if !acp.Status.Ready {
+		acp.Status.Ready = true
+		observeTimeToReady(acp, r.clock())
+	}
+	return ctrl.Result{}
+}
+
+// dialControlPlane checks that endpoint accepts TCP connections.
+func dialControlPlane(ctx context.Context, endpoint clusterv1.APIEndpoint) error {
+	if !endpoint.IsValid() {
+		return errors.New("the Cluster has no control plane endpoint")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, controlPlaneDialTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", endpoint.String())
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+// timeToReadyObservations returns the count and sum of the time to ready
+// observations, as exported by the controller-runtime metrics registry.
+func timeToReadyObservations() (uint64, float64) {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() == "agentcontrolplane_time_to_ready_seconds" {
+			histogram := family.GetMetric()[0].GetHistogram()
+			return histogram.GetSampleCount(), histogram.GetSampleSum()
+		}
+	}
+	return 0, 0
+}
+
+var _ = Describe("Control plane reachability", func() {
+	ctx := context.Background()
+
+	var (
+		acp      *controlplanev1.AgentControlPlane
+		cluster  *clusterv1.Cluster
+		listener net.Listener
+	)
+
+	// endpointOf returns the API endpoint served by l.
+	endpointOf := func(l net.Listener) clusterv1.APIEndpoint {
+		host, port, err := net.SplitHostPort(l.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		portNumber, err := strconv.Atoi(port)
+		Expect(err).NotTo(HaveOccurred())
+		return clusterv1.APIEndpoint{Host: host, Port: int32(portNumber)}
+	}
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() { _ = listener.Close() })
+
+		cluster = newCluster("test-cluster")
+		cluster.Spec.ControlPlaneEndpoint = endpointOf(listener)
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, cluster)
+	})
+
+	newCompletedInstall := func() *hiveext.AgentClusterInstall {
+		return &hiveext.AgentClusterInstall{
+			ObjectMeta: acp.ObjectMeta,
+			Spec: hiveext.AgentClusterInstallSpec{
+				ClusterDeploymentRef: corev1.LocalObjectReference{Name: acp.Name},
+			},
+			Status: hiveext.AgentClusterInstallStatus{Conditions: []hivev1.ClusterInstallCondition{
+				{Type: hiveext.ClusterCompletedCondition, Status: corev1.ConditionTrue},
+			}},
+		}
+	}
+
+	reconcileACP := func(c client.Client) (reconcile.Result, *controlplanev1.AgentControlPlane) {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return result, updated
+	}
+
+	It("marks the control plane ready once the endpoint accepts connections", func() {
+		c := newFakeClient(newTestScheme(), acp, cluster, newCompletedInstall())
+
+		_, updated := reconcileACP(c)
+
+		Expect(conditions.IsTrue(updated, controlplanev1.ControlPlaneReachableCondition)).To(BeTrue())
+		Expect(updated.Status.Ready).To(BeTrue())
+	})
+
+	It("observes the time to ready once", func() {
+		acp = newAgentControlPlane("time-to-ready")
+		created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		acp.CreationTimestamp = metav1.NewTime(created)
+		setOwnerCluster(acp, cluster)
+		c := newFakeClient(newTestScheme(), acp, cluster, newCompletedInstall())
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme(),
+			now: func() time.Time { return created.Add(45 * time.Minute) }}
+		count, sum := timeToReadyObservations()
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		updatedCount, updatedSum := timeToReadyObservations()
+		Expect(updatedCount).To(Equal(count + 1))
+		Expect(updatedSum - sum).To(BeNumerically("~", (45 * time.Minute).Seconds()))
+
+		// Neither reconciling the ready control plane again, nor a reconcile
+		// reading it stale, observes it again.
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		acp.Status.Ready = false
+		observeTimeToReady(acp, created.Add(time.Hour))
+		updatedCount, _ = timeToReadyObservations()
+		Expect(updatedCount).To(Equal(count + 1))
+	})
+
+	It("does not mark the control plane ready while the endpoint is unreachable", func() {
+		Expect(listener.Close()).To(Succeed())
+		c := newFakeClient(newTestScheme(), acp, cluster, newCompletedInstall())
+
+		result, updated := reconcileACP(c)
+
+		Expect(result.RequeueAfter).To(Equal(reachabilityRequeueInterval))
+		condition := conditions.Get(updated, controlplanev1.ControlPlaneReachableCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.ControlPlaneUnreachableReason))
+		Expect(updated.Status.Ready).To(BeFalse())
+	})
+
+	It("keeps the control plane ready when the endpoint becomes unreachable", func() {
+		acp.Status.Initialized = true
+		acp.Status.Ready = true
+		Expect(listener.Close()).To(Succeed())
+		c := newFakeClient(newTestScheme(), acp, cluster, newCompletedInstall())
+
+		_, updated := reconcileACP(c)
+
+		Expect(conditions.IsFalse(updated, controlplanev1.ControlPlaneReachableCondition)).To(BeTrue())
+		Expect(updated.Status.Ready).To(BeTrue())
+	})
+
+	It("does not probe before the install completed", func() {
+		c := newFakeClient(newTestScheme(), acp, cluster)
+
+		_, updated := reconcileACP(c)
+
+		Expect(conditions.Has(updated, controlplanev1.ControlPlaneReachableCondition)).To(BeFalse())
+		Expect(updated.Status.Ready).To(BeFalse())
+	})
+})
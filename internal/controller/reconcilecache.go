@@ -0,0 +1,181 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// reconcileCacheTTL is how long a reconcile that left nothing to do is
+// trusted to still hold. Inputs that are not watched, such as referenced
+// Secrets and ConfigMaps or the workload cluster's Nodes, are picked up by
+// the first reconcile after it.
+const reconcileCacheTTL = 5 * time.Minute
+
+// reconciledState is what a reconcile that left nothing to do started from.
+type reconciledState struct {
+	key                    types.NamespacedName
+	generation             int64
+	annotations            map[string]string
+	clusterResourceVersion string
+	at                     time.Time
+}
+
+// reconcileCache remembers, per AgentControlPlane UID, the state of the last
+// reconcile that succeeded without asking to be requeued, so that reconciles
+// starting from that same state, like those triggered by the controller's
+// own status patches, can return early. Entries are invalidated whenever a
+// watched child object of the AgentControlPlane changes.
+type reconcileCache struct {
+	mu    sync.Mutex
+	state map[types.UID]reconciledState
+}
+
+// reconciledState returns the state a reconcile of acp starts from, or false
+// when it cannot be told, e.g. because the owner Cluster cannot be read.
+func (r *AgentControlPlaneReconciler) reconciledState(ctx context.Context, acp *controlplanev1.AgentControlPlane) (reconciledState, bool) {
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, acp.ObjectMeta)
+	if err != nil {
+		return reconciledState{}, false
+	}
+	state := reconciledState{
+		key:         types.NamespacedName{Namespace: acp.Namespace, Name: acp.Name},
+		generation:  acp.Generation,
+		annotations: maps.Clone(acp.Annotations),
+		at:          r.clock(),
+	}
+	if cluster != nil {
+		state.clusterResourceVersion = cluster.ResourceVersion
+	}
+	return state, true
+}
+
+// unchanged returns whether the last reconcile of acp left nothing to do
+// starting from state, no longer than reconcileCacheTTL ago.
+func (c *reconcileCache) unchanged(acp *controlplanev1.AgentControlPlane, state reconciledState) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last, ok := c.state[acp.UID]
+	return ok &&
+		last.generation == state.generation &&
+		maps.Equal(last.annotations, state.annotations) &&
+		last.clusterResourceVersion == state.clusterResourceVersion &&
+		state.at.Sub(last.at) < reconcileCacheTTL
+}
+
+// record notes that a reconcile of acp starting from state left nothing to
+// do.
+func (c *reconcileCache) record(acp *controlplanev1.AgentControlPlane, state reconciledState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == nil {
+		c.state = map[types.UID]reconciledState{}
+	}
+	c.state[acp.UID] = state
+}
+
+// forget drops what is remembered about acp.
+func (c *reconcileCache) forget(acp *controlplanev1.AgentControlPlane) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.state, acp.UID)
+}
+
+// invalidate drops what is remembered about the AgentControlPlane named key.
+func (c *reconcileCache) invalidate(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for uid, state := range c.state {
+		if state.key == key {
+			delete(c.state, uid)
+		}
+	}
+}
+
+// invalidating wraps the handler of a child object watch so that the
+// AgentControlPlanes it enqueues are reconciled in full.
+func (c *reconcileCache) invalidating(h handler.EventHandler) handler.EventHandler {
+	return invalidatingHandler{EventHandler: h, cache: c}
+}
+
+type invalidatingHandler struct {
+	handler.EventHandler
+	cache *reconcileCache
+}
+
+func (h invalidatingHandler) queue(q workqueue.RateLimitingInterface) workqueue.RateLimitingInterface {
+	return invalidatingQueue{RateLimitingInterface: q, cache: h.cache}
+}
+
+func (h invalidatingHandler) Create(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Create(ctx, e, h.queue(q))
+}
+
+func (h invalidatingHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Update(ctx, e, h.queue(q))
+}
+
+func (h invalidatingHandler) Delete(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Delete(ctx, e, h.queue(q))
+}
+
+func (h invalidatingHandler) Generic(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Generic(ctx, e, h.queue(q))
+}
+
+// invalidatingQueue invalidates the cache entry of each request added.
+type invalidatingQueue struct {
+	workqueue.RateLimitingInterface
+	cache *reconcileCache
+}
+
+func (q invalidatingQueue) invalidate(item interface{}) {
+	if req, ok := item.(reconcile.Request); ok {
+		q.cache.invalidate(req.NamespacedName)
+	}
+}
+
+func (q invalidatingQueue) Add(item interface{}) {
+	q.invalidate(item)
+	q.RateLimitingInterface.Add(item)
+}
+
+func (q invalidatingQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.invalidate(item)
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
+
+func (q invalidatingQueue) AddRateLimited(item interface{}) {
+	q.invalidate(item)
+	q.RateLimitingInterface.AddRateLimited(item)
+}
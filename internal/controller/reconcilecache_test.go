@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+)
+
+var _ = Describe("Reconcile cache", func() {
+	ctx := context.Background()
+
+	var (
+		acp     *controlplanev1.AgentControlPlane
+		cluster *clusterv1.Cluster
+		writes  int
+		c       client.Client
+		r       *AgentControlPlaneReconciler
+	)
+
+	countWrite := func() { writes++ }
+
+	BeforeEach(func() {
+		cluster = newCluster("test-cluster")
+		cluster.Status.InfrastructureReady = true
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, cluster)
+
+		writes = 0
+		c = newFakeClientBuilder(newTestScheme(), append(newAvailableAgents(acp, 3), acp, cluster, newPullSecret())...).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					countWrite()
+					return c.Create(ctx, obj, opts...)
+				},
+				Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					countWrite()
+					return c.Update(ctx, obj, opts...)
+				},
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					countWrite()
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+				Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+					countWrite()
+					return c.Delete(ctx, obj, opts...)
+				},
+				SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+					countWrite()
+					return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+				},
+			}).
+			Build()
+		r = &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+	})
+
+	reconcileACP := func() reconcile.Result {
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	It("does no writes when reconciling again with nothing changed", func() {
+		Expect(reconcileACP()).To(BeZero())
+		Expect(writes).NotTo(BeZero())
+
+		writes = 0
+		Expect(reconcileACP()).To(BeZero())
+		Expect(writes).To(BeZero())
+	})
+
+	It("reconciles in full once the spec changes", func() {
+		reconcileACP()
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		updated.Spec.SSHAuthorizedKey = "ssh-ed25519 key"
+		// The fake client does not bump it on spec changes.
+		updated.Generation++
+		Expect(c.Update(ctx, updated)).To(Succeed())
+
+		writes = 0
+		reconcileACP()
+		Expect(writes).NotTo(BeZero())
+	})
+
+	It("reconciles in full once a watched child changes", func() {
+		reconcileACP()
+
+		agentClusterInstall := &hiveext.AgentClusterInstall{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), agentClusterInstall)).To(Succeed())
+		Expect(c.Delete(ctx, agentClusterInstall)).To(Succeed())
+
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer queue.ShutDown()
+		h := r.reconciles.invalidating(handler.EnqueueRequestForOwner(c.Scheme(), newRESTMapper(c.Scheme()),
+			&controlplanev1.AgentControlPlane{}, handler.OnlyControllerOwner()))
+		h.Delete(ctx, event.DeleteEvent{Object: agentClusterInstall}, queue)
+		Expect(queue.Len()).To(Equal(1))
+
+		reconcileACP()
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &hiveext.AgentClusterInstall{})).To(Succeed())
+	})
+})
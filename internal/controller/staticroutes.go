@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+const (
+	// staticRoutesKey holds the static routes of the discovered hosts in a
+	// static routes ConfigMap.
+	staticRoutesKey = "routes"
+
+	// nmStateConfigLabel selects the NMStateConfig of an AgentControlPlane
+	// from its InfraEnv, with the AgentControlPlane name as its value.
+	nmStateConfigLabel = "controlplane.openshift.io/agent-control-plane"
+)
+
+// staticRoute is a route of a static routes ConfigMap.
+type staticRoute struct {
+	Destination      string `json:"destination"`
+	NextHopAddress   string `json:"nextHopAddress"`
+	NextHopInterface string `json:"nextHopInterface"`
+	Metric           *int32 `json:"metric,omitempty"`
+}
+
+// nmStateRoute is a route of an nmstate config.
+type nmStateRoute struct {
+	Destination      string `json:"destination"`
+	NextHopAddress   string `json:"next-hop-address"`
+	NextHopInterface string `json:"next-hop-interface"`
+	Metric           *int32 `json:"metric,omitempty"`
+}
+
+// nmStateConfigSelector returns the selector of the NMStateConfig of acp.
+func nmStateConfigSelector(acp *controlplanev1.AgentControlPlane) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{nmStateConfigLabel: acp.Name}}
+}
+
+// reconcileStaticRoutes applies the static routes held by the ConfigMap
+// referenced by spec.staticRoutesRef as an NMStateConfig next to the InfraEnv,
+// and records on the StaticRoutesConfiguredCondition whether it could. It
+// returns the selector of the NMStateConfig for the InfraEnv, or nil, after
+// deleting the NMStateConfig, when no routes are referenced. A missing or
+// invalid ConfigMap leaves the NMStateConfig applied last in place.
+func (r *AgentControlPlaneReconciler) reconcileStaticRoutes(ctx context.Context, acp *controlplanev1.AgentControlPlane) (*metav1.LabelSelector, error) {
+	key := infraEnvKey(acp)
+	ref := acp.Spec.StaticRoutesRef
+	if ref == nil {
+		conditions.Delete(acp, controlplanev1.StaticRoutesConfiguredCondition)
+		nmStateConfig := &aiv1beta1.NMStateConfig{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		return nil, r.deleteAnnotatedFor(ctx, acp, nmStateConfig)
+	}
+	selector := nmStateConfigSelector(acp)
+
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: acp.Namespace, Name: ref.Name}, configMap)
+	if apierrors.IsNotFound(err) {
+		conditions.MarkFalse(acp, controlplanev1.StaticRoutesConfiguredCondition, controlplanev1.StaticRoutesConfigMapMissingReason,
+			clusterv1.ConditionSeverityWarning, "Static routes ConfigMap %s not found", ref.Name)
+		return selector, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	routes, problems := parseStaticRoutesConfigMap(configMap)
+	if len(problems) > 0 {
+		conditions.MarkFalse(acp, controlplanev1.StaticRoutesConfiguredCondition, controlplanev1.StaticRoutesConfigMapInvalidReason,
+			clusterv1.ConditionSeverityError, "Static routes ConfigMap %s is invalid: %s", ref.Name, strings.Join(problems, "; "))
+		return selector, nil
+	}
+
+	netConfig, err := nmStateRoutesConfig(routes)
+	if err != nil {
+		return nil, err
+	}
+	desired := &aiv1beta1.NMStateConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels:    selector.MatchLabels,
+		},
+		Spec: aiv1beta1.NMStateConfigSpec{NetConfig: netConfig},
+	}
+	setACPAnnotation(desired, acp)
+	if key.Namespace == acp.Namespace {
+		if err := controllerutil.SetControllerReference(acp, desired, r.Scheme); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.applyObject(ctx, desired, &aiv1beta1.NMStateConfig{}, func(existing client.Object) error {
+		return checkNotAnnotatedForOther(acp, existing)
+	}); err != nil {
+		return nil, fmt.Errorf("applying NMStateConfig %s: %w", key, err)
+	}
+	conditions.MarkTrue(acp, controlplanev1.StaticRoutesConfiguredCondition)
+	return selector, nil
+}
+
+// parseStaticRoutesConfigMap returns the static routes held by configMap,
+// and what is wrong with them.
+func parseStaticRoutesConfigMap(configMap *corev1.ConfigMap) ([]staticRoute, []string) {
+	var problems []string
+	var routes []staticRoute
+	data, ok := configMap.Data[staticRoutesKey]
+	switch err := yaml.UnmarshalStrict([]byte(data), &routes); {
+	case !ok:
+		problems = append(problems, fmt.Sprintf("missing key %q", staticRoutesKey))
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("%s is not a list of routes: %v", staticRoutesKey, err))
+	case len(routes) == 0:
+		problems = append(problems, fmt.Sprintf("%s holds no route", staticRoutesKey))
+	}
+	for i, route := range routes {
+		if problem := validateStaticRoute(route); problem != "" {
+			problems = append(problems, fmt.Sprintf("route %d %s", i, problem))
+		}
+	}
+
+	var unknown []string
+	for key := range configMap.Data {
+		if key != staticRoutesKey {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	if len(unknown) > 0 {
+		problems = append(problems, fmt.Sprintf("unknown keys %s", strings.Join(unknown, ", ")))
+	}
+	return routes, problems
+}
+
+// validateStaticRoute describes what is wrong with route, or returns "" when
+// it is valid.
+func validateStaticRoute(route staticRoute) string {
+	destination, err := netip.ParsePrefix(route.Destination)
+	if err != nil {
+		return fmt.Sprintf("has an invalid destination %q", route.Destination)
+	}
+	nextHop, err := netip.ParseAddr(route.NextHopAddress)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("has an invalid nextHopAddress %q", route.NextHopAddress)
+	case nextHop.Is4() != destination.Addr().Is4():
+		return "has a nextHopAddress of another IP family than its destination"
+	case route.NextHopInterface == "":
+		return "has no nextHopInterface"
+	case route.Metric != nil && *route.Metric < 0:
+		return "has a negative metric"
+	}
+	return ""
+}
+
+// nmStateRoutesConfig returns the nmstate config setting routes.
+func nmStateRoutesConfig(routes []staticRoute) (aiv1beta1.NetConfig, error) {
+	config := make([]nmStateRoute, 0, len(routes))
+	for _, route := range routes {
+		config = append(config, nmStateRoute(route))
+	}
+	raw, err := yaml.Marshal(map[string]interface{}{
+		"routes": map[string]interface{}{"config": config},
+	})
+	if err != nil {
+		return aiv1beta1.NetConfig{}, fmt.Errorf("encoding the nmstate config: %w", err)
+	}
+	return aiv1beta1.NetConfig{Raw: raw}, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("Static routes", func() {
+	ctx := context.Background()
+
+	const routes = `
+- destination: 10.0.0.0/8
+  nextHopAddress: 192.168.111.1
+  nextHopInterface: eth0
+  metric: 100
+- destination: fd00::/8
+  nextHopAddress: fd2e:6f44:5dd8::1
+  nextHopInterface: eth1
+`
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+		acp.Spec.StaticRoutesRef = &corev1.LocalObjectReference{Name: "static-routes"}
+	})
+
+	newConfigMap := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "static-routes", Namespace: testNamespace},
+			Data:       data,
+		}
+	}
+
+	reconcileACP := func(c client.Client) *controlplanev1.AgentControlPlane {
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return updated
+	}
+
+	It("applies the routes through an NMStateConfig selected by the InfraEnv", func() {
+		c := newFakeClient(newTestScheme(), acp, newConfigMap(map[string]string{"routes": routes}))
+		updated := reconcileACP(c)
+		Expect(conditions.IsTrue(updated, controlplanev1.StaticRoutesConfiguredCondition)).To(BeTrue())
+
+		nmStateConfig := &aiv1beta1.NMStateConfig{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), nmStateConfig)).To(Succeed())
+		Expect(metav1.IsControlledBy(nmStateConfig, acp)).To(BeTrue())
+		Expect(nmStateConfig.Spec.NetConfig.MarshalJSON()).To(MatchJSON(`{"routes":{"config":[
+			{"destination":"10.0.0.0/8","next-hop-address":"192.168.111.1","next-hop-interface":"eth0","metric":100},
+			{"destination":"fd00::/8","next-hop-address":"fd2e:6f44:5dd8::1","next-hop-interface":"eth1"}
+		]}}`))
+
+		infraEnv := &aiv1beta1.InfraEnv{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+		Expect(infraEnv.Spec.NMStateConfigLabelSelector).NotTo(BeNil())
+		Expect(infraEnv.Spec.NMStateConfigLabelSelector.MatchLabels).To(Equal(nmStateConfig.Labels))
+	})
+
+	It("deletes the NMStateConfig once the routes are no longer referenced", func() {
+		c := newFakeClient(newTestScheme(), acp, newConfigMap(map[string]string{"routes": routes}))
+		reconcileACP(c)
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), acp)).To(Succeed())
+		acp.Spec.StaticRoutesRef = nil
+		Expect(c.Update(ctx, acp)).To(Succeed())
+		updated := reconcileACP(c)
+
+		Expect(conditions.Has(updated, controlplanev1.StaticRoutesConfiguredCondition)).To(BeFalse())
+		err := c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.NMStateConfig{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("selects the NMStateConfigs of the user's selector instead of its own", func() {
+		acp.Spec.StaticRoutesRef = nil
+		acp.Spec.NMStateConfigLabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"hosts": "test-acp"}}
+		c := newFakeClient(newTestScheme(), acp)
+		reconcileACP(c)
+
+		infraEnv := &aiv1beta1.InfraEnv{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+		Expect(infraEnv.Spec.NMStateConfigLabelSelector).To(Equal(acp.Spec.NMStateConfigLabelSelector))
+		err := c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.NMStateConfig{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("reports a malformed ConfigMap", func() {
+		c := newFakeClient(newTestScheme(), acp, newConfigMap(map[string]string{
+			"routes": `
+- destination: 10.0.0.0/8
+  nextHopAddress: fd2e:6f44:5dd8::1
+  nextHopInterface: eth0
+- destination: 10.0.0.0
+  nextHopAddress: 192.168.111.1
+`,
+			"gateway": "192.168.111.1",
+		}))
+
+		condition := conditions.Get(reconcileACP(c), controlplanev1.StaticRoutesConfiguredCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.StaticRoutesConfigMapInvalidReason))
+		Expect(condition.Message).To(Equal("Static routes ConfigMap static-routes is invalid: " +
+			"route 0 has a nextHopAddress of another IP family than its destination; " +
+			`route 1 has an invalid destination "10.0.0.0"; unknown keys gateway`))
+		err := c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.NMStateConfig{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("reports a missing ConfigMap", func() {
+		c := newFakeClient(newTestScheme(), acp)
+
+		condition := conditions.Get(reconcileACP(c), controlplanev1.StaticRoutesConfiguredCondition)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(controlplanev1.StaticRoutesConfigMapMissingReason))
+	})
+})
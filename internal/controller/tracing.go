@@ -0,0 +1,57 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans emitted by this package.
+const tracerName = "github.com/openshift-assisted/agent-controlplane-provider/internal/controller"
+
+// tracer returns the tracer spans are started from. It falls back to the
+// global TracerProvider, which is a no-op unless an exporter is configured.
+func (r *AgentControlPlaneReconciler) tracer() trace.Tracer {
+	if r.TracerProvider != nil {
+		return r.TracerProvider.Tracer(tracerName)
+	}
+	return otel.Tracer(tracerName)
+}
+
+// traced runs fn in a span named "AgentControlPlane.<name>", recording the
+// error it returns on the span.
+func (r *AgentControlPlaneReconciler) traced(ctx context.Context, name string, fn func(context.Context) error) error {
+	ctx, span := r.tracer().Start(ctx, "AgentControlPlane."+name)
+	defer span.End()
+
+	err := fn(ctx)
+	recordError(span, err)
+	return err
+}
+
+// recordError marks span as failed with err, if any.
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("Reconcile tracing", func() {
+	ctx := context.Background()
+
+	var (
+		acp      *controlplanev1.AgentControlPlane
+		recorder *tracetest.SpanRecorder
+	)
+
+	BeforeEach(func() {
+		cluster := newCluster("test-cluster")
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, cluster)
+		recorder = tracetest.NewSpanRecorder()
+	})
+
+	reconcileACP := func(c client.Client) error {
+		r := &AgentControlPlaneReconciler{
+			Client:         c,
+			Scheme:         c.Scheme(),
+			TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+		}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		return err
+	}
+
+	spanNames := func() []string {
+		var names []string
+		for _, span := range recorder.Ended() {
+			names = append(names, span.Name())
+		}
+		return names
+	}
+
+	It("records a span for each reconcile step under the Reconcile span", func() {
+		Expect(reconcileACP(newFakeClient(newTestScheme(), acp, newCluster("test-cluster")))).To(Succeed())
+
+		Expect(spanNames()).To(ConsistOf(
+			"AgentControlPlane.Reconcile",
+			"AgentControlPlane.reconcileDependencies",
+			"AgentControlPlane.reconcilePullSecret",
+			"AgentControlPlane.reconcileInfraEnv",
+			"AgentControlPlane.reconcileControlPlaneEndpoint",
+			"AgentControlPlane.reconcileClusterInstall",
+			"AgentControlPlane.reconcileKubeconfig",
+			"AgentControlPlane.reconcileReachability",
+			"AgentControlPlane.reconcileAgents",
+			"AgentControlPlane.reconcileMachines",
+			"AgentControlPlane.reconcileUnexpectedMachines",
+			"AgentControlPlane.reconcileNodes",
+			"AgentControlPlane.reconcileClusterStatus",
+			"AgentControlPlane.patchStatus",
+		))
+
+		var root sdktrace.ReadOnlySpan
+		for _, span := range recorder.Ended() {
+			if span.Name() == "AgentControlPlane.Reconcile" {
+				root = span
+			}
+		}
+		for _, span := range recorder.Ended() {
+			if span != root {
+				Expect(span.Parent().SpanID()).To(Equal(root.SpanContext().SpanID()), span.Name())
+			}
+		}
+	})
+
+	It("records the error status of a failed step", func() {
+		c := newFakeClientBuilder(newTestScheme(), acp).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if isInfraEnv(obj) {
+						return errors.New("injected create failure")
+					}
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).
+			Build()
+
+		Expect(reconcileACP(c)).To(MatchError(ContainSubstring("injected create failure")))
+
+		for _, span := range recorder.Ended() {
+			switch span.Name() {
+			case "AgentControlPlane.reconcileInfraEnv", "AgentControlPlane.Reconcile":
+				Expect(span.Status().Code).To(Equal(codes.Error), span.Name())
+				Expect(span.Status().Description).To(ContainSubstring("injected create failure"))
+			default:
+				Expect(span.Status().Code).To(Equal(codes.Unset), span.Name())
+			}
+		}
+	})
+})
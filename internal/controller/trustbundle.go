@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	hiveext "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/hiveextension/v1beta1"
+)
+
+const (
+	// additionalCAsManifestKey is the key, and so the manifest file name, of
+	// the additional CAs manifest in the generated manifests ConfigMap.
+	additionalCAsManifestKey = "additional-trusted-cas.yaml"
+	// additionalCAsBundleKey is the key of the CA bundle in the installed
+	// ConfigMap, the one the cluster proxy's trustedCA expects.
+	additionalCAsBundleKey = "ca-bundle.crt"
+)
+
+// additionalCAsConfigMapName returns the name of the manifests ConfigMap
+// generated from the additional CAs of acp.
+func additionalCAsConfigMapName(acp *controlplanev1.AgentControlPlane) string {
+	return acp.Name + "-additional-cas"
+}
+
+// reconcileAdditionalCAs writes spec.additionalCAs as a manifests ConfigMap,
+// controlled by acp, installing them as the additional-trusted-cas ConfigMap
+// of the openshift-config namespace. It returns the reference to add to the
+// AgentClusterInstall, or nil when acp has no additional CAs.
+func (r *AgentControlPlaneReconciler) reconcileAdditionalCAs(ctx context.Context, acp *controlplanev1.AgentControlPlane) (*hiveext.ManifestsConfigMapReference, error) {
+	if len(acp.Spec.AdditionalCAs) == 0 {
+		return nil, nil
+	}
+
+	manifest, err := yaml.Marshal(&corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "additional-trusted-cas", Namespace: "openshift-config"},
+		Data:       map[string]string{additionalCAsBundleKey: mergeTrustBundles(acp.Spec.AdditionalCAs...)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	desired := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      additionalCAsConfigMapName(acp),
+			Namespace: acp.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         controlplanev1.GroupVersion.String(),
+				Kind:               "AgentControlPlane",
+				Name:               acp.Name,
+				UID:                acp.UID,
+				Controller:         ptr.To(true),
+				BlockOwnerDeletion: ptr.To(true),
+			}},
+		},
+		Data: map[string]string{additionalCAsManifestKey: string(manifest)},
+	}
+	if err := r.applyObject(ctx, desired, &corev1.ConfigMap{}, nil); err != nil {
+		return nil, fmt.Errorf("writing the additional CAs manifest: %w", err)
+	}
+	return &hiveext.ManifestsConfigMapReference{Name: desired.Name}, nil
+}
+
+// additionalTrustBundle returns the certificates acp asks hosts to trust:
+// spec.additionalTrustBundle merged with the bundle read from
+// spec.additionalTrustBundleConfigMapRef. A missing optional ConfigMap or key
+// contributes nothing.
+func (r *AgentControlPlaneReconciler) additionalTrustBundle(ctx context.Context, acp *controlplanev1.AgentControlPlane) (string, error) {
+	ref := acp.Spec.AdditionalTrustBundleConfigMapRef
+	if ref == nil {
+		return mergeTrustBundles(acp.Spec.AdditionalTrustBundle), nil
+	}
+	optional := ptr.Deref(ref.Optional, false)
+
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: acp.Namespace, Name: ref.Name}, configMap)
+	switch {
+	case apierrors.IsNotFound(err) && optional:
+		return mergeTrustBundles(acp.Spec.AdditionalTrustBundle), nil
+	case err != nil:
+		return "", fmt.Errorf("reading additional trust bundle ConfigMap %s: %w", ref.Name, err)
+	}
+
+	bundle, ok := configMap.Data[ref.Key]
+	if !ok && !optional {
+		return "", fmt.Errorf("additional trust bundle ConfigMap %s has no key %q", ref.Name, ref.Key)
+	}
+	return mergeTrustBundles(acp.Spec.AdditionalTrustBundle, bundle), nil
+}
+
+// mergeTrustBundles concatenates the certificates of the PEM bundles, in
+// order, keeping only the first copy of each certificate. Anything that is
+// not a PEM-encoded certificate is dropped.
+func mergeTrustBundles(bundles ...string) string {
+	var merged bytes.Buffer
+	seen := map[string]bool{}
+	for _, bundle := range bundles {
+		rest := []byte(bundle)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" || seen[string(block.Bytes)] {
+				continue
+			}
+			seen[string(block.Bytes)] = true
+			// Headers are not part of the identity of a certificate.
+			_ = pem.Encode(&merged, &pem.Block{Type: block.Type, Bytes: block.Bytes})
+		}
+	}
+	return merged.String()
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("Additional trust bundle", func() {
+	ctx := context.Background()
+
+	// The certificates are not parsed, so any DER-like bytes will do.
+	certA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("certificate a")}))
+	certB := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("certificate b")}))
+	certC := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("certificate c")}))
+
+	It("merges PEM bundles without duplicate certificates", func() {
+		Expect(mergeTrustBundles(certA+certB, certB+"\n"+certC+certA)).To(Equal(certA + certB + certC))
+	})
+
+	It("drops PEM blocks that are not certificates", func() {
+		key := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}))
+		Expect(mergeTrustBundles("# comment\n" + certA + key)).To(Equal(certA))
+	})
+
+	It("sets the merged bundle on the InfraEnv", func() {
+		acp := newAgentControlPlane("test-acp")
+		acp.Spec.AdditionalTrustBundle = certA + certB
+		acp.Spec.AdditionalTrustBundleConfigMapRef = &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "trusted-ca"},
+			Key:                  "ca-bundle.crt",
+		}
+		trustedCA := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca", Namespace: testNamespace},
+			Data:       map[string]string{"ca-bundle.crt": certB + certC},
+		}
+		c := newFakeClient(newTestScheme(), acp, trustedCA)
+
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		infraEnv := &aiv1beta1.InfraEnv{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), infraEnv)).To(Succeed())
+		Expect(infraEnv.Spec.AdditionalTrustBundle).To(Equal(certA + certB + certC))
+	})
+})
@@ -0,0 +1,49 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/util/version"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// newerVersion returns the version of the controller that last reconciled
+// acp when it is newer than ControllerVersion, and nil otherwise. Stamps
+// that are not semantic versions are ignored, and so get replaced.
+func (r *AgentControlPlaneReconciler) newerVersion(acp *controlplanev1.AgentControlPlane) *version.Version {
+	if r.ControllerVersion == nil {
+		return nil
+	}
+	stamped, err := version.ParseSemantic(acp.Annotations[controlplanev1.ControllerVersionAnnotation])
+	if err != nil || !r.ControllerVersion.LessThan(stamped) {
+		return nil
+	}
+	return stamped
+}
+
+// stampVersion records ControllerVersion as the version of the controller
+// that last reconciled acp.
+func (r *AgentControlPlaneReconciler) stampVersion(acp *controlplanev1.AgentControlPlane) {
+	if r.ControllerVersion == nil {
+		return
+	}
+	if acp.Annotations == nil {
+		acp.Annotations = map[string]string{}
+	}
+	acp.Annotations[controlplanev1.ControllerVersionAnnotation] = r.ControllerVersion.String()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+	aiv1beta1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/assisted-service/api/v1beta1"
+)
+
+var _ = Describe("Controller version guard", func() {
+	ctx := context.Background()
+
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = newAgentControlPlane("test-acp")
+		setOwnerCluster(acp, newCluster("test-cluster"))
+	})
+
+	reconcileACP := func(controllerVersion string) (client.Client, *controlplanev1.AgentControlPlane) {
+		c := newFakeClient(newTestScheme(), acp, newCluster("test-cluster"), newPullSecret())
+		r := &AgentControlPlaneReconciler{Client: c, Scheme: c.Scheme()}
+		if controllerVersion != "" {
+			r.ControllerVersion = version.MustParseSemantic(controllerVersion)
+		}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(acp)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &controlplanev1.AgentControlPlane{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), updated)).To(Succeed())
+		return c, updated
+	}
+
+	It("leaves an AgentControlPlane stamped by a newer controller alone", func() {
+		acp.Annotations = map[string]string{controlplanev1.ControllerVersionAnnotation: "0.3.0"}
+		c, updated := reconcileACP("0.2.1")
+
+		Expect(updated.Annotations).To(HaveKeyWithValue(controlplanev1.ControllerVersionAnnotation, "0.3.0"))
+		Expect(updated.Finalizers).To(BeEmpty())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{})).NotTo(Succeed())
+	})
+
+	DescribeTable("stamps its own version",
+		func(stamp string) {
+			if stamp != "" {
+				acp.Annotations = map[string]string{controlplanev1.ControllerVersionAnnotation: stamp}
+			}
+			c, updated := reconcileACP("0.3.0")
+
+			Expect(updated.Annotations).To(HaveKeyWithValue(controlplanev1.ControllerVersionAnnotation, "0.3.0"))
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{})).To(Succeed())
+		},
+		Entry("on an unstamped AgentControlPlane", ""),
+		Entry("over an older version", "0.2.1"),
+		Entry("over a pre-release of its version", "0.3.0-rc.1"),
+		Entry("over an invalid stamp", "latest"),
+	)
+
+	It("neither stamps nor skips without a version", func() {
+		acp.Annotations = map[string]string{controlplanev1.ControllerVersionAnnotation: "0.3.0"}
+		c, updated := reconcileACP("")
+
+		Expect(updated.Annotations).To(HaveKeyWithValue(controlplanev1.ControllerVersionAnnotation, "0.3.0"))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(acp), &aiv1beta1.InfraEnv{})).To(Succeed())
+	})
+})
@@ -0,0 +1,195 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	hivev1 "github.com/openshift-assisted/agent-controlplane-provider/internal/thirdparty/hive/apis/hive/v1"
+)
+
+const (
+	// ClusterCompletedCondition mirrors the hive ClusterInstallCompleted condition.
+	ClusterCompletedCondition hivev1.ClusterInstallConditionType = hivev1.ClusterInstallCompleted
+	// ClusterFailedCondition mirrors the hive ClusterInstallFailed condition.
+	ClusterFailedCondition hivev1.ClusterInstallConditionType = hivev1.ClusterInstallFailed
+
+	// ClusterInstallationInProgressReason is the reason of the Completed
+	// condition while the install is in progress.
+	ClusterInstallationInProgressReason = "InstallationInProgress"
+)
+
+// PlatformType is the platform the cluster is installed on.
+type PlatformType string
+
+const (
+	// BareMetalPlatformType installs on bare metal hosts.
+	BareMetalPlatformType PlatformType = "BareMetal"
+	// NonePlatformType installs without platform integration.
+	NonePlatformType PlatformType = "None"
+	// VSpherePlatformType installs on vSphere virtual machines.
+	VSpherePlatformType PlatformType = "VSphere"
+)
+
+// AgentClusterInstallSpec defines the desired state of the AgentClusterInstall.
+type AgentClusterInstallSpec struct {
+	// ImageSetRef is a reference to a ClusterImageSet. The release image specified in the ClusterImageSet will be used
+	// to install the cluster.
+	ImageSetRef *hivev1.ClusterImageSetReference `json:"imageSetRef,omitempty"`
+
+	// ClusterDeploymentRef is a reference to the ClusterDeployment associated with this AgentClusterInstall.
+	ClusterDeploymentRef corev1.LocalObjectReference `json:"clusterDeploymentRef"`
+
+	// Networking is the configuration for the pod network provider in
+	// the cluster.
+	Networking Networking `json:"networking"`
+
+	// ProvisionRequirements defines configuration for when the installation is ready to be launched automatically.
+	ProvisionRequirements ProvisionRequirements `json:"provisionRequirements"`
+
+	// PlatformType is the name for the specific platform upon which to perform the installation.
+	// +optional
+	PlatformType PlatformType `json:"platformType,omitempty"`
+
+	// APIVIPs are the virtual IPs used to reach the OpenShift cluster's API.
+	// Enter one IP address for single-stack clusters, or up to two for dual-stack clusters (at
+	// most one IP address per IP stack used). The order of stacks should be the same as order
+	// of subnets in Cluster Networks, Service Networks, and Machine Networks.
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	APIVIPs []string `json:"apiVIPs,omitempty"`
+
+	// IngressVIPs are the virtual IPs used for cluster ingress traffic.
+	// Enter one IP address for single-stack clusters, or up to two for dual-stack clusters (at
+	// most one IP address per IP stack used). The order of stacks should be the same as order
+	// of subnets in Cluster Networks, Service Networks, and Machine Networks.
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	IngressVIPs []string `json:"ingressVIPs,omitempty"`
+
+	// SSHPublicKey will be added to all cluster hosts for use in debugging.
+	// +optional
+	SSHPublicKey string `json:"sshPublicKey,omitempty"`
+
+	// DiskEncryption is the configuration to enable/disable disk encryption for cluster nodes.
+	// +optional
+	DiskEncryption *DiskEncryption `json:"diskEncryption,omitempty"`
+
+	// ManifestsConfigMapRefs is an array of references to user-provided manifests ConfigMaps
+	// +optional
+	ManifestsConfigMapRefs []ManifestsConfigMapReference `json:"manifestsConfigMapRefs,omitempty"`
+}
+
+// ManifestsConfigMapReference is a reference to a manifests ConfigMap
+type ManifestsConfigMapReference struct {
+	// Name is the name of the ConfigMap that this refers to
+	Name string `json:"name"`
+}
+
+// DiskEncryption is the disk encryption configuration of the cluster nodes.
+type DiskEncryption struct {
+	// Enable/disable disk encryption on master nodes, worker nodes, or all nodes.
+	// +kubebuilder:default=none
+	// +kubebuilder:validation:Enum=none;all;masters;workers
+	EnableOn *string `json:"enableOn,omitempty"`
+
+	// The disk encryption mode to use.
+	// +kubebuilder:validation:Enum=tpmv2;tang
+	Mode *string `json:"mode,omitempty"`
+
+	// JSON-formatted string containing additional information regarding tang's configuration
+	TangServers string `json:"tangServers,omitempty"`
+}
+
+// Networking defines the pod network provider in the cluster.
+type Networking struct {
+	// MachineNetwork is the list of IP address pools for machines.
+	// +optional
+	MachineNetwork []MachineNetworkEntry `json:"machineNetwork,omitempty"`
+
+	// ClusterNetwork is the list of IP address pools for pods.
+	// +optional
+	ClusterNetwork []ClusterNetworkEntry `json:"clusterNetwork,omitempty"`
+
+	// ServiceNetwork is the list of IP address pools for services.
+	// +optional
+	ServiceNetwork []string `json:"serviceNetwork,omitempty"`
+}
+
+// MachineNetworkEntry is a single IP address block for node IP blocks.
+type MachineNetworkEntry struct {
+	// CIDR is the IP block address pool for machines within the cluster.
+	CIDR string `json:"cidr"`
+}
+
+// ClusterNetworkEntry is a single IP address block for pod IP blocks. IP blocks
+// are allocated with size 2^HostSubnetLength.
+type ClusterNetworkEntry struct {
+	// CIDR is the IP block address pool.
+	CIDR string `json:"cidr"`
+
+	// HostPrefix is the prefix size to allocate to each node from the CIDR.
+	// For example, 24 would allocate 2^8=256 addresses to each node. If this
+	// field is not used by the plugin, it can be left unset.
+	// +optional
+	HostPrefix int32 `json:"hostPrefix,omitempty"`
+}
+
+// ProvisionRequirements defines configuration for when the installation is ready to be launched automatically.
+type ProvisionRequirements struct {
+	// ControlPlaneAgents is the number of matching approved and ready Agents with the control plane role
+	// required to launch the install. Must be either 1 or 3-5.
+	ControlPlaneAgents int `json:"controlPlaneAgents"`
+
+	// WorkerAgents is the minimum number of matching approved and ready Agents with the worker role
+	// required to launch the install.
+	// +optional
+	WorkerAgents int `json:"workerAgents,omitempty"`
+}
+
+// AgentClusterInstallStatus defines the observed state of the AgentClusterInstall.
+type AgentClusterInstallStatus struct {
+	// Conditions includes more detailed status for the cluster install.
+	// +optional
+	Conditions []hivev1.ClusterInstallCondition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// AgentClusterInstall represents a request to install a cluster using assisted-service.
+type AgentClusterInstall struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentClusterInstallSpec   `json:"spec"`
+	Status AgentClusterInstallStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AgentClusterInstallList contains a list of AgentClusterInstalls
+type AgentClusterInstallList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentClusterInstall `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentClusterInstall{}, &AgentClusterInstallList{})
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HostRole is the role a host is installed with.
+type HostRole string
+
+const (
+	// HostRoleMaster installs the host as a control plane node.
+	HostRoleMaster HostRole = "master"
+	// HostRoleWorker installs the host as a worker node.
+	HostRoleWorker HostRole = "worker"
+)
+
+// ClusterReference represents a Cluster Reference. It has enough information to retrieve cluster
+// in any namespace
+type ClusterReference struct {
+	// Name is unique within a namespace to reference a cluster resource.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Namespace defines the space within which the cluster name must be unique.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// AgentSpec defines the desired state of Agent
+type AgentSpec struct {
+	// ClusterDeploymentName is the ClusterDeployment the agent is bound to.
+	// +optional
+	ClusterDeploymentName *ClusterReference `json:"clusterDeploymentName,omitempty"`
+	// +optional
+	Role HostRole `json:"role"`
+	// Approved allows the agent to be installed.
+	Approved bool `json:"approved"`
+	// Hostname overrides the hostname reported by the host.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+	// InstallationDiskID is the ID of the disk RHCOS is installed on.
+	// +optional
+	InstallationDiskID string `json:"installation_disk_id,omitempty"`
+	// IgnitionConfigOverrides is a JSON-formatted ignition config merged into
+	// the host's installation ignition.
+	// +optional
+	IgnitionConfigOverrides string `json:"ignitionConfigOverrides,omitempty"`
+}
+
+// HostInventory is the hardware inventory reported by a host.
+type HostInventory struct {
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+	// +optional
+	Cpu HostCPU `json:"cpu,omitempty"`
+	// +optional
+	Memory HostMemory `json:"memory,omitempty"`
+}
+
+type HostCPU struct {
+	// Name in REST API: count
+	Count int64 `json:"count,omitempty"`
+}
+
+type HostMemory struct {
+	// Name in REST API: physical_bytes
+	PhysicalBytes int64 `json:"physicalBytes,omitempty"`
+	// Name in REST API: usable_bytes
+	UsableBytes int64 `json:"usableBytes,omitempty"`
+}
+
+// ValidationResult is the result of a single host validation.
+type ValidationResult struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// ValidationResults are the results of the validations of a category.
+type ValidationResults []ValidationResult
+
+// ValidationsInfo holds the validation results of a host, keyed by category
+// (e.g. "hardware", "network").
+type ValidationsInfo map[string]ValidationResults
+
+// ValidationFailure is the status of a failed validation.
+const ValidationFailure = "failure"
+
+// AgentConditionType is the type of an Agent condition.
+type AgentConditionType string
+
+const (
+	// RequirementsMetCondition reports whether the host is ready to be
+	// installed.
+	RequirementsMetCondition AgentConditionType = "RequirementsMet"
+)
+
+// AgentCondition is a condition of an Agent.
+type AgentCondition struct {
+	Type   AgentConditionType     `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// HostProgressInfo is the installation progress of a host.
+type HostProgressInfo struct {
+	// CurrentStage is the installation stage the host is at.
+	// +optional
+	CurrentStage string `json:"currentStage,omitempty"`
+}
+
+// AgentStatus defines the observed state of Agent
+type AgentStatus struct {
+	// ValidationsInfo is a JSON-formatted string containing the validation results for each validation id grouped by category (network, hosts-data, etc.)
+	// +optional
+	ValidationsInfo ValidationsInfo `json:"validationsInfo,omitempty"`
+	// +optional
+	Inventory HostInventory `json:"inventory,omitempty"`
+	// +optional
+	Progress HostProgressInfo `json:"progress,omitempty"`
+	// +optional
+	Conditions []AgentCondition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// Agent is the Schema for the agents API
+type Agent struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentSpec   `json:"spec,omitempty"`
+	Status AgentStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AgentList contains a list of Agent
+type AgentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Agent `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Agent{}, &AgentList{})
+}
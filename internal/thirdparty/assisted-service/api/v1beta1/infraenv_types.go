@@ -0,0 +1,190 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InfraEnvNameLabel is set by assisted-service on every Agent discovered
+// through an InfraEnv, with the InfraEnv name as its value.
+const InfraEnvNameLabel = "infraenvs.agent-install.openshift.io"
+
+// ImageType is the type of discovery image generated for an InfraEnv.
+type ImageType string
+
+const (
+	// ImageTypeFullISO embeds the full RHCOS image in the discovery ISO.
+	ImageTypeFullISO ImageType = "full-iso"
+	// ImageTypeMinimalISO downloads the RHCOS rootfs at boot time.
+	ImageTypeMinimalISO ImageType = "minimal-iso"
+)
+
+// IPXEScriptType is the type of iPXE script served for an InfraEnv.
+type IPXEScriptType string
+
+const (
+	// DiscoveryImageAlways boots the discovery image on every boot.
+	DiscoveryImageAlways IPXEScriptType = "DiscoveryImageAlways"
+	// BootOrderControl boots the discovery image until the host is
+	// installed, and the installed disk after that.
+	BootOrderControl IPXEScriptType = "BootOrderControl"
+)
+
+// InfraEnvSpec defines the desired state of InfraEnv
+type InfraEnvSpec struct {
+	// PullSecretRef is the reference to the secret to use when pulling images.
+	PullSecretRef *corev1.LocalObjectReference `json:"pullSecretRef"`
+
+	// SSHAuthorizedKey is a SSH public keys that will be added to all agents for use in debugging.
+	// +optional
+	SSHAuthorizedKey string `json:"sshAuthorizedKey,omitempty"`
+
+	// ImageType specifies the type of discovery ISO to be generated by the Infrastructure Environment.
+	// +optional
+	ImageType ImageType `json:"imageType,omitempty"`
+
+	// IPXEScriptType the script type that should be served (DiscoveryImageAlways/BootOrderControl).
+	// It is omitted when empty, unlike upstream, so that an unset type is left out of applies.
+	// +optional
+	IPXEScriptType IPXEScriptType `json:"ipxeScriptType,omitempty"`
+
+	// KernelArguments is the additional kernel arguments to be passed during boot time of the discovery image.
+	// Applicable for both iPXE, and ISO streaming from Image Service.
+	// +optional
+	KernelArguments []KernelArgument `json:"kernelArguments,omitempty"`
+
+	// Json formatted string containing the user overrides for the initial ignition config
+	// +optional
+	IgnitionConfigOverride string `json:"ignitionConfigOverride,omitempty"`
+
+	// PEM-encoded X.509 certificate bundle. Hosts discovered by this
+	// infra-env will trust the certificates in this bundle. Clusters formed
+	// from the hosts discovered by this infra-env will also trust the
+	// certificates in this bundle.
+	// +optional
+	AdditionalTrustBundle string `json:"additionalTrustBundle,omitempty"`
+
+	// MirrorRegistryRef is the reference to the ConfigMap holding the registry
+	// mirror configuration of the discovered hosts.
+	// +optional
+	MirrorRegistryRef *corev1.LocalObjectReference `json:"mirrorRegistryRef,omitempty"`
+
+	// Proxy defines the proxy settings for agents and clusters that use the InfraEnv. If
+	// unset, the agents and clusters will not be configured to use a proxy.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+
+	// AdditionalNTPSources is a list of NTP sources (hostname or IP) to be added to all cluster
+	// hosts. They are added to any NTP sources that were configured through other means.
+	// +optional
+	AdditionalNTPSources []string `json:"additionalNTPSources,omitempty"`
+
+	// NMStateConfigLabelSelector associates NMStateConfigs for hosts that are considered part
+	// of this installation environment. It is a pointer here, unlike upstream, so that an
+	// unset selector is left out of applies.
+	// +optional
+	NMStateConfigLabelSelector *metav1.LabelSelector `json:"nmStateConfigLabelSelector,omitempty"`
+}
+
+// Proxy defines the proxy settings for agents and clusters that use the InfraEnv.
+type Proxy struct {
+	// HTTPProxy is the URL of the proxy for HTTP requests.
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the URL of the proxy for HTTPS requests.
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma-separated list of domains and CIDRs for which the proxy should not be used.
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+// KernelArgument is a kernel argument change applied to the discovery image.
+type KernelArgument struct {
+	// Operation is the operation to apply on the kernel argument.
+	// +kubebuilder:validation:Enum=append;replace;delete
+	Operation string `json:"operation,omitempty"`
+
+	// Value can have the form <parameter> or <parameter>=<value>. The following examples should be supported:
+	// rd.net.timeout.carrier=60
+	// isolcpus=1,2,10-20,100-2000:2/25
+	// quiet
+	// +kubebuilder:validation:Pattern=`^(?:(?:[^ \t\n\r"]+)|(?:"[^"]*"))+$`
+	Value string `json:"value,omitempty"`
+}
+
+// InfraEnvStatus defines the observed state of InfraEnv
+type InfraEnvStatus struct {
+	// ISODownloadURL specifies an HTTP/S URL that contains a discovery ISO.
+	// +optional
+	ISODownloadURL string `json:"isoDownloadURL,omitempty"`
+
+	// CreatedTime is the time at which the ISO was created
+	// +optional
+	CreatedTime *metav1.Time `json:"createdTime,omitempty"`
+
+	// BootArtifacts specifies the URLs for each boot artifact
+	// +optional
+	BootArtifacts BootArtifacts `json:"bootArtifacts"`
+}
+
+type BootArtifacts struct {
+	// InitrdURL specifies an HTTP/S URL that contains the initrd
+	// +optional
+	InitrdURL string `json:"initrd"`
+	// RootfsURL specifies an HTTP/S URL that contains the rootfs
+	// +optional
+	RootfsURL string `json:"rootfs"`
+	// KernelURL specifies an HTTP/S URL that contains the kernel
+	// +optional
+	KernelURL string `json:"kernel"`
+	// DiscoveryIgnitionURL specifies and HTTP/S URL that contains the discovery ignition
+	// +optional
+	DiscoveryIgnitionURL string `json:"discoveryIgnitionURL"`
+	// IpxeScriptURL specifies an HTTP/S URL that contains the iPXE script
+	// +optional
+	IpxeScriptURL string `json:"ipxeScript"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// InfraEnv is the Schema for the infraenvs API
+type InfraEnv struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   InfraEnvSpec   `json:"spec,omitempty"`
+	Status InfraEnvStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// InfraEnvList contains a list of InfraEnv
+type InfraEnvList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []InfraEnv `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&InfraEnv{}, &InfraEnvList{})
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"bytes"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// NMStateConfigSpec defines the desired state of NMStateConfig
+type NMStateConfigSpec struct {
+	// Interfaces is an array of interface objects containing the name and MAC
+	// address for interfaces that are referenced in the raw nmstate config YAML.
+	// +optional
+	Interfaces []*Interface `json:"interfaces,omitempty"`
+
+	// yaml that can be processed by nmstate, using custom marshaling/unmarshaling that will allow to populate nmstate config as plain yaml.
+	// +kubebuilder:validation:XPreserveUnknownFields
+	NetConfig NetConfig `json:"config,omitempty"`
+}
+
+// Interface maps an interface name of the nmstate config to the MAC address
+// of the host NIC it is applied to.
+type Interface struct {
+	// name of the interface
+	Name string `json:"name"`
+
+	// mac address present on the host.
+	MacAddress string `json:"macAddress"`
+}
+
+// NetConfig holds an nmstate config as YAML. It is serialized as the JSON
+// document rather than a string.
+type NetConfig struct {
+	Raw RawNetConfig `json:"-"`
+}
+
+// RawNetConfig is a YAML-encoded nmstate config.
+type RawNetConfig []byte
+
+// MarshalJSON implements json.Marshaler.
+func (t NetConfig) MarshalJSON() ([]byte, error) {
+	if t.Raw == nil {
+		return []byte("null"), nil
+	}
+	// The raw data is YAML, convert it to JSON.
+	return yaml.YAMLToJSON(t.Raw)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *NetConfig) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		t.Raw = nil
+		return nil
+	}
+	// The data is JSON, convert it to YAML.
+	raw, err := yaml.JSONToYAML(b)
+	if err != nil {
+		return err
+	}
+	t.Raw = raw
+	return nil
+}
+
+//+kubebuilder:object:root=true
+
+// NMStateConfig is the Schema for the nmstateconfigs API
+type NMStateConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NMStateConfigSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NMStateConfigList contains a list of NMStateConfig
+type NMStateConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NMStateConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NMStateConfig{}, &NMStateConfigList{})
+}
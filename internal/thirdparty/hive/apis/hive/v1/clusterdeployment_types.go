@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterDeploymentSpec defines the desired state of ClusterDeployment
+type ClusterDeploymentSpec struct {
+	// ClusterName is the friendly name of the cluster. It is used for subdomains,
+	// some resource tagging, and other instances where a friendly name for the
+	// cluster is useful.
+	ClusterName string `json:"clusterName"`
+
+	// BaseDomain is the base domain to which the cluster should belong.
+	BaseDomain string `json:"baseDomain"`
+
+	// Platform is the configuration for the specific platform upon which to
+	// perform the installation.
+	Platform Platform `json:"platform"`
+
+	// PullSecretRef is the reference to the secret to use when pulling images.
+	// +optional
+	PullSecretRef *corev1.LocalObjectReference `json:"pullSecretRef,omitempty"`
+
+	// Installed is true if the cluster has been installed
+	// +optional
+	Installed bool `json:"installed"`
+
+	// ClusterMetadata contains metadata information about the installed cluster.
+	// +optional
+	ClusterMetadata *ClusterMetadata `json:"clusterMetadata,omitempty"`
+
+	// ClusterInstallLocalReference provides reference to an object that implements
+	// the hivecontract ClusterInstall. The namespace of the object is same as the
+	// ClusterDeployment.
+	// +optional
+	ClusterInstallRef *ClusterInstallLocalReference `json:"clusterInstallRef,omitempty"`
+}
+
+// ClusterMetadata contains metadata information about the installed cluster.
+type ClusterMetadata struct {
+	// ClusterID is a globally unique identifier for this cluster generated during installation. Used for reporting metrics among other places.
+	ClusterID string `json:"clusterID"`
+
+	// InfraID is an identifier for this cluster generated during installation and used for tagging/naming resources in cloud providers.
+	InfraID string `json:"infraID"`
+
+	// AdminKubeconfigSecretRef references the secret containing the admin kubeconfig for this cluster.
+	AdminKubeconfigSecretRef corev1.LocalObjectReference `json:"adminKubeconfigSecretRef"`
+
+	// AdminPasswordSecretRef references the secret containing the admin username/password which can be used to login to this cluster.
+	// +optional
+	AdminPasswordSecretRef *corev1.LocalObjectReference `json:"adminPasswordSecretRef,omitempty"`
+}
+
+// ClusterInstallLocalReference provides reference to an object that implements
+// the hivecontract ClusterInstall.
+type ClusterInstallLocalReference struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+
+	Name string `json:"name"`
+}
+
+// Platform is the configuration for the specific platform upon which to perform
+// the installation. Only one of the platform configuration should be set.
+type Platform struct {
+	// AgentBareMetal is the configuration used when performing an Assisted Agent based installation
+	// to bare metal.
+	// +optional
+	AgentBareMetal *AgentBareMetalPlatform `json:"agentBareMetal,omitempty"`
+}
+
+// AgentBareMetalPlatform defines agent based install configuration specific to
+// bare metal clusters.
+type AgentBareMetalPlatform struct {
+	// AgentSelector is a label selector used for associating relevant custom resources with this cluster.
+	// (Agent, BareMetalHost, etc)
+	AgentSelector metav1.LabelSelector `json:"agentSelector"`
+}
+
+// ClusterDeploymentStatus defines the observed state of ClusterDeployment
+type ClusterDeploymentStatus struct{}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ClusterDeployment is the Schema for the clusterdeployments API
+type ClusterDeployment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterDeploymentSpec   `json:"spec,omitempty"`
+	Status ClusterDeploymentStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterDeploymentList contains a list of ClusterDeployment
+type ClusterDeploymentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterDeployment `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterDeployment{}, &ClusterDeploymentList{})
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterImageSetSpec defines the desired state of ClusterImageSet
+type ClusterImageSetSpec struct {
+	// ReleaseImage is the image that contains the payload to use when installing
+	// a cluster.
+	ReleaseImage string `json:"releaseImage"`
+}
+
+// ClusterImageSetReference is a reference to a ClusterImageSet
+type ClusterImageSetReference struct {
+	// Name is the name of the ClusterImageSet that this refers to
+	Name string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// ClusterImageSet is the Schema for the clusterimagesets API
+type ClusterImageSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterImageSetSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterImageSetList contains a list of ClusterImageSet
+type ClusterImageSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterImageSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterImageSet{}, &ClusterImageSetList{})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package acp provides helpers for tooling working with AgentControlPlanes.
+// The helpers are pure functions of the object: they apply the defaulting
+// and status conventions of the controller without talking to an API server.
+package acp
+
+import (
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+// EffectiveReplicas returns the number of control plane replicas acp asks
+// for, applying the default of one when spec.replicas is unset.
+func EffectiveReplicas(acp *controlplanev1.AgentControlPlane) int32 {
+	if acp.Spec.Replicas == nil {
+		return 1
+	}
+	return *acp.Spec.Replicas
+}
+
+// IsReady reports whether the control plane of acp is installed and its API
+// server reachable, and no terminal failure was recorded since.
+func IsReady(acp *controlplanev1.AgentControlPlane) bool {
+	return acp.Status.Ready && acp.Status.FailureReason == "" && acp.Status.FailureMessage == nil
+}
+
+// GetReadyReplicas returns the number of ready control plane replicas. The
+// controller reports readiness for the control plane as a whole, so either
+// all of the effective replicas are ready or none is.
+func GetReadyReplicas(acp *controlplanev1.AgentControlPlane) int32 {
+	if !IsReady(acp) {
+		return 0
+	}
+	return EffectiveReplicas(acp)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acp
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/utils/ptr"
+
+	controlplanev1 "github.com/openshift-assisted/agent-controlplane-provider/api/v1"
+)
+
+var _ = Describe("AgentControlPlane helpers", func() {
+	var acp *controlplanev1.AgentControlPlane
+
+	BeforeEach(func() {
+		acp = &controlplanev1.AgentControlPlane{}
+	})
+
+	Context("EffectiveReplicas", func() {
+		It("defaults to one replica", func() {
+			Expect(EffectiveReplicas(acp)).To(BeEquivalentTo(1))
+		})
+
+		It("returns spec.replicas when set", func() {
+			acp.Spec.Replicas = ptr.To[int32](3)
+			Expect(EffectiveReplicas(acp)).To(BeEquivalentTo(3))
+		})
+	})
+
+	Context("IsReady", func() {
+		It("is false until the controller marks the control plane ready", func() {
+			Expect(IsReady(acp)).To(BeFalse())
+
+			acp.Status.Ready = true
+			Expect(IsReady(acp)).To(BeTrue())
+		})
+
+		It("is false once a terminal failure is recorded", func() {
+			acp.Status.Ready = true
+			acp.Status.FailureReason = "InstallFailed"
+			acp.Status.FailureMessage = ptr.To("the installation failed")
+			Expect(IsReady(acp)).To(BeFalse())
+		})
+	})
+
+	Context("GetReadyReplicas", func() {
+		BeforeEach(func() {
+			acp.Spec.Replicas = ptr.To[int32](3)
+		})
+
+		It("reports no ready replicas before the control plane is ready", func() {
+			Expect(GetReadyReplicas(acp)).To(BeZero())
+		})
+
+		It("reports every replica ready once the control plane is ready", func() {
+			acp.Status.Ready = true
+			Expect(GetReadyReplicas(acp)).To(BeEquivalentTo(3))
+		})
+	})
+})